@@ -0,0 +1,169 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"math"
+
+	log "github.com/sirupsen/logrus"
+	coreV1 "k8s.io/api/core/v1"
+)
+
+// ComputeClass is the GKE Autopilot compute class pods run on. It defines the resource rules and the SKUs pods are billed with
+type ComputeClass string
+
+const (
+	// GeneralPurpose is the default Autopilot compute class, used when pods don't select one
+	GeneralPurpose ComputeClass = "General-purpose"
+	// Balanced compute class runs on N2 or N2D nodes and supports higher memory to CPU ratios
+	Balanced ComputeClass = "Balanced"
+	// ScaleOut compute class runs on T2D nodes, with simultaneous multithreading disabled
+	ScaleOut ComputeClass = "Scale-Out"
+)
+
+// ComputeClassNodeLabel is the nodeSelector label pods use to select an Autopilot compute class
+const ComputeClassNodeLabel = "cloud.google.com/compute-class"
+
+// autopilotRules are the minimum and ratio rules Autopilot applies to pod requests
+type autopilotRules struct {
+	minCPU          int64   // millis
+	cpuStep         int64   // millis
+	minMemory       int64   // bytes
+	minMemoryPerCPU float64 // GiB per vCPU
+	maxMemoryPerCPU float64 // GiB per vCPU
+}
+
+var autopilotComputeClassRules = map[ComputeClass]autopilotRules{
+	GeneralPurpose: {minCPU: 250, cpuStep: 250, minMemory: 512 * 1024 * 1024, minMemoryPerCPU: 1, maxMemoryPerCPU: 6.5},
+	Balanced:       {minCPU: 250, cpuStep: 250, minMemory: 512 * 1024 * 1024, minMemoryPerCPU: 1, maxMemoryPerCPU: 8},
+	ScaleOut:       {minCPU: 250, cpuStep: 250, minMemory: 1024 * 1024 * 1024, minMemoryPerCPU: 1, maxMemoryPerCPU: 4},
+}
+
+// autopilotDefaultRequests is what Autopilot requests for containers not requesting resources
+var autopilotDefaultRequests = Resource{CPU: 500, Memory: 2 * 1024 * 1024 * 1024, Storage: 1024 * 1024 * 1024}
+
+// autopilotMinEphemeralStorage is the minimum ephemeral storage Autopilot requests for a pod
+const autopilotMinEphemeralStorage = 10 * 1024 * 1024
+
+// computeClass returns the Autopilot compute class pods run on, or empty for Standard clusters
+// Compute classes not supported by the estimator are priced as General-purpose
+func computeClass(spec coreV1.PodSpec, conf CostimatorConfig) ComputeClass {
+	if conf.ClusterConf.Mode != Autopilot {
+		return ""
+	}
+	class, ok := spec.NodeSelector[ComputeClassNodeLabel]
+	if !ok {
+		return GeneralPurpose
+	}
+	if _, supported := autopilotComputeClassRules[ComputeClass(class)]; !supported {
+		log.Infof("Autopilot compute class '%s' not supported. Using %s instead", class, GeneralPurpose)
+		return GeneralPurpose
+	}
+	return ComputeClass(class)
+}
+
+// autopilotContainers appends the resources Autopilot adds to the pod when rounding its requests up
+// Autopilot bills requests and sets limits equal to requests, so the adjustment also brings limits down to requests
+func autopilotContainers(containers []Container, class ComputeClass) []Container {
+	requests, limits := sumContainers(containers)
+	rounded := autopilotComputeClassRules[class].roundUp(requests)
+	adjustment := Container{
+		Requests: rounded.add(negative(requests)),
+		Limits:   rounded.add(negative(limits)),
+		Type:     AutopilotAdjustment,
+	}
+	return append(containers, adjustment)
+}
+
+// roundUp applies Autopilot minimums, CPU increments and memory to CPU ratio to the pod requests
+func (r autopilotRules) roundUp(requests Resource) Resource {
+	cpu := requests.CPU
+	if cpu < r.minCPU {
+		cpu = r.minCPU
+	}
+	memory := requests.Memory
+	if memory < r.minMemory {
+		memory = r.minMemory
+	}
+	// CPU is increased when memory exceeds the max ratio
+	if ratioCPU := int64(math.Ceil(float64(memory) / bytesInGiB / r.maxMemoryPerCPU * 1000)); cpu < ratioCPU {
+		cpu = ratioCPU
+	}
+	if remainder := cpu % r.cpuStep; remainder != 0 {
+		cpu = cpu + r.cpuStep - remainder
+	}
+	// memory is increased when it is below the min ratio
+	if ratioMemory := int64(math.Ceil(float64(cpu) / 1000 * r.minMemoryPerCPU * bytesInGiB)); memory < ratioMemory {
+		memory = ratioMemory
+	}
+	storage := requests.Storage
+	if storage < autopilotMinEphemeralStorage {
+		storage = autopilotMinEphemeralStorage
+	}
+	return Resource{CPU: cpu, Memory: memory, Storage: storage}
+}
+
+func negative(r Resource) Resource {
+	return Resource{CPU: -r.CPU, Memory: -r.Memory, Storage: -r.Storage}
+}
+
+// -------- Autopilot Price Catalog ---------
+
+// AutopilotPriceProvider interface is implemented by price providers knowing GKE Autopilot pod prices
+// found is false if prices for the compute class are not available
+type AutopilotPriceProvider interface {
+	AutopilotResourcePrice(class ComputeClass, spot bool) (rp ResourcePrice, found bool)
+}
+
+// EphemeralStoragePrice interface is implemented by resource prices billing pod ephemeral storage (eg. GKE Autopilot)
+type EphemeralStoragePrice interface {
+	EphemeralStorageMonthlyPrice() float32
+}
+
+type autopilotPriceKey struct {
+	class ComputeClass
+	spot  bool
+}
+
+// autopilotPrice holds GKE Autopilot pod prices. Memory and ephemeral storage prices are per byte
+type autopilotPrice struct {
+	cpuPrice              float32
+	memoryPrice           float32
+	ephemeralStoragePrice float32
+}
+
+// CPUMonthlyPrice returns the GKE Autopilot pod CPU price in USD
+func (p *autopilotPrice) CPUMonthlyPrice() float32 {
+	return p.cpuPrice
+}
+
+// MemoryMonthlyPrice returns the GKE Autopilot pod Memory price in USD
+func (p *autopilotPrice) MemoryMonthlyPrice() float32 {
+	return p.memoryPrice
+}
+
+// EphemeralStorageMonthlyPrice returns the GKE Autopilot pod Ephemeral Storage price in USD
+func (p *autopilotPrice) EphemeralStorageMonthlyPrice() float32 {
+	return p.ephemeralStoragePrice
+}
+
+// AutopilotResourcePrice returns the GKE Autopilot pod prices in USD for the given compute class
+func (pc *GCPPriceCatalog) AutopilotResourcePrice(class ComputeClass, spot bool) (ResourcePrice, bool) {
+	price, found := pc.autopilotPrices[autopilotPriceKey{class: class, spot: spot}]
+	if !found {
+		return nil, false
+	}
+	return &price, true
+}
@@ -0,0 +1,184 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	coreV1 "k8s.io/api/core/v1"
+)
+
+const mebibyte = 1024 * 1024
+
+func TestAutopilotRoundUp(t *testing.T) {
+	tests := map[string]struct {
+		class    ComputeClass
+		requests Resource
+		want     Resource
+	}{
+		"minimums": {
+			class:    GeneralPurpose,
+			requests: Resource{CPU: 100, Memory: 64 * mebibyte},
+			want:     Resource{CPU: 250, Memory: 512 * mebibyte, Storage: 10 * mebibyte},
+		},
+		"cpu increments and min memory ratio": {
+			class:    GeneralPurpose,
+			requests: Resource{CPU: 600, Memory: 600 * mebibyte, Storage: 1024 * mebibyte},
+			want:     Resource{CPU: 750, Memory: 768 * mebibyte, Storage: 1024 * mebibyte},
+		},
+		"max memory ratio": {
+			class:    GeneralPurpose,
+			requests: Resource{CPU: 250, Memory: 4096 * mebibyte},
+			want:     Resource{CPU: 750, Memory: 4096 * mebibyte, Storage: 10 * mebibyte},
+		},
+		"balanced max memory ratio": {
+			class:    Balanced,
+			requests: Resource{CPU: 250, Memory: 4096 * mebibyte},
+			want:     Resource{CPU: 500, Memory: 4096 * mebibyte, Storage: 10 * mebibyte},
+		},
+		"scale-out min memory": {
+			class:    ScaleOut,
+			requests: Resource{CPU: 1000, Memory: 512 * mebibyte},
+			want:     Resource{CPU: 1000, Memory: 1024 * mebibyte, Storage: 10 * mebibyte},
+		},
+	}
+	for name, tt := range tests {
+		if got := autopilotComputeClassRules[tt.class].roundUp(tt.requests); got != tt.want {
+			t.Errorf("%s: rounded requests are %+v, want %+v", name, got, tt.want)
+		}
+	}
+}
+
+func TestComputeClass(t *testing.T) {
+	autopilot := CostimatorConfig{ClusterConf: ClusterConfig{Mode: Autopilot}}
+	tests := map[string]struct {
+		nodeSelector map[string]string
+		conf         CostimatorConfig
+		want         ComputeClass
+	}{
+		"standard cluster": {
+			nodeSelector: map[string]string{ComputeClassNodeLabel: "Balanced"},
+			conf:         CostimatorConfig{},
+			want:         "",
+		},
+		"no compute class": {
+			conf: autopilot,
+			want: GeneralPurpose,
+		},
+		"balanced": {
+			nodeSelector: map[string]string{ComputeClassNodeLabel: "Balanced"},
+			conf:         autopilot,
+			want:         Balanced,
+		},
+		"scale-out": {
+			nodeSelector: map[string]string{ComputeClassNodeLabel: "Scale-Out"},
+			conf:         autopilot,
+			want:         ScaleOut,
+		},
+		"not supported": {
+			nodeSelector: map[string]string{ComputeClassNodeLabel: "Performance"},
+			conf:         autopilot,
+			want:         GeneralPurpose,
+		},
+	}
+	for name, tt := range tests {
+		spec := coreV1.PodSpec{NodeSelector: tt.nodeSelector}
+		if got := computeClass(spec, tt.conf); got != tt.want {
+			t.Errorf("%s: compute class is '%s', want '%s'", name, got, tt.want)
+		}
+	}
+}
+
+func TestEstimateCostAutopilot(t *testing.T) {
+	data := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: my-nginx
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: my-nginx
+        image: nginx
+        resources:
+          requests:
+            memory: "256Mi"
+            cpu: "100m"
+          limits:
+            memory: "1Gi"
+            cpu: "1"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: balanced
+spec:
+  replicas: 1
+  template:
+    spec:
+      nodeSelector:
+        cloud.google.com/compute-class: Balanced
+      containers:
+      - name: balanced
+        image: nginx`
+
+	conf := CostimatorConfig{
+		ResourceConf: ResourceConfig{MachineFamily: N1},
+		ClusterConf:  ClusterConfig{Mode: Autopilot},
+	}
+	manifests := Manifests{}
+	err := manifests.LoadObjects([]byte(data), conf)
+	if err != nil {
+		t.Fatalf("Error loading objects: %+v", err)
+	}
+
+	pc := &GCPPriceCatalog{
+		cpuPrice:    10,
+		memoryPrice: 1.0 / bytesInGiB,
+		autopilotPrices: map[autopilotPriceKey]autopilotPrice{
+			{class: GeneralPurpose}: {cpuPrice: 40, memoryPrice: 4.0 / bytesInGiB, ephemeralStoragePrice: 0.5 / bytesInGiB},
+		},
+	}
+	cost := manifests.EstimateCost(pc)
+
+	// 250m, 512Mi and 1Gi ephemeral storage per pod: 10 + 2 + 0.5. Limits are equal to requests
+	expected := ObjectCostRange{
+		APIVersionKindName: "apps/v1|Deployment|default|my-nginx",
+		Namespace:          "default",
+		Kind:               DeploymentKind,
+		Name:               "my-nginx",
+		Replicas:           2,
+		MinReplicas:        2,
+		MaxReplicas:        2,
+		Requests:           Resource{CPU: 250, Memory: 512 * mebibyte, Storage: 1024 * mebibyte},
+		Limits:             Resource{CPU: 250, Memory: 512 * mebibyte, Storage: 1024 * mebibyte},
+		ComputeClass:       GeneralPurpose,
+		MonthlyRange:       CostRange{Kind: DeploymentKind, MinRequested: 25, MaxRequested: 25, HPABuffer: 25, MinLimited: 25, MaxLimited: 25},
+	}
+	if got := cost.MonthlyObjectRanges[0]; !cmp.Equal(got, expected) {
+		t.Errorf("Deployment cost should be equal, expected: %+v, got: %+v", expected, got)
+	}
+
+	// Balanced prices are not in the catalog: Autopilot defaults (500m, 2Gi) are priced at Standard prices
+	balanced := cost.MonthlyObjectRanges[1]
+	if balanced.ComputeClass != Balanced || balanced.MonthlyRange.MinRequested != 7 {
+		t.Errorf("Balanced deployment should have been priced at Standard prices, got %+v", balanced)
+	}
+	if cost.Discounts != nil {
+		t.Errorf("Discounts should not be applied to Autopilot clusters, got %+v", cost.Discounts)
+	}
+}
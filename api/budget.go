@@ -0,0 +1,125 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// BudgetStatus tells how the estimated cost of a budget bucket compares to its monthly budget
+type BudgetStatus string
+
+const (
+	// BudgetWithin max limited cost fits in the budget
+	BudgetWithin BudgetStatus = "within"
+	// BudgetAtRisk min requested cost fits in the budget, but max limited cost doesn't
+	BudgetAtRisk BudgetStatus = "atRisk"
+	// BudgetOver even min requested cost exceeds the budget
+	BudgetOver BudgetStatus = "over"
+)
+
+// BudgetResult holds the cost of the objects matching a budget
+type BudgetResult struct {
+	Name          string       `json:"name"`
+	Namespace     string       `json:"namespace,omitempty"`
+	Selector      string       `json:"selector,omitempty"`
+	MonthlyBudget float64      `json:"monthlyBudget"`
+	MinRequested  float64      `json:"minRequested"`
+	MaxLimited    float64      `json:"maxLimited"`
+	Objects       int          `json:"objects"` // number of objects matching the budget
+	Status        BudgetStatus `json:"status"`
+}
+
+// EvaluateBudgets aggregates object costs into the configured budgets
+// An object is counted in every budget matching its namespace and labels
+func EvaluateBudgets(conf CostimatorConfig, cost Cost) ([]BudgetResult, error) {
+	results := []BudgetResult{}
+	for i, b := range conf.BudgetConf.Budgets {
+		selector, err := labels.Parse(b.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid 'budgetConf.budgets[%d].selector'. %+v", i, err)
+		}
+		r := BudgetResult{Name: b.Name, Namespace: b.Namespace, Selector: b.Selector, MonthlyBudget: b.MonthlyUSD, Status: BudgetWithin}
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("budgets[%d]", i)
+		}
+		total := CostRange{}
+		for _, or := range cost.MonthlyObjectRanges {
+			if (b.Namespace == "" || or.Namespace == b.Namespace) && selector.Matches(labels.Set(or.Labels)) {
+				total = total.Add(or.MonthlyRange)
+				r.Objects++
+			}
+		}
+		r.MinRequested, r.MaxLimited = total.MinRequested, total.MaxLimited
+		if r.MinRequested > r.MonthlyBudget {
+			r.Status = BudgetOver
+		} else if r.MaxLimited > r.MonthlyBudget {
+			r.Status = BudgetAtRisk
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// BudgetsExitCode returns the exit code the binary must return for the budget results
+// Budgets over their limit exit as a failed cost policy (11) and budgets at risk as a warned one (10)
+func BudgetsExitCode(results []BudgetResult) int {
+	code := PolicyPassExitCode
+	for _, r := range results {
+		switch r.Status {
+		case BudgetOver:
+			return PolicyFailExitCode
+		case BudgetAtRisk:
+			code = PolicyWarnExitCode
+		}
+	}
+	return code
+}
+
+func budgetsToMarkdown(results []BudgetResult) string {
+	data := [][]string{}
+	for _, r := range results {
+		scope := []string{}
+		if r.Namespace != "" {
+			scope = append(scope, "namespace: "+r.Namespace)
+		}
+		if r.Selector != "" {
+			scope = append(scope, "selector: "+r.Selector)
+		}
+		data = append(data,
+			[]string{r.Name,
+				strings.Join(scope, "<br>"),
+				fmt.Sprintf("%d", r.Objects),
+				currency(r.MonthlyBudget),
+				currency(r.MinRequested),
+				currency(r.MaxLimited),
+				strings.ToUpper(string(r.Status))})
+	}
+
+	out := &strings.Builder{}
+	table := tablewriter.NewWriter(out)
+	table.SetHeader([]string{"Budget", "Scope", "Objects", "Monthly Budget (USD)", headers[0] + " (USD)", headers[4] + " (USD)", "Status"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.SetAutoWrapText(false)
+	table.SetColumnAlignment([]int{0, 0, 2, 2, 2, 2, 0})
+	table.AppendBulk(data)
+	table.Render()
+	return out.String()
+}
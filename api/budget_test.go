@@ -0,0 +1,94 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func budgetTestCost() Cost {
+	frontend := newObjectCostRange("apps/v1|Deployment|shop|frontend", CostRange{Kind: DeploymentKind, MinRequested: 100, MaxRequested: 150, HPABuffer: 110, MinLimited: 200, MaxLimited: 300})
+	frontend.Labels = map[string]string{"team": "web"}
+	mysql := newObjectCostRange("apps/v1|StatefulSet|shop|mysql", CostRange{Kind: StatefulSetKind, MinRequested: 40, MaxRequested: 40, HPABuffer: 40, MinLimited: 40, MaxLimited: 40})
+	mysql.Labels = map[string]string{"team": "data"}
+	etl := newObjectCostRange("batch/v1|CronJob|analytics|etl", CostRange{Kind: CronJobKind, MinRequested: 60, MaxRequested: 60, HPABuffer: 60, MinLimited: 90, MaxLimited: 90})
+	etl.Labels = map[string]string{"team": "data"}
+	return Cost{MonthlyObjectRanges: []ObjectCostRange{frontend, mysql, etl}}
+}
+
+func TestEvaluateBudgets(t *testing.T) {
+	conf := CostimatorConfig{BudgetConf: BudgetConfig{Budgets: []Budget{
+		{Name: "shop", Namespace: "shop", MonthlyUSD: 400},
+		{Name: "data team", Selector: "team=data", MonthlyUSD: 120},
+		{Namespace: "shop", Selector: "team=web", MonthlyUSD: 90},
+		{Name: "nobody", Selector: "team=ghost", MonthlyUSD: 10},
+	}}}
+
+	got, err := EvaluateBudgets(conf, budgetTestCost())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []BudgetResult{
+		{Name: "shop", Namespace: "shop", MonthlyBudget: 400, MinRequested: 140, MaxLimited: 340, Objects: 2, Status: BudgetWithin},
+		{Name: "data team", Selector: "team=data", MonthlyBudget: 120, MinRequested: 100, MaxLimited: 130, Objects: 2, Status: BudgetAtRisk},
+		{Name: "budgets[2]", Namespace: "shop", Selector: "team=web", MonthlyBudget: 90, MinRequested: 100, MaxLimited: 300, Objects: 1, Status: BudgetOver},
+		{Name: "nobody", Selector: "team=ghost", MonthlyBudget: 10, Status: BudgetWithin},
+	}
+	if !cmp.Equal(got, expected) {
+		t.Errorf("Budget results mismatch (-want +got):\n%s", cmp.Diff(expected, got))
+	}
+	if code := BudgetsExitCode(got); code != PolicyFailExitCode {
+		t.Errorf("Expected exit code %d, got %d", PolicyFailExitCode, code)
+	}
+	if code := BudgetsExitCode(got[:2]); code != PolicyWarnExitCode {
+		t.Errorf("Expected exit code %d, got %d", PolicyWarnExitCode, code)
+	}
+	if code := BudgetsExitCode(nil); code != PolicyPassExitCode {
+		t.Errorf("Expected exit code %d, got %d", PolicyPassExitCode, code)
+	}
+}
+
+func TestEvaluateBudgetsInvalidSelector(t *testing.T) {
+	conf := CostimatorConfig{BudgetConf: BudgetConfig{Budgets: []Budget{{Selector: "team in web", MonthlyUSD: 10}}}}
+	_, err := EvaluateBudgets(conf, budgetTestCost())
+	if err == nil || !strings.HasPrefix(err.Error(), "Invalid 'budgetConf.budgets[0].selector'.") {
+		t.Errorf("Should have returned a selector error, but returned '%+v'", err)
+	}
+}
+
+func TestRenderMarkdownWithBudgets(t *testing.T) {
+	conf := CostimatorConfig{BudgetConf: BudgetConfig{Budgets: []Budget{{Name: "data team", Namespace: "analytics", Selector: "team=data", MonthlyUSD: 50}}}}
+	cost := budgetTestCost()
+	budgets, err := EvaluateBudgets(conf, cost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := renderMarkdown(RenderInput{Cost: cost, Budgets: budgets})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"## Budgets",
+		"| data team | namespace: analytics<br>selector: team=data |       1 |               $50.00 |              $60.00 |            $90.00 | OVER   |",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("Markdown should contain '%s', got:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+
+	batchV1 "k8s.io/api/batch/v1"
+)
+
+//decodeCronJob reads k8s CronJob yaml and trasform to CronJob object - mostly used by tests
+func decodeCronJob(data []byte, conf CostimatorConfig) (CronJob, error) {
+	obj, groupVersionKind, err := decode(data)
+	if err != nil {
+		return CronJob{}, fmt.Errorf("Error Decoding. Check if your GroupVersionKind is defined in api/k8s_decoder.go. Root cause %+v", err)
+	}
+	return buildCronJob(obj, groupVersionKind, conf)
+}
+
+//buildCronJob reads k8s CronJob object and trasform to CronJob object
+func buildCronJob(obj interface{}, groupVersionKind GroupVersionKind, conf CostimatorConfig) (CronJob, error) {
+	switch obj.(type) {
+	default:
+		return CronJob{}, fmt.Errorf("APIVersion and Kind not Implemented: %+v", groupVersionKind)
+	case *batchV1.CronJob:
+		return buildCronJobV1(obj.(*batchV1.CronJob), conf)
+	}
+}
+
+func buildCronJobV1(cronjob *batchV1.CronJob, conf CostimatorConfig) (CronJob, error) {
+	conf = populateConfigNotProvided(conf)
+	jobSpec := cronjob.Spec.JobTemplate.Spec
+	containers := buildPodContainers(jobSpec.Template.Spec, conf)
+	runDuration, err := buildRunDuration(cronjob.GetAnnotations(), conf)
+	if err != nil {
+		return CronJob{}, err
+	}
+	runs, err := monthlyRuns(cronjob.Spec.Schedule)
+	if err != nil {
+		return CronJob{}, err
+	}
+	suspended := cronjob.Spec.Suspend != nil && *cronjob.Spec.Suspend
+	if suspended {
+		// suspended cronjobs don't schedule any run, so they cost nothing
+		runs = 0
+	}
+	parallelism, completions := buildParallelismCompletions(jobSpec)
+	return CronJob{
+		APIVersionKindName: buildAPIVersionKindName(cronjob.APIVersion, cronjob.Kind, cronjob.GetNamespace(), cronjob.GetName()),
+		Schedule:           cronjob.Spec.Schedule,
+		Suspended:          suspended,
+		MonthlyRuns:        runs,
+		Parallelism:        parallelism,
+		Completions:        completions,
+		RunDuration:        runDuration,
+		Containers:         containers,
+		PodTemplate:        buildPodTemplate(jobSpec.Template.Spec, cronjob.GetLabels(), jobSpec.Template.GetLabels(), conf),
+	}, nil
+}
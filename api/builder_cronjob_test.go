@@ -0,0 +1,177 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCronJobAPINotImplemented(t *testing.T) {
+	yaml := `
+apiVersion: batch/v1222
+kind: CronJob
+metadata:
+  name: hello
+spec:
+  schedule: "@daily"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: hello
+            image: busybox`
+
+	_, err := decodeCronJob([]byte(yaml), CostimatorConfig{})
+	if err == nil || !strings.HasPrefix(err.Error(), "Error Decoding.") {
+		t.Error(fmt.Errorf("Should have return an APIVersion error, but returned '%+v'", err))
+	}
+}
+
+func TestCronJobBasicV1(t *testing.T) {
+	yaml := `
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: hello
+  namespace: batch
+  annotations:
+    k8s-cost-estimator/run-duration: 2h
+spec:
+  schedule: "0 0 1 * *"
+  jobTemplate:
+    spec:
+      parallelism: 2
+      template:
+        spec:
+          containers:
+          - name: hello
+            image: busybox
+            resources:
+              requests:
+                memory: "64Mi"
+                cpu: "1"
+          restartPolicy: OnFailure`
+
+	cronjob, err := decodeCronJob([]byte(yaml), CostimatorConfig{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expectedAPIVersionKindName := "batch/v1|CronJob|batch|hello"
+	if got := cronjob.APIVersionKindName; got != expectedAPIVersionKindName {
+		t.Errorf("Expected APIVersionKindName %+v, got %+v", expectedAPIVersionKindName, got)
+	}
+	if cronjob.MonthlyRuns != 1 {
+		t.Errorf("Expected MonthlyRuns 1, got %v", cronjob.MonthlyRuns)
+	}
+	if cronjob.Parallelism != 2 || cronjob.Completions != 2 {
+		t.Errorf("Expected Parallelism 2 and Completions 2, got %d and %d", cronjob.Parallelism, cronjob.Completions)
+	}
+	if got := cronjob.RunDuration; got != 2*time.Hour {
+		t.Errorf("Expected RunDuration 2h, got %v", got)
+	}
+	if got := cronjob.Containers[0].Requests.CPU; got != 1000 {
+		t.Errorf("Expected Requests CPU 1000, got %v", got)
+	}
+}
+
+func TestCronJobSuspended(t *testing.T) {
+	yaml := `
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: hello
+spec:
+  schedule: "@daily"
+  suspend: true
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: hello
+            image: busybox
+            resources:
+              requests:
+                cpu: "1"`
+
+	cronjob, err := decodeCronJob([]byte(yaml), CostimatorConfig{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !cronjob.Suspended || cronjob.MonthlyRuns != 0 {
+		t.Errorf("Expected suspended CronJob with MonthlyRuns 0, got %t and %v", cronjob.Suspended, cronjob.MonthlyRuns)
+	}
+	if got := cronjob.estimateCost(&unitResourcePrice{cpu: 10, memory: 10}); got.MinRequested != 0 || got.MaxLimited != 0 {
+		t.Errorf("Expected no cost for a suspended CronJob, got %+v", got)
+	}
+}
+
+func TestCronJobV1beta1(t *testing.T) {
+	yaml := `
+apiVersion: batch/v1beta1
+kind: CronJob
+metadata:
+  name: hello
+spec:
+  schedule: "@daily"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: hello
+            image: busybox`
+
+	cronjob, err := decodeCronJob([]byte(yaml), CostimatorConfig{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got := cronjob.RunDuration; got != time.Hour {
+		t.Errorf("Expected default RunDuration 1h, got %v", got)
+	}
+	if got := cronjob.MonthlyRuns; got != 365/12.0 {
+		t.Errorf("Expected MonthlyRuns %v, got %v", 365/12.0, got)
+	}
+}
+
+func TestCronJobInvalidSchedule(t *testing.T) {
+	yaml := `
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: hello
+spec:
+  schedule: "every day"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: hello
+            image: busybox`
+
+	_, err := decodeCronJob([]byte(yaml), CostimatorConfig{})
+	if err == nil || !strings.HasPrefix(err.Error(), "Invalid cron schedule") {
+		t.Errorf("Should have return a schedule error, but returned '%+v'", err)
+	}
+}
@@ -41,10 +41,13 @@ func buildDaemonSet(obj interface{}, groupVersionKind GroupVersionKind, conf Cos
 
 func buildDaemonSetV1(deploy *appsV1.DaemonSet, conf CostimatorConfig) DaemonSet {
 	conf = populateConfigNotProvided(conf)
-	containers := buildContainers(deploy.Spec.Template.Spec.Containers, conf)
+	containers := buildPodContainers(deploy.Spec.Template.Spec, conf)
 	return DaemonSet{
 		APIVersionKindName: buildAPIVersionKindName(deploy.APIVersion, deploy.Kind, deploy.GetNamespace(), deploy.GetName()),
 		NodesCount:         conf.ClusterConf.NodesCount,
+		MaxNodesCount:      conf.ClusterConf.NodesCount,
 		Containers:         containers,
+		PodTemplate:        buildPodTemplate(deploy.Spec.Template.Spec, deploy.GetLabels(), deploy.Spec.Template.GetLabels(), conf),
+		NodePools:          nodePoolNames(matchNodePools(deploy.Spec.Template.Spec, conf)),
 	}
 }
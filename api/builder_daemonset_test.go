@@ -620,8 +620,8 @@ spec:
 		return
 	}
 
-	if len(daemonset.Containers) != 2 {
-		t.Errorf("Should have ignored initContainers")
+	if len(daemonset.Containers) != 3 {
+		t.Errorf("Should have loaded initContainers")
 	}
 
 	expectedRequestsCPU := float64(0.5)
@@ -41,7 +41,7 @@ func buildDeployment(obj interface{}, groupVersionKind GroupVersionKind, conf Co
 
 func buildDeploymentV1(deploy *appsV1.Deployment, conf CostimatorConfig) Deployment {
 	conf = populateConfigNotProvided(conf)
-	containers := buildContainers(deploy.Spec.Template.Spec.Containers, conf)
+	containers := buildPodContainers(deploy.Spec.Template.Spec, conf)
 	var replicas int32 = 1
 	if deploy.Spec.Replicas != (*int32)(nil) {
 		replicas = *deploy.Spec.Replicas
@@ -50,5 +50,6 @@ func buildDeploymentV1(deploy *appsV1.Deployment, conf CostimatorConfig) Deploym
 		APIVersionKindName: buildAPIVersionKindName(deploy.APIVersion, deploy.Kind, deploy.GetNamespace(), deploy.GetName()),
 		Replicas:           replicas,
 		Containers:         containers,
+		PodTemplate:        buildPodTemplate(deploy.Spec.Template.Spec, deploy.GetLabels(), deploy.Spec.Template.GetLabels(), conf),
 	}
 }
@@ -501,8 +501,8 @@ spec:
 		return
 	}
 
-	if len(deploy.Containers) != 2 {
-		t.Errorf("Should have ignored initContainers")
+	if len(deploy.Containers) != 3 {
+		t.Errorf("Should have loaded initContainers")
 	}
 
 	expectedRequestsCPU := float64(0.5)
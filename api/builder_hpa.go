@@ -47,15 +47,29 @@ func buildHPA(obj interface{}, groupVersionKind GroupVersionKind) (HPA, error) {
 
 func buildHPAV2beta2(hpa *v2beta2.HorizontalPodAutoscaler) HPA {
 	targetCPUPercentage := int32(0)
-	netrics := hpa.Spec.Metrics
-	for i := 0; i < len(netrics); i++ {
-		metric := netrics[i]
-		if metric.Type == v2beta2.ResourceMetricSourceType {
+	metrics := []HPAMetric{}
+	for _, metric := range hpa.Spec.Metrics {
+		switch metric.Type {
+		case v2beta2.ResourceMetricSourceType:
 			res := metric.Resource
 			target := res.Target
 			if res.Name == "cpu" && target.AverageUtilization != (*int32)(nil) {
 				targetCPUPercentage = *target.AverageUtilization
 			}
+			metrics = append(metrics, newHPAMetric(string(metric.Type), string(res.Name), "", target.AverageUtilization, target.AverageValue, target.Value))
+		case v2beta2.ContainerResourceMetricSourceType:
+			res := metric.ContainerResource
+			target := res.Target
+			metrics = append(metrics, newHPAMetric(string(metric.Type), string(res.Name), res.Container, target.AverageUtilization, target.AverageValue, target.Value))
+		case v2beta2.PodsMetricSourceType:
+			target := metric.Pods.Target
+			metrics = append(metrics, newHPAMetric(string(metric.Type), metric.Pods.Metric.Name, "", target.AverageUtilization, target.AverageValue, target.Value))
+		case v2beta2.ObjectMetricSourceType:
+			target := metric.Object.Target
+			metrics = append(metrics, newHPAMetric(string(metric.Type), metric.Object.Metric.Name, "", target.AverageUtilization, target.AverageValue, target.Value))
+		case v2beta2.ExternalMetricSourceType:
+			target := metric.External.Target
+			metrics = append(metrics, newHPAMetric(string(metric.Type), metric.External.Metric.Name, "", target.AverageUtilization, target.AverageValue, target.Value))
 		}
 	}
 
@@ -76,19 +90,33 @@ func buildHPAV2beta2(hpa *v2beta2.HorizontalPodAutoscaler) HPA {
 		MinReplicas:         minReplicas,
 		MaxReplicas:         hpa.Spec.MaxReplicas,
 		TargetCPUPercentage: targetCPUPercentage,
+		Metrics:             metrics,
 	}
 }
 
 func buildHPAV2beta1(hpa *v2beta1.HorizontalPodAutoscaler) HPA {
 	targetCPUPercentage := int32(0)
-	netrics := hpa.Spec.Metrics
-	for i := 0; i < len(netrics); i++ {
-		metric := netrics[i]
-		if metric.Type == v2beta1.ResourceMetricSourceType {
+	metrics := []HPAMetric{}
+	for _, metric := range hpa.Spec.Metrics {
+		switch metric.Type {
+		case v2beta1.ResourceMetricSourceType:
 			res := metric.Resource
 			if res.Name == "cpu" && res.TargetAverageUtilization != (*int32)(nil) {
 				targetCPUPercentage = *res.TargetAverageUtilization
 			}
+			metrics = append(metrics, newHPAMetric(string(metric.Type), string(res.Name), "", res.TargetAverageUtilization, res.TargetAverageValue, nil))
+		case v2beta1.ContainerResourceMetricSourceType:
+			res := metric.ContainerResource
+			metrics = append(metrics, newHPAMetric(string(metric.Type), string(res.Name), res.Container, res.TargetAverageUtilization, res.TargetAverageValue, nil))
+		case v2beta1.PodsMetricSourceType:
+			pods := metric.Pods
+			metrics = append(metrics, newHPAMetric(string(metric.Type), pods.MetricName, "", nil, &pods.TargetAverageValue, nil))
+		case v2beta1.ObjectMetricSourceType:
+			object := metric.Object
+			metrics = append(metrics, newHPAMetric(string(metric.Type), object.MetricName, "", nil, object.AverageValue, &object.TargetValue))
+		case v2beta1.ExternalMetricSourceType:
+			external := metric.External
+			metrics = append(metrics, newHPAMetric(string(metric.Type), external.MetricName, "", nil, external.TargetAverageValue, external.TargetValue))
 		}
 	}
 
@@ -109,13 +137,17 @@ func buildHPAV2beta1(hpa *v2beta1.HorizontalPodAutoscaler) HPA {
 		MinReplicas:         minReplicas,
 		MaxReplicas:         hpa.Spec.MaxReplicas,
 		TargetCPUPercentage: targetCPUPercentage,
+		Metrics:             metrics,
 	}
 }
 
 func buildHPAV1(hpa *v1.HorizontalPodAutoscaler) HPA {
 	var targetCPUPercentage int32 = 0
+	metrics := []HPAMetric{}
 	if hpa.Spec.TargetCPUUtilizationPercentage != (*int32)(nil) {
 		targetCPUPercentage = *hpa.Spec.TargetCPUUtilizationPercentage
+		// v1 only supports cpu utilization
+		metrics = append(metrics, newHPAMetric(resourceMetric, "cpu", "", &targetCPUPercentage, nil, nil))
 	}
 	var minReplicas int32 = 1
 	if hpa.Spec.MinReplicas != (*int32)(nil) {
@@ -128,5 +160,6 @@ func buildHPAV1(hpa *v1.HorizontalPodAutoscaler) HPA {
 		MinReplicas:         minReplicas,
 		MaxReplicas:         hpa.Spec.MaxReplicas,
 		TargetCPUPercentage: targetCPUPercentage,
+		Metrics:             metrics,
 	}
 }
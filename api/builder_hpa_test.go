@@ -20,6 +20,8 @@ import (
 	"io/ioutil"
 	"strings"
 	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 func TestHPAAPINotImplemented(t *testing.T) {
@@ -150,6 +152,91 @@ spec:
 	}
 }
 
+func TestHPAMetricsV2beta2(t *testing.T) {
+	yaml := `apiVersion: autoscaling/v2beta2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: php-apache
+spec:
+  maxReplicas: 20
+  minReplicas: 10
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: php-apache
+  metrics:
+  - type: Resource
+    resource:
+      name: cpu
+      target:
+        type: Utilization
+        averageUtilization: 60
+  - type: Resource
+    resource:
+      name: memory
+      target:
+        type: AverageValue
+        averageValue: 500Mi
+  - type: ContainerResource
+    containerResource:
+      name: cpu
+      container: application
+      target:
+        type: Utilization
+        averageUtilization: 70
+  - type: Pods
+    pods:
+      metric:
+        name: packets-per-second
+      target:
+        type: AverageValue
+        averageValue: 1k
+  - type: Object
+    object:
+      metric:
+        name: requests-per-second
+      describedObject:
+        apiVersion: networking.k8s.io/v1
+        kind: Ingress
+        name: main-route
+      target:
+        type: Value
+        value: 10k
+  - type: External
+    external:
+      metric:
+        name: queue_messages_ready
+      target:
+        type: AverageValue
+        averageValue: 30`
+
+	hpa, err := decodeHPA([]byte(yaml))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if got := hpa.TargetCPUPercentage; got != 60 {
+		t.Errorf("Expected target CPU 60, got %+v", got)
+	}
+	expected := []string{
+		"cpu: 60% utilization",
+		"memory: 500Mi average value",
+		"cpu (container application): 70% utilization",
+		"pods/packets-per-second: 1k average value",
+		"object/requests-per-second: 10k value",
+		"external/queue_messages_ready: 30 average value",
+	}
+	if len(hpa.Metrics) != len(expected) {
+		t.Fatalf("Expected %d metrics, got %+v", len(expected), hpa.Metrics)
+	}
+	for i, want := range expected {
+		if got := hpa.Metrics[i].String(); got != want {
+			t.Errorf("Expected metric %d to be '%s', got '%s'", i, want, got)
+		}
+	}
+}
+
 func TestHPABasicV2beta1(t *testing.T) {
 	yaml := `
 apiVersion: autoscaling/v2beta1
@@ -381,6 +468,116 @@ func TestHPADecodeListV2Beta1(t *testing.T) {
 	}
 }
 
+func TestHPAMetricsV2beta1(t *testing.T) {
+	yaml := `
+apiVersion: autoscaling/v2beta1
+kind: HorizontalPodAutoscaler
+metadata:
+  name: frontend-scaler
+spec:
+  scaleTargetRef:
+    kind: Deployment
+    name: frobinator-frontend
+  minReplicas: 2
+  maxReplicas: 10
+  metrics:
+  - type: Resource
+    resource:
+      name: memory
+      targetAverageUtilization: 50
+  - type: ContainerResource
+    containerResource:
+      name: memory
+      container: application
+      targetAverageValue: 1Gi
+  - type: Pods
+    pods:
+      metricName: packets-per-second
+      targetAverageValue: 1k
+  - type: Object
+    object:
+      target:
+        kind: Service
+        name: sample-app
+      metricName: requests-per-second
+      targetValue: 2k
+  - type: External
+    external:
+      metricName: queue_messages_ready
+      targetValue: 30`
+
+	hpa, err := decodeHPA([]byte(yaml))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if got := hpa.TargetCPUPercentage; got != 0 {
+		t.Errorf("Expected no target CPU, got %+v", got)
+	}
+	expected := []string{
+		"memory: 50% utilization",
+		"memory (container application): 1Gi average value",
+		"pods/packets-per-second: 1k average value",
+		"object/requests-per-second: 2k value",
+		"external/queue_messages_ready: 30 value",
+	}
+	if len(hpa.Metrics) != len(expected) {
+		t.Fatalf("Expected %d metrics, got %+v", len(expected), hpa.Metrics)
+	}
+	for i, want := range expected {
+		if got := hpa.Metrics[i].String(); got != want {
+			t.Errorf("Expected metric %d to be '%s', got '%s'", i, want, got)
+		}
+	}
+}
+
+func TestHPABufferTarget(t *testing.T) {
+	containers := []Container{
+		{Name: "application", Requests: Resource{CPU: 1000, Memory: 1024 * 1024 * 1024}},
+		{Name: "proxy", Requests: Resource{CPU: 1000, Memory: 1024 * 1024 * 1024}},
+		{Name: "init", Requests: Resource{CPU: 4000, Memory: 4 * 1024 * 1024 * 1024}, Type: InitContainer},
+	}
+	cpu60, memory50 := int32(60), int32(50)
+	memoryValue := resource.MustParse("1Gi")     // 50% of the 2Gi requested by the pod
+	cpuValue := resource.MustParse("800m")       // 80% of the application container 1 vCPU
+	customValue := resource.MustParse("1")       // custom metrics are not related to requests
+	highMemoryValue := resource.MustParse("4Gi") // above requests
+
+	tests := []struct {
+		name            string
+		hpa             HPA
+		wantFound       bool
+		wantUtilization int32
+		wantMetric      string
+	}{
+		{"no target", HPA{}, false, 0, ""},
+		{"only custom metrics", HPA{Metrics: []HPAMetric{newHPAMetric("Pods", "packets-per-second", "", nil, &customValue, nil)}}, false, 0, ""},
+		{"cpu target without metrics", HPA{TargetCPUPercentage: 70}, true, 70, "cpu: 70% utilization"},
+		{"memory is more restrictive", HPA{Metrics: []HPAMetric{
+			newHPAMetric("Resource", "cpu", "", &cpu60, nil, nil),
+			newHPAMetric("Resource", "memory", "", &memory50, nil, nil)}}, true, 50, "memory: 50% utilization"},
+		{"memory average value", HPA{Metrics: []HPAMetric{
+			newHPAMetric("Resource", "cpu", "", &cpu60, nil, nil),
+			newHPAMetric("Resource", "memory", "", nil, &memoryValue, nil)}}, true, 50, "memory: 1Gi average value (50% of requests)"},
+		{"container cpu average value", HPA{Metrics: []HPAMetric{
+			newHPAMetric("ContainerResource", "cpu", "application", nil, &cpuValue, nil)}}, true, 80, "cpu (container application): 800m average value (80% of requests)"},
+		{"target above requests", HPA{Metrics: []HPAMetric{
+			newHPAMetric("Resource", "memory", "", nil, &highMemoryValue, nil)}}, true, 100, "memory: 4Gi average value (100% of requests)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, utilization, found := tt.hpa.bufferTarget(containers)
+			if found != tt.wantFound || utilization != tt.wantUtilization {
+				t.Errorf("bufferTarget() = %d, %v, want %d, %v", utilization, found, tt.wantUtilization, tt.wantFound)
+			}
+			if got := tt.hpa.bufferMetricDescription(containers); got != tt.wantMetric {
+				t.Errorf("bufferMetricDescription() = '%s', want '%s'", got, tt.wantMetric)
+			}
+		})
+	}
+}
+
 func TestHPADecodeListV1(t *testing.T) {
 	hpas := readHPAsFromFile("./testdata/hpa/hpa-v1.yaml", t)
 	noUtilizationDefined := 0
@@ -0,0 +1,87 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"time"
+
+	batchV1 "k8s.io/api/batch/v1"
+)
+
+//decodeJob reads k8s Job yaml and trasform to Job object - mostly used by tests
+func decodeJob(data []byte, conf CostimatorConfig) (Job, error) {
+	obj, groupVersionKind, err := decode(data)
+	if err != nil {
+		return Job{}, fmt.Errorf("Error Decoding. Check if your GroupVersionKind is defined in api/k8s_decoder.go. Root cause %+v", err)
+	}
+	return buildJob(obj, groupVersionKind, conf)
+}
+
+//buildJob reads k8s Job object and trasform to Job object
+func buildJob(obj interface{}, groupVersionKind GroupVersionKind, conf CostimatorConfig) (Job, error) {
+	switch obj.(type) {
+	default:
+		return Job{}, fmt.Errorf("APIVersion and Kind not Implemented: %+v", groupVersionKind)
+	case *batchV1.Job:
+		return buildJobV1(obj.(*batchV1.Job), conf)
+	}
+}
+
+func buildJobV1(job *batchV1.Job, conf CostimatorConfig) (Job, error) {
+	conf = populateConfigNotProvided(conf)
+	containers := buildPodContainers(job.Spec.Template.Spec, conf)
+	runDuration, err := buildRunDuration(job.GetAnnotations(), conf)
+	if err != nil {
+		return Job{}, err
+	}
+	parallelism, completions := buildParallelismCompletions(job.Spec)
+	return Job{
+		APIVersionKindName: buildAPIVersionKindName(job.APIVersion, job.Kind, job.GetNamespace(), job.GetName()),
+		Parallelism:        parallelism,
+		Completions:        completions,
+		RunDuration:        runDuration,
+		Containers:         containers,
+		PodTemplate:        buildPodTemplate(job.Spec.Template.Spec, job.GetLabels(), job.Spec.Template.GetLabels(), conf),
+	}, nil
+}
+
+// buildParallelismCompletions applies k8s defaults. If completions is not set, job ends once all parallel pods are done (work queue)
+func buildParallelismCompletions(spec batchV1.JobSpec) (parallelism int32, completions int32) {
+	parallelism = 1
+	if spec.Parallelism != (*int32)(nil) {
+		parallelism = *spec.Parallelism
+	}
+	completions = parallelism
+	if spec.Completions != (*int32)(nil) {
+		completions = *spec.Completions
+	}
+	return
+}
+
+func buildRunDuration(annotations map[string]string, conf CostimatorConfig) (time.Duration, error) {
+	value, ok := annotations[RunDurationAnnotation]
+	if !ok || value == "" {
+		return time.Duration(conf.ResourceConf.DefaultJobRunDurationInMinutes) * time.Minute, nil
+	}
+	runDuration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid annotation '%s: %s'. Root cause %+v", RunDurationAnnotation, value, err)
+	}
+	if runDuration <= 0 {
+		return 0, fmt.Errorf("Invalid annotation '%s: %s'. Run duration must be greater than zero", RunDurationAnnotation, value)
+	}
+	return runDuration, nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJobAPINotImplemented(t *testing.T) {
+	yaml := `
+apiVersion: batch/v1222
+kind: Job
+metadata:
+  name: pi
+spec:
+  template:
+    spec:
+      containers:
+      - name: pi
+        image: perl`
+
+	_, err := decodeJob([]byte(yaml), CostimatorConfig{})
+	if err == nil || !strings.HasPrefix(err.Error(), "Error Decoding.") {
+		t.Error(fmt.Errorf("Should have return an APIVersion error, but returned '%+v'", err))
+	}
+}
+
+func TestJobBasicV1(t *testing.T) {
+	yaml := `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: pi
+  annotations:
+    k8s-cost-estimator/run-duration: 90m
+spec:
+  parallelism: 2
+  completions: 6
+  template:
+    spec:
+      containers:
+      - name: pi
+        image: perl
+        resources:
+          requests:
+            memory: "64Mi"
+            cpu: "250m"
+      restartPolicy: Never`
+
+	job, err := decodeJob([]byte(yaml), CostimatorConfig{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expectedAPIVersionKindName := "batch/v1|Job|default|pi"
+	if got := job.APIVersionKindName; got != expectedAPIVersionKindName {
+		t.Errorf("Expected APIVersionKindName %+v, got %+v", expectedAPIVersionKindName, got)
+	}
+	if job.Parallelism != 2 || job.Completions != 6 {
+		t.Errorf("Expected Parallelism 2 and Completions 6, got %d and %d", job.Parallelism, job.Completions)
+	}
+	if got := job.RunDuration; got != 90*time.Minute {
+		t.Errorf("Expected RunDuration 90m, got %v", got)
+	}
+	if got := job.Containers[0].Requests.CPU; got != 250 {
+		t.Errorf("Expected Requests CPU 250, got %v", got)
+	}
+}
+
+func TestJobDefaults(t *testing.T) {
+	yaml := `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: pi
+spec:
+  parallelism: 3
+  template:
+    spec:
+      containers:
+      - name: pi
+        image: perl`
+
+	conf := CostimatorConfig{ResourceConf: ResourceConfig{DefaultJobRunDurationInMinutes: 15}}
+	job, err := decodeJob([]byte(yaml), conf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// work queue jobs run all parallel pods
+	if job.Parallelism != 3 || job.Completions != 3 {
+		t.Errorf("Expected Parallelism 3 and Completions 3, got %d and %d", job.Parallelism, job.Completions)
+	}
+	if got := job.RunDuration; got != 15*time.Minute {
+		t.Errorf("Expected RunDuration 15m, got %v", got)
+	}
+}
+
+func TestJobInvalidRunDuration(t *testing.T) {
+	yaml := `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: pi
+  annotations:
+    k8s-cost-estimator/run-duration: %s
+spec:
+  template:
+    spec:
+      containers:
+      - name: pi
+        image: perl`
+
+	for _, runDuration := range []string{"forever", "0s", "-1h"} {
+		_, err := decodeJob([]byte(fmt.Sprintf(yaml, runDuration)), CostimatorConfig{})
+		if err == nil || !strings.Contains(err.Error(), RunDurationAnnotation) {
+			t.Errorf("Run duration '%s' should have return an annotation error, but returned '%+v'", runDuration, err)
+		}
+	}
+}
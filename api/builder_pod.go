@@ -0,0 +1,50 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+
+	coreV1 "k8s.io/api/core/v1"
+)
+
+//decodePod reads k8s pod yaml and trasform to Pod object - mainly used by tests
+func decodePod(data []byte, conf CostimatorConfig) (Pod, error) {
+	obj, groupVersionKind, err := decode(data)
+	if err != nil {
+		return Pod{}, fmt.Errorf("Error Decoding. Check if your GroupVersionKind is defined in api/k8s_decoder.go. Root cause %+v", err)
+	}
+	return buildPod(obj, groupVersionKind, conf)
+}
+
+//buildPod reads k8s pod object and trasform to Pod object
+func buildPod(obj interface{}, groupVersionKind GroupVersionKind, conf CostimatorConfig) (Pod, error) {
+	switch obj.(type) {
+	default:
+		return Pod{}, fmt.Errorf("APIVersion and Kind not Implemented: %+v", groupVersionKind)
+	case *coreV1.Pod:
+		return buildPodV1(obj.(*coreV1.Pod), conf), nil
+	}
+}
+
+func buildPodV1(pod *coreV1.Pod, conf CostimatorConfig) Pod {
+	conf = populateConfigNotProvided(conf)
+	containers := buildPodContainers(pod.Spec, conf)
+	return Pod{
+		APIVersionKindName: buildAPIVersionKindName(pod.APIVersion, pod.Kind, pod.GetNamespace(), pod.GetName()),
+		Containers:         containers,
+		PodTemplate:        buildPodTemplate(pod.Spec, pod.GetLabels(), nil, conf),
+	}
+}
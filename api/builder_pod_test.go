@@ -0,0 +1,181 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPodAPINotImplemented(t *testing.T) {
+	yaml := `
+apiVersion: v1222
+kind: Pod
+metadata:
+  name: nginx
+spec:
+  containers:
+  - name: nginx
+    image: nginx`
+
+	_, err := decodePod([]byte(yaml), CostimatorConfig{})
+	if err == nil || !strings.HasPrefix(err.Error(), "Error Decoding.") {
+		t.Error(fmt.Errorf("Should have return an APIVersion error, but returned '%+v'", err))
+	}
+}
+
+func TestPodBasicV1(t *testing.T) {
+	yaml := `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: nginx
+  namespace: legacy
+spec:
+  containers:
+  - name: nginx
+    image: nginx
+    resources:
+      requests:
+        memory: "64Mi"
+        cpu: "250m"
+      limits:
+        memory: "128Mi"
+        cpu: "500m"
+  - name: sidecar
+    image: busybox`
+
+	pod, err := decodePod([]byte(yaml), CostimatorConfig{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expectedAPIVersionKindName := "v1|Pod|legacy|nginx"
+	if got := pod.APIVersionKindName; got != expectedAPIVersionKindName {
+		t.Errorf("Expected APIVersionKindName %+v, got %+v", expectedAPIVersionKindName, got)
+	}
+	if got := len(pod.Containers); got != 2 {
+		t.Errorf("Expected 2 containers, got %+v", got)
+	}
+	if got := pod.getReplicas(); got != 1 {
+		t.Errorf("Expected 1 replica, got %+v", got)
+	}
+	container := pod.Containers[0]
+	if got := container.Requests.CPU; got != 250 {
+		t.Errorf("Expected Requests CPU 250, got %+v", got)
+	}
+	if got := container.Limits.Memory; got != 134217728 {
+		t.Errorf("Expected Limits Memory 134217728, got %+v", got)
+	}
+	defaults := ConfigDefaults().ResourceConf
+	if got := pod.Containers[1].Requests.CPU; got != defaults.DefaultCPUinMillis {
+		t.Errorf("Expected default Requests CPU %+v, got %+v", defaults.DefaultCPUinMillis, got)
+	}
+}
+
+func TestPodInitContainersSidecarsAndOverhead(t *testing.T) {
+	yaml := `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: nginx
+spec:
+  overhead:
+    cpu: 250m
+    memory: 120Mi
+  initContainers:
+  - name: migrate
+    image: busybox
+    resources:
+      requests:
+        memory: "1Gi"
+        cpu: "2"
+  - name: proxy
+    image: envoy
+    restartPolicy: Always
+    resources:
+      requests:
+        memory: "128Mi"
+        cpu: "500m"
+  containers:
+  - name: nginx
+    image: nginx
+    resources:
+      requests:
+        memory: "256Mi"
+        cpu: "1"`
+
+	pod, err := decodePod([]byte(yaml), CostimatorConfig{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expectedTypes := []ContainerType{InitContainer, SidecarContainer, AppContainer, PodOverhead}
+	if got := len(pod.Containers); got != len(expectedTypes) {
+		t.Fatalf("Expected %d containers, got %+v", len(expectedTypes), got)
+	}
+	for i, expected := range expectedTypes {
+		if got := pod.Containers[i].Type; got != expected {
+			t.Errorf("Expected container %d to be of type %+v, got %+v", i, expected, got)
+		}
+	}
+
+	// cpu: max(2, 1 + 0.5) + 0.25, memory: max(1Gi, 256Mi + 128Mi) + 120Mi
+	requests, _ := sumContainers(pod.Containers)
+	expected := Resource{CPU: 2250, Memory: 1144 * 1024 * 1024}
+	if requests != expected {
+		t.Errorf("Expected effective requests %+v, got %+v", expected, requests)
+	}
+}
+
+func TestPodInitContainersWithoutRequests(t *testing.T) {
+	yaml := `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: nginx
+spec:
+  initContainers:
+  - name: migrate
+    image: busybox
+  - name: proxy
+    image: envoy
+    restartPolicy: Always
+  containers:
+  - name: nginx
+    image: nginx`
+
+	conf := CostimatorConfig{ResourceConf: ResourceConfig{DefaultCPUinMillis: 100, DefaultMemoryinBytes: 64 * 1024 * 1024}}
+	pod, err := decodePod([]byte(yaml), conf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// defaults are only applied to long running containers (sidecars and app containers)
+	expected := []Resource{{}, {CPU: 100, Memory: 64 * 1024 * 1024}, {CPU: 100, Memory: 64 * 1024 * 1024}}
+	for i, want := range expected {
+		if got := pod.Containers[i].Requests; got != want {
+			t.Errorf("Expected container '%s' requests %+v, got %+v", pod.Containers[i].Name, want, got)
+		}
+	}
+	requests, _ := sumContainers(pod.Containers)
+	if want := (Resource{CPU: 200, Memory: 128 * 1024 * 1024}); requests != want {
+		t.Errorf("Expected effective requests %+v, got %+v", want, requests)
+	}
+}
@@ -41,7 +41,7 @@ func buildReplicaSet(obj interface{}, groupVersionKind GroupVersionKind, conf Co
 
 func buildReplicaSetV1(replicaset *appsV1.ReplicaSet, conf CostimatorConfig) ReplicaSet {
 	conf = populateConfigNotProvided(conf)
-	containers := buildContainers(replicaset.Spec.Template.Spec.Containers, conf)
+	containers := buildPodContainers(replicaset.Spec.Template.Spec, conf)
 	var replicas int32 = 1
 	if replicaset.Spec.Replicas != (*int32)(nil) {
 		replicas = *replicaset.Spec.Replicas
@@ -50,5 +50,6 @@ func buildReplicaSetV1(replicaset *appsV1.ReplicaSet, conf CostimatorConfig) Rep
 		APIVersionKindName: buildAPIVersionKindName(replicaset.APIVersion, replicaset.Kind, replicaset.GetNamespace(), replicaset.GetName()),
 		Replicas:           replicas,
 		Containers:         containers,
+		PodTemplate:        buildPodTemplate(replicaset.Spec.Template.Spec, replicaset.GetLabels(), replicaset.Spec.Template.GetLabels(), conf),
 	}
 }
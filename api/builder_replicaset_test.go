@@ -495,8 +495,8 @@ spec:
 		return
 	}
 
-	if len(replicaset.Containers) != 2 {
-		t.Errorf("Should have ignored initContainers")
+	if len(replicaset.Containers) != 3 {
+		t.Errorf("Should have loaded initContainers")
 	}
 
 	expectedRequestsCPU := float64(0.5)
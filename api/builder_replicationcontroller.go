@@ -0,0 +1,63 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+
+	coreV1 "k8s.io/api/core/v1"
+)
+
+//decodeReplicationController reads k8s replicationController yaml and trasform to ReplicationController object - mainly used by tests
+func decodeReplicationController(data []byte, conf CostimatorConfig) (ReplicationController, error) {
+	obj, groupVersionKind, err := decode(data)
+	if err != nil {
+		return ReplicationController{}, fmt.Errorf("Error Decoding. Check if your GroupVersionKind is defined in api/k8s_decoder.go. Root cause %+v", err)
+	}
+	return buildReplicationController(obj, groupVersionKind, conf)
+}
+
+//buildReplicationController reads k8s replicationController object and trasform to ReplicationController object
+func buildReplicationController(obj interface{}, groupVersionKind GroupVersionKind, conf CostimatorConfig) (ReplicationController, error) {
+	switch obj.(type) {
+	default:
+		return ReplicationController{}, fmt.Errorf("APIVersion and Kind not Implemented: %+v", groupVersionKind)
+	case *coreV1.ReplicationController:
+		return buildReplicationControllerV1(obj.(*coreV1.ReplicationController), conf), nil
+	}
+}
+
+func buildReplicationControllerV1(rc *coreV1.ReplicationController, conf CostimatorConfig) ReplicationController {
+	conf = populateConfigNotProvided(conf)
+	containers := []Container{}
+	spec := coreV1.PodSpec{}
+	var templateLabels map[string]string
+	// unlike other controllers, the pod template is optional in ReplicationController
+	if rc.Spec.Template != nil {
+		spec = rc.Spec.Template.Spec
+		templateLabels = rc.Spec.Template.GetLabels()
+		containers = buildPodContainers(spec, conf)
+	}
+	var replicas int32 = 1
+	if rc.Spec.Replicas != (*int32)(nil) {
+		replicas = *rc.Spec.Replicas
+	}
+	return ReplicationController{
+		APIVersionKindName: buildAPIVersionKindName(rc.APIVersion, rc.Kind, rc.GetNamespace(), rc.GetName()),
+		Replicas:           replicas,
+		Containers:         containers,
+		PodTemplate:        buildPodTemplate(spec, rc.GetLabels(), templateLabels, conf),
+	}
+}
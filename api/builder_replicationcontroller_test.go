@@ -0,0 +1,109 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReplicationControllerAPINotImplemented(t *testing.T) {
+	yaml := `
+apiVersion: v1222
+kind: ReplicationController
+metadata:
+  name: nginx
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: nginx
+        image: nginx`
+
+	_, err := decodeReplicationController([]byte(yaml), CostimatorConfig{})
+	if err == nil || !strings.HasPrefix(err.Error(), "Error Decoding.") {
+		t.Error(fmt.Errorf("Should have return an APIVersion error, but returned '%+v'", err))
+	}
+}
+
+func TestReplicationControllerBasicV1(t *testing.T) {
+	yaml := `
+apiVersion: v1
+kind: ReplicationController
+metadata:
+  name: nginx
+spec:
+  replicas: 3
+  selector:
+    app: nginx
+  template:
+    metadata:
+      labels:
+        app: nginx
+    spec:
+      containers:
+      - name: nginx
+        image: nginx
+        resources:
+          requests:
+            memory: "64Mi"
+            cpu: "250m"
+          limits:
+            memory: "64Mi"
+            cpu: 1`
+
+	rc, err := decodeReplicationController([]byte(yaml), CostimatorConfig{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expectedAPIVersionKindName := "v1|ReplicationController|default|nginx"
+	if got := rc.APIVersionKindName; got != expectedAPIVersionKindName {
+		t.Errorf("Expected APIVersionKindName %+v, got %+v", expectedAPIVersionKindName, got)
+	}
+	if got := rc.Replicas; got != 3 {
+		t.Errorf("Expected 3 replicas, got %+v", got)
+	}
+	container := rc.Containers[0]
+	if got := container.Requests.CPU; got != 250 {
+		t.Errorf("Expected Requests CPU 250, got %+v", got)
+	}
+	if got := container.Limits.CPU; got != 1000 {
+		t.Errorf("Expected Limits CPU 1000, got %+v", got)
+	}
+}
+
+func TestReplicationControllerNoReplicasNoTemplate(t *testing.T) {
+	yaml := `
+apiVersion: v1
+kind: ReplicationController
+metadata:
+  name: nginx`
+
+	rc, err := decodeReplicationController([]byte(yaml), CostimatorConfig{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got := rc.Replicas; got != 1 {
+		t.Errorf("Expected 1 replica, got %+v", got)
+	}
+	if got := len(rc.Containers); got != 0 {
+		t.Errorf("Expected no containers, got %+v", got)
+	}
+}
@@ -41,12 +41,13 @@ func buildStatefulSet(obj interface{}, groupVersionKind GroupVersionKind, conf C
 
 func buildStatefulSetV1(statefulset *appsV1.StatefulSet, conf CostimatorConfig) (StatefulSet, error) {
 	conf = populateConfigNotProvided(conf)
-	containers := buildContainers(statefulset.Spec.Template.Spec.Containers, conf)
+	containers := buildPodContainers(statefulset.Spec.Template.Spec, conf)
 	var replicas int32 = 1
 	if statefulset.Spec.Replicas != (*int32)(nil) {
 		replicas = *statefulset.Spec.Replicas
 	}
 
+	podTemplate := buildPodTemplate(statefulset.Spec.Template.Spec, statefulset.GetLabels(), statefulset.Spec.Template.GetLabels(), conf)
 	volumeClaims := []*VolumeClaim{}
 	for _, vct := range statefulset.Spec.VolumeClaimTemplates {
 		groupVersionKind := GroupVersionKind{Kind: VolumeClaimKind}
@@ -54,6 +55,8 @@ func buildStatefulSetV1(statefulset *appsV1.StatefulSet, conf CostimatorConfig)
 		if err != nil {
 			return StatefulSet{}, err
 		}
+		// claims created from templates are budgeted with their StatefulSet
+		pvc.Labels = buildLabels(pvc.Labels, podTemplate.Labels)
 		volumeClaims = append(volumeClaims, &pvc)
 	}
 
@@ -61,6 +64,7 @@ func buildStatefulSetV1(statefulset *appsV1.StatefulSet, conf CostimatorConfig)
 		APIVersionKindName: buildAPIVersionKindName(statefulset.APIVersion, statefulset.Kind, statefulset.GetNamespace(), statefulset.GetName()),
 		Replicas:           replicas,
 		Containers:         containers,
+		PodTemplate:        podTemplate,
 		VolumeClaims:       volumeClaims,
 	}, nil
 }
@@ -18,6 +18,8 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestStatefulSetAPINotImplemented(t *testing.T) {
@@ -659,8 +661,8 @@ spec:
 		return
 	}
 
-	if len(deploy.Containers) != 2 {
-		t.Errorf("Should have ignored initContainers")
+	if len(deploy.Containers) != 3 {
+		t.Errorf("Should have loaded initContainers")
 	}
 
 	expectedRequestsCPU := float64(0.5)
@@ -747,3 +749,54 @@ func TestStatefulSetVolumeClaimTemplates(t *testing.T) {
 		t.Errorf("Expected Limits Storage %+v, got %+v", expectedStorage, got)
 	}
 }
+
+func TestStatefulSetLabels(t *testing.T) {
+	yaml := `
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: my-nginx
+  labels:
+    team: payments
+    tier: web
+spec:
+  selector:
+    matchLabels:
+      app: nginx
+  serviceName: "nginx"
+  template:
+    metadata:
+      labels:
+        app: nginx
+        tier: frontend # object labels win
+    spec:
+      containers:
+      - name: nginx
+        image: k8s.gcr.io/nginx-slim:0.8
+  volumeClaimTemplates:
+  - metadata:
+      name: www
+      labels:
+        tier: storage # claim labels win
+    spec:
+      accessModes: [ "ReadWriteOnce" ]
+      resources:
+        requests:
+          storage: 1Gi`
+
+	statefulset, err := decodeStatefulSet([]byte(yaml), CostimatorConfig{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := map[string]string{"app": "nginx", "team": "payments", "tier": "web"}
+	if got := statefulset.Labels; !cmp.Equal(got, expected) {
+		t.Errorf("Expected Labels %+v, got %+v", expected, got)
+	}
+	// volume claims are budgeted with their StatefulSet
+	expected = map[string]string{"app": "nginx", "team": "payments", "tier": "storage"}
+	if got := statefulset.VolumeClaims[0].Labels; !cmp.Equal(got, expected) {
+		t.Errorf("Expected VolumeClaim Labels %+v, got %+v", expected, got)
+	}
+}
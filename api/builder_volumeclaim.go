@@ -60,7 +60,9 @@ func buildVolumeClaimV1(volume *coreV1.PersistentVolumeClaim, conf CostimatorCon
 	return VolumeClaim{
 		APIVersionKindName: buildAPIVersionKindName(volume.APIVersion, VolumeClaimKind, volume.GetNamespace(), volume.GetName()),
 		StorageClass:       storageClass,
+		Disk:               conf.ClusterConf.persistentDisk(storageClass),
 		Requests:           Resource{Storage: requests},
 		Limits:             Resource{Storage: limits},
+		Labels:             buildLabels(volume.GetLabels(), nil),
 	}
 }
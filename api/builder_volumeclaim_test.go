@@ -103,3 +103,37 @@ func TestVolumeClaimCustomStorageClass(t *testing.T) {
 		t.Errorf("Expected StorageClassName %+v, got %+v", storageClass, got)
 	}
 }
+
+func TestVolumeClaimPersistentDisk(t *testing.T) {
+	yaml := `
+kind: PersistentVolumeClaim
+apiVersion: v1
+metadata:
+  name: my-volumeclaim
+spec:
+  storageClassName: %s
+  resources:
+    requests:
+      storage: 10Gi`
+
+	volume, err := decodeVolumeClaim([]byte(fmt.Sprintf(yaml, "premium-rwo")), CostimatorConfig{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := volume.Disk, (PersistentDisk{DiskType: PdSSD, Replication: Zonal}); got != want {
+		t.Errorf("Expected Disk %s, got %s", want, got)
+	}
+
+	conf := CostimatorConfig{ClusterConf: ClusterConfig{StorageClasses: []StorageClassConfig{
+		{Name: "regional-ssd", PersistentDisk: PersistentDisk{DiskType: PdSSD, Replication: Regional}},
+	}}}
+	volume, err = decodeVolumeClaim([]byte(fmt.Sprintf(yaml, "regional-ssd")), conf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got, want := volume.Disk, (PersistentDisk{DiskType: PdSSD, Replication: Regional}); got != want {
+		t.Errorf("Expected Disk %s, got %s", want, got)
+	}
+}
@@ -14,6 +14,15 @@
 
 package api
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
 // MachineFamily type
 type MachineFamily string
 
@@ -26,26 +35,173 @@ const (
 	N2 MachineFamily = "N2"
 	// N2D machines
 	N2D MachineFamily = "N2D"
+	// C2 compute-optimized machines
+	C2 MachineFamily = "C2"
+	// C2D compute-optimized machines
+	C2D MachineFamily = "C2D"
+	// T2D Tau machines
+	T2D MachineFamily = "T2D"
+	// M1 memory-optimized machines
+	M1 MachineFamily = "M1"
+	// A2 accelerator-optimized machines
+	A2 MachineFamily = "A2"
+)
+
+// DiskType is the GCE Persistent Disk type
+type DiskType string
+
+const (
+	// PdStandard is the standard persistent disk (HDD)
+	PdStandard DiskType = "standard"
+	// PdBalanced is the balanced persistent disk (SSD)
+	PdBalanced DiskType = "balanced"
+	// PdSSD is the performance persistent disk (SSD)
+	PdSSD DiskType = "ssd"
+	// PdExtreme is the extreme persistent disk (SSD)
+	PdExtreme DiskType = "extreme"
+)
+
+// DiskReplication tells if a GCE Persistent Disk is replicated in one or two zones
+type DiskReplication string
+
+const (
+	// Zonal disks live in a single zone
+	Zonal DiskReplication = "zonal"
+	// Regional disks are synchronously replicated in two zones of the same region
+	Regional DiskReplication = "regional"
+)
+
+// PersistentDisk is the GCE Persistent Disk backing a PersistentVolumeClaim
+type PersistentDisk struct {
+	DiskType    DiskType        `yaml:"diskType,omitempty" json:"diskType,omitempty"`
+	Replication DiskReplication `yaml:"replication,omitempty" json:"replication,omitempty"`
+}
+
+func (d PersistentDisk) String() string {
+	return fmt.Sprintf("pd-%s (%s)", d.DiskType, d.Replication)
+}
+
+// StorageClassConfig maps a k8s StorageClass to the GCE Persistent Disk used to price it
+type StorageClassConfig struct {
+	Name           string `yaml:"name,omitempty" json:"name,omitempty"`
+	PersistentDisk `yaml:",inline"`
+}
+
+// defaultPersistentDisk is used for StorageClasses not mapped in config
+var defaultPersistentDisk = PersistentDisk{DiskType: PdStandard, Replication: Zonal}
+
+// gkeStorageClasses maps the StorageClasses GKE creates by default
+var gkeStorageClasses = []StorageClassConfig{
+	{Name: "standard", PersistentDisk: PersistentDisk{DiskType: PdStandard, Replication: Zonal}},
+	{Name: "standard-rwo", PersistentDisk: PersistentDisk{DiskType: PdBalanced, Replication: Zonal}},
+	{Name: "premium-rwo", PersistentDisk: PersistentDisk{DiskType: PdSSD, Replication: Zonal}},
+}
+
+// ClusterMode is the GKE mode of operation, which defines how workloads are billed
+type ClusterMode string
+
+const (
+	// Standard clusters are billed by the nodes (GCE instances) running the workloads
+	Standard ClusterMode = "standard"
+	// Autopilot clusters are billed by the resources requested by pods
+	Autopilot ClusterMode = "autopilot"
+)
+
+// CommitmentTerm is the duration of a committed use discount
+type CommitmentTerm string
+
+const (
+	// OneYear commitment
+	OneYear CommitmentTerm = "1y"
+	// ThreeYears commitment
+	ThreeYears CommitmentTerm = "3y"
 )
 
 // CostimatorConfig Defaults for not provided info in manifests
 type CostimatorConfig struct {
-	ResourceConf ResourceConfig `yaml:"resourceConf,omitempty"`
-	ClusterConf  ClusterConfig  `yaml:"clusterConf,omitempty"`
+	ResourceConf ResourceConfig `yaml:"resourceConf,omitempty" json:"resourceConf,omitempty"`
+	ClusterConf  ClusterConfig  `yaml:"clusterConf,omitempty" json:"clusterConf,omitempty"`
+	DiscountConf DiscountConfig `yaml:"discountConf,omitempty" json:"discountConf,omitempty"`
+	BudgetConf   BudgetConfig   `yaml:"budgetConf,omitempty" json:"budgetConf,omitempty"`
 }
 
 // ResourceConfig is used to setup defaults for resources
 type ResourceConfig struct {
-	MachineFamily                          MachineFamily `yaml:"machineFamily,omitempty"`
-	Region                                 string        `yaml:"region,omitempty"`
-	DefaultCPUinMillis                     int64         `yaml:"defaultCPUinMillis,omitempty"`
-	DefaultMemoryinBytes                   int64         `yaml:"defaultMemoryinBytes,omitempty"`
-	PercentageIncreaseForUnboundedRerouces int64         `yaml:"percentageIncreaseForUnboundedRerouces,omitempty"`
+	MachineFamily                          MachineFamily `yaml:"machineFamily,omitempty" json:"machineFamily,omitempty"`
+	Region                                 string        `yaml:"region,omitempty" json:"region,omitempty"`
+	DefaultCPUinMillis                     int64         `yaml:"defaultCPUinMillis,omitempty" json:"defaultCPUinMillis,omitempty"`
+	DefaultMemoryinBytes                   int64         `yaml:"defaultMemoryinBytes,omitempty" json:"defaultMemoryinBytes,omitempty"`
+	PercentageIncreaseForUnboundedRerouces int64         `yaml:"percentageIncreaseForUnboundedRerouces,omitempty" json:"percentageIncreaseForUnboundedRerouces,omitempty"`
+	DefaultJobRunDurationInMinutes         int64         `yaml:"defaultJobRunDurationInMinutes,omitempty" json:"defaultJobRunDurationInMinutes,omitempty"`
+	DefaultAccelerator                     string        `yaml:"defaultAccelerator,omitempty" json:"defaultAccelerator,omitempty"` // GPU type of pods requesting GPUs without selecting one
 }
 
 // ClusterConfig is used to setup defaults for cluster
 type ClusterConfig struct {
-	NodesCount int32 `yaml:"nodesCount,omitempty"`
+	Mode           ClusterMode          `yaml:"mode,omitempty" json:"mode,omitempty"`
+	NodesCount     int32                `yaml:"nodesCount,omitempty" json:"nodesCount,omitempty"`
+	StorageClasses []StorageClassConfig `yaml:"storageClasses,omitempty" json:"storageClasses,omitempty"`
+	// SpotVMs prices all workloads at Spot VM rates. Otherwise, only workloads selecting (or tolerating) Spot nodes are
+	SpotVMs bool `yaml:"spotVMs,omitempty" json:"spotVMs,omitempty"`
+	// NodePool is deprecated, use NodePools instead. It is handled as a single node pool, named DefaultNodePoolName if it has no name
+	NodePool *NodePoolConfig `yaml:"nodePool,omitempty" json:"nodePool,omitempty"`
+	// NodePools places each workload on the first node pool it can be scheduled on, pricing it with the node pool prices.
+	// Pods are also packed onto the nodes of each node pool with a machine type, and DaemonSets run on the simulated nodes instead of NodesCount
+	NodePools []NodePoolConfig `yaml:"nodePools,omitempty" json:"nodePools,omitempty"`
+}
+
+// NodePoolConfig is a GKE node pool. It is used to price the workloads placed on it (see ClusterConfig.NodePools)
+// and to pack pods onto nodes to estimate the number of nodes and their cost (when it has a machine type)
+type NodePoolConfig struct {
+	Name                      string            `yaml:"name,omitempty" json:"name,omitempty"`
+	MachineType               string            `yaml:"machineType,omitempty" json:"machineType,omitempty"`                             // eg. e2-standard-4 or n2-custom-4-16384
+	MachineFamily             MachineFamily     `yaml:"machineFamily,omitempty" json:"machineFamily,omitempty"`                         // machine type family or ResourceConfig.MachineFamily if not provided
+	Region                    string            `yaml:"region,omitempty" json:"region,omitempty"`                                       // ResourceConfig.Region if not provided
+	Spot                      bool              `yaml:"spot,omitempty" json:"spot,omitempty"`                                           // node pool of Spot VMs
+	LocalSSD                  bool              `yaml:"localSSD,omitempty" json:"localSSD,omitempty"`                                   // nodes use local SSDs as ephemeral storage
+	Labels                    map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`                                       // besides GKE node pool, Spot and machine family labels
+	Taints                    []NodeTaint       `yaml:"taints,omitempty" json:"taints,omitempty"`                                       // pods must tolerate them to be placed on the node pool
+	KubeReservedCPUinMillis   int64             `yaml:"kubeReservedCPUinMillis,omitempty" json:"kubeReservedCPUinMillis,omitempty"`     // GKE reservation if not provided
+	KubeReservedMemoryinBytes int64             `yaml:"kubeReservedMemoryinBytes,omitempty" json:"kubeReservedMemoryinBytes,omitempty"` // GKE reservation (including eviction threshold) if not provided
+	MaxPods                   int32             `yaml:"maxPods,omitempty" json:"maxPods,omitempty"`                                     // 110 if not provided
+}
+
+// NodeTaint is a k8s taint set on all nodes of a node pool
+type NodeTaint struct {
+	Key    string `yaml:"key,omitempty" json:"key,omitempty"`
+	Value  string `yaml:"value,omitempty" json:"value,omitempty"`
+	Effect string `yaml:"effect,omitempty" json:"effect,omitempty"` // NoSchedule if not provided
+}
+
+// DiscountConfig is used to model GCP discounts, so effective costs match the invoice
+type DiscountConfig struct {
+	CommittedUse        []CommittedUseDiscount `yaml:"committedUse,omitempty" json:"committedUse,omitempty"`
+	DisableSustainedUse bool                   `yaml:"disableSustainedUse,omitempty" json:"disableSustainedUse,omitempty"`
+}
+
+// CommittedUseDiscount is a resource-based commitment of vCPUs and memory
+// Only commitments for the configured region and machine family are applied
+type CommittedUseDiscount struct {
+	Region             string         `yaml:"region,omitempty" json:"region,omitempty"`               // ResourceConfig.Region if not provided
+	MachineFamily      MachineFamily  `yaml:"machineFamily,omitempty" json:"machineFamily,omitempty"` // ResourceConfig.MachineFamily if not provided
+	Term               CommitmentTerm `yaml:"term,omitempty" json:"term,omitempty"`
+	VCPUs              float64        `yaml:"vcpus,omitempty" json:"vcpus,omitempty"`
+	MemoryGiB          float64        `yaml:"memoryGiB,omitempty" json:"memoryGiB,omitempty"`
+	DiscountPercentage float64        `yaml:"discountPercentage,omitempty" json:"discountPercentage,omitempty"` // GCP published rate for the term if not provided
+}
+
+// BudgetConfig holds the monthly budgets that object costs are aggregated into
+type BudgetConfig struct {
+	Budgets []Budget `yaml:"budgets,omitempty" json:"budgets,omitempty"`
+}
+
+// Budget is a monthly USD limit for the objects of a namespace and/or matching a label selector (eg. team=payments)
+// An object is counted in every budget it matches
+type Budget struct {
+	Name       string  `yaml:"name,omitempty" json:"name,omitempty"`
+	Namespace  string  `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+	Selector   string  `yaml:"selector,omitempty" json:"selector,omitempty"` // k8s label selector matched against object (and pod template) labels
+	MonthlyUSD float64 `yaml:"monthlyUSD,omitempty" json:"monthlyUSD,omitempty"`
 }
 
 // ConfigDefaults set default values for config
@@ -57,8 +213,11 @@ func ConfigDefaults() CostimatorConfig {
 			DefaultCPUinMillis:                     250,      //250m
 			DefaultMemoryinBytes:                   64000000, //64M
 			PercentageIncreaseForUnboundedRerouces: 200,
+			DefaultJobRunDurationInMinutes:         60,
+			DefaultAccelerator:                     "nvidia-tesla-t4",
 		},
 		ClusterConf: ClusterConfig{
+			Mode:       Standard,
 			NodesCount: 3,
 		},
 	}
@@ -81,9 +240,181 @@ func populateConfigNotProvided(conf CostimatorConfig) CostimatorConfig {
 	if conf.ResourceConf.PercentageIncreaseForUnboundedRerouces != 0 {
 		ret.ResourceConf.PercentageIncreaseForUnboundedRerouces = conf.ResourceConf.PercentageIncreaseForUnboundedRerouces
 	}
+	if conf.ResourceConf.DefaultJobRunDurationInMinutes != 0 {
+		ret.ResourceConf.DefaultJobRunDurationInMinutes = conf.ResourceConf.DefaultJobRunDurationInMinutes
+	}
+	if conf.ResourceConf.DefaultAccelerator != "" {
+		ret.ResourceConf.DefaultAccelerator = conf.ResourceConf.DefaultAccelerator
+	}
 
+	if conf.ClusterConf.Mode != "" {
+		ret.ClusterConf.Mode = conf.ClusterConf.Mode
+	}
 	if conf.ClusterConf.NodesCount != 0 {
 		ret.ClusterConf.NodesCount = conf.ClusterConf.NodesCount
 	}
+	if len(conf.DiscountConf.CommittedUse) > 0 {
+		ret.DiscountConf.CommittedUse = conf.DiscountConf.CommittedUse
+	}
+	if conf.DiscountConf.DisableSustainedUse {
+		ret.DiscountConf.DisableSustainedUse = conf.DiscountConf.DisableSustainedUse
+	}
+	if conf.ClusterConf.SpotVMs {
+		ret.ClusterConf.SpotVMs = conf.ClusterConf.SpotVMs
+	}
+	if pools := conf.ClusterConf.EffectiveNodePools(); len(pools) > 0 {
+		ret.ClusterConf.NodePools = pools
+	}
+	if len(conf.ClusterConf.StorageClasses) > 0 {
+		ret.ClusterConf.StorageClasses = conf.ClusterConf.StorageClasses
+	}
+	if len(conf.BudgetConf.Budgets) > 0 {
+		ret.BudgetConf.Budgets = conf.BudgetConf.Budgets
+	}
 	return ret
 }
+
+// ValidateConfig checks config values that would otherwise only fail (or be silently ignored) when retrieving prices
+func ValidateConfig(conf CostimatorConfig) error {
+	if conf.ResourceConf.MachineFamily != "" {
+		if err := validateMachineFamily("resourceConf.machineFamily", conf.ResourceConf.MachineFamily); err != nil {
+			return err
+		}
+	}
+	if conf.ResourceConf.DefaultAccelerator != "" {
+		if _, ok := gpuPrefixes[conf.ResourceConf.DefaultAccelerator]; !ok {
+			return fmt.Errorf("Accelerator '%s' in 'resourceConf.defaultAccelerator' not supported. Supported accelerators: %s", conf.ResourceConf.DefaultAccelerator, strings.Join(supportedAccelerators(), ", "))
+		}
+	}
+	if conf.ResourceConf.DefaultJobRunDurationInMinutes < 0 {
+		return fmt.Errorf("Invalid 'resourceConf.defaultJobRunDurationInMinutes'. It must be greater than zero")
+	}
+	if conf.ClusterConf.Mode != "" && conf.ClusterConf.Mode != Standard && conf.ClusterConf.Mode != Autopilot {
+		return fmt.Errorf("Cluster mode '%s' in 'clusterConf.mode' not supported. Supported modes: %s, %s", conf.ClusterConf.Mode, Standard, Autopilot)
+	}
+	for i, sc := range conf.ClusterConf.StorageClasses {
+		if err := validatePersistentDisk(fmt.Sprintf("clusterConf.storageClasses[%d]", i), sc.PersistentDisk); err != nil {
+			return err
+		}
+	}
+	if conf.ClusterConf.NodePool != nil && len(conf.ClusterConf.NodePools) > 0 {
+		return fmt.Errorf("Invalid 'clusterConf.nodePool'. It is deprecated and can't be used along with 'clusterConf.nodePools'. Add it to 'clusterConf.nodePools' instead")
+	}
+	if conf.ClusterConf.NodePool != nil {
+		if err := validateNodePool("clusterConf.nodePool", *conf.ClusterConf.NodePool); err != nil {
+			return err
+		}
+	}
+	for i, pool := range conf.ClusterConf.NodePools {
+		if err := validateNodePool(fmt.Sprintf("clusterConf.nodePools[%d]", i), pool); err != nil {
+			return err
+		}
+	}
+	for i, cud := range conf.DiscountConf.CommittedUse {
+		if cud.MachineFamily != "" {
+			if err := validateMachineFamily(fmt.Sprintf("discountConf.committedUse[%d].machineFamily", i), cud.MachineFamily); err != nil {
+				return err
+			}
+		}
+	}
+	for i, budget := range conf.BudgetConf.Budgets {
+		if err := validateBudget(fmt.Sprintf("budgetConf.budgets[%d]", i), budget); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validatePersistentDisk(field string, disk PersistentDisk) error {
+	switch disk.DiskType {
+	case "", PdStandard, PdBalanced, PdSSD, PdExtreme:
+	default:
+		return fmt.Errorf("Disk type '%s' in '%s.diskType' not supported. Supported disk types (without 'pd-' prefix): %s, %s, %s, %s", disk.DiskType, field, PdStandard, PdBalanced, PdSSD, PdExtreme)
+	}
+	switch disk.Replication {
+	case "", Zonal, Regional:
+	default:
+		return fmt.Errorf("Replication '%s' in '%s.replication' not supported. Supported replications: %s, %s", disk.Replication, field, Zonal, Regional)
+	}
+	return nil
+}
+
+func validateBudget(field string, budget Budget) error {
+	if budget.Namespace == "" && budget.Selector == "" {
+		return fmt.Errorf("Invalid '%s'. Either namespace or selector must be provided", field)
+	}
+	if _, err := labels.Parse(budget.Selector); err != nil {
+		return fmt.Errorf("Invalid '%s.selector'. %+v", field, err)
+	}
+	if budget.MonthlyUSD <= 0 {
+		return fmt.Errorf("Invalid '%s.monthlyUSD'. It must be greater than 0", field)
+	}
+	return nil
+}
+
+func validateNodePool(field string, pool NodePoolConfig) error {
+	if pool.MachineFamily != "" {
+		if err := validateMachineFamily(field+".machineFamily", pool.MachineFamily); err != nil {
+			return err
+		}
+	}
+	if pool.MachineType != "" {
+		if _, err := parseMachineType(pool.MachineType); err != nil {
+			return fmt.Errorf("Invalid '%s.machineType'. %+v", field, err)
+		}
+	}
+	return nil
+}
+
+func validateMachineFamily(field string, family MachineFamily) error {
+	if _, ok := cpuPrefixes[family]; ok {
+		return nil
+	}
+	supported := supportedMachineFamilies()
+	if _, ok := cpuPrefixes[MachineFamily(strings.ToUpper(string(family)))]; ok {
+		return fmt.Errorf("Machine family '%s' in '%s' not supported. Did you mean '%s'? Supported machine families: %s", family, field, strings.ToUpper(string(family)), strings.Join(supported, ", "))
+	}
+	return fmt.Errorf("Machine family '%s' in '%s' not supported. Supported machine families: %s", family, field, strings.Join(supported, ", "))
+}
+
+func supportedMachineFamilies() []string {
+	families := []string{}
+	for family := range cpuPrefixes {
+		families = append(families, string(family))
+	}
+	sort.Strings(families)
+	return families
+}
+
+func supportedAccelerators() []string {
+	accelerators := []string{}
+	for accelerator := range gpuPrefixes {
+		accelerators = append(accelerators, accelerator)
+	}
+	sort.Strings(accelerators)
+	return accelerators
+}
+
+// persistentDisk returns the GCE Persistent Disk for the given StorageClass
+// StorageClasses in config take precedence over GKE default ones
+func (c *ClusterConfig) persistentDisk(storageClass string) PersistentDisk {
+	for _, storageClasses := range [][]StorageClassConfig{c.StorageClasses, gkeStorageClasses} {
+		for _, sc := range storageClasses {
+			if sc.Name == storageClass {
+				return sc.PersistentDisk.withDefaults()
+			}
+		}
+	}
+	log.Infof("StorageClass '%s' not mapped in clusterConf.storageClasses. Using %s instead", storageClass, defaultPersistentDisk)
+	return defaultPersistentDisk
+}
+
+func (d PersistentDisk) withDefaults() PersistentDisk {
+	if d.DiskType == "" {
+		d.DiskType = defaultPersistentDisk.DiskType
+	}
+	if d.Replication == "" {
+		d.Replication = defaultPersistentDisk.Replication
+	}
+	return d
+}
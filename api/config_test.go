@@ -15,6 +15,7 @@
 package api
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -34,9 +35,20 @@ func TestPopulateDaemonSetConfigNotProvided(t *testing.T) {
 			DefaultCPUinMillis:                     300,
 			DefaultMemoryinBytes:                   65000000,
 			PercentageIncreaseForUnboundedRerouces: 100,
+			DefaultJobRunDurationInMinutes:         30,
+			DefaultAccelerator:                     "nvidia-l4",
 		},
 		ClusterConf: ClusterConfig{
+			Mode:       Autopilot,
 			NodesCount: 5,
+			StorageClasses: []StorageClassConfig{
+				{Name: "fast", PersistentDisk: PersistentDisk{DiskType: PdSSD, Replication: Regional}},
+			},
+			SpotVMs: true,
+		},
+		DiscountConf: DiscountConfig{
+			CommittedUse:        []CommittedUseDiscount{{Term: ThreeYears, VCPUs: 8, MemoryGiB: 32}},
+			DisableSustainedUse: true,
 		},
 	}
 
@@ -45,3 +57,114 @@ func TestPopulateDaemonSetConfigNotProvided(t *testing.T) {
 		t.Errorf("Config should be equal, expected: %+v, got: %+v", expected, populated)
 	}
 }
+
+func TestPersistentDisk(t *testing.T) {
+	conf := ClusterConfig{
+		StorageClasses: []StorageClassConfig{
+			{Name: "fast", PersistentDisk: PersistentDisk{DiskType: PdSSD, Replication: Regional}},
+			{Name: "balanced-default-replication", PersistentDisk: PersistentDisk{DiskType: PdBalanced}},
+			{Name: "premium-rwo", PersistentDisk: PersistentDisk{DiskType: PdExtreme, Replication: Zonal}},
+		},
+	}
+
+	tests := map[string]PersistentDisk{
+		"fast":                         {DiskType: PdSSD, Replication: Regional},
+		"balanced-default-replication": {DiskType: PdBalanced, Replication: Zonal},
+		"premium-rwo":                  {DiskType: PdExtreme, Replication: Zonal}, // config overrides GKE default
+		"standard-rwo":                 {DiskType: PdBalanced, Replication: Zonal},
+		"standard":                     {DiskType: PdStandard, Replication: Zonal},
+		"unknown":                      {DiskType: PdStandard, Replication: Zonal},
+	}
+	for storageClass, want := range tests {
+		if got := conf.persistentDisk(storageClass); got != want {
+			t.Errorf("StorageClass '%s' should be mapped to %s, got %s", storageClass, want, got)
+		}
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	valid := CostimatorConfig{
+		ResourceConf: ResourceConfig{MachineFamily: C2D},
+		ClusterConf: ClusterConfig{
+			Mode:           Standard,
+			StorageClasses: []StorageClassConfig{{Name: "regional-ssd", PersistentDisk: PersistentDisk{DiskType: PdSSD, Replication: Regional}}},
+			NodePools:      []NodePoolConfig{{Name: "default", MachineType: "t2d-standard-4"}, {Name: "memory", MachineFamily: M1}, {Name: "gpu", MachineType: "a2-highgpu-1g"}},
+		},
+		DiscountConf: DiscountConfig{CommittedUse: []CommittedUseDiscount{{MachineFamily: C2, Term: OneYear}}},
+		BudgetConf:   BudgetConfig{Budgets: []Budget{{Namespace: "shop", MonthlyUSD: 100}, {Selector: "team in (payments,checkout)", MonthlyUSD: 50}}},
+	}
+	if err := ValidateConfig(valid); err != nil {
+		t.Errorf("Config should be valid, got: %+v", err)
+	}
+	if err := ValidateConfig(CostimatorConfig{}); err != nil {
+		t.Errorf("Empty config should be valid, got: %+v", err)
+	}
+
+	tests := map[string]struct {
+		conf CostimatorConfig
+		want string
+	}{
+		"resource machine family": {
+			conf: CostimatorConfig{ResourceConf: ResourceConfig{MachineFamily: "N3"}},
+			want: "Machine family 'N3' in 'resourceConf.machineFamily' not supported. Supported machine families: A2, C2, C2D, E2, M1, N1, N2, N2D, T2D",
+		},
+		"lower case machine family": {
+			conf: CostimatorConfig{ResourceConf: ResourceConfig{MachineFamily: "c2d"}},
+			want: "Machine family 'c2d' in 'resourceConf.machineFamily' not supported. Did you mean 'C2D'?",
+		},
+		"node pool machine family": {
+			conf: CostimatorConfig{ClusterConf: ClusterConfig{NodePools: []NodePoolConfig{{Name: "default"}, {Name: "memory", MachineFamily: "M3"}}}},
+			want: "Machine family 'M3' in 'clusterConf.nodePools[1].machineFamily' not supported.",
+		},
+		"node pool machine type": {
+			conf: CostimatorConfig{ClusterConf: ClusterConfig{NodePool: &NodePoolConfig{MachineType: "x2-standard-4"}}},
+			want: "Invalid 'clusterConf.nodePool.machineType'. Machine type 'x2-standard-4' not supported",
+		},
+		"deprecated node pool along with node pools": {
+			conf: CostimatorConfig{ClusterConf: ClusterConfig{NodePool: &NodePoolConfig{MachineType: "e2-standard-4"}, NodePools: []NodePoolConfig{{Name: "default"}}}},
+			want: "Invalid 'clusterConf.nodePool'. It is deprecated and can't be used along with 'clusterConf.nodePools'.",
+		},
+		"negative job run duration": {
+			conf: CostimatorConfig{ResourceConf: ResourceConfig{DefaultJobRunDurationInMinutes: -30}},
+			want: "Invalid 'resourceConf.defaultJobRunDurationInMinutes'. It must be greater than zero",
+		},
+		"cluster mode": {
+			conf: CostimatorConfig{ClusterConf: ClusterConfig{Mode: "Autopliot"}},
+			want: "Cluster mode 'Autopliot' in 'clusterConf.mode' not supported. Supported modes: standard, autopilot",
+		},
+		"storage class disk type": {
+			conf: CostimatorConfig{ClusterConf: ClusterConfig{StorageClasses: []StorageClassConfig{{Name: "fast", PersistentDisk: PersistentDisk{DiskType: PdSSD}}, {Name: "balanced", PersistentDisk: PersistentDisk{DiskType: "pd-balance"}}}}},
+			want: "Disk type 'pd-balance' in 'clusterConf.storageClasses[1].diskType' not supported.",
+		},
+		"storage class replication": {
+			conf: CostimatorConfig{ClusterConf: ClusterConfig{StorageClasses: []StorageClassConfig{{Name: "regional", PersistentDisk: PersistentDisk{Replication: "multi-regional"}}}}},
+			want: "Replication 'multi-regional' in 'clusterConf.storageClasses[0].replication' not supported. Supported replications: zonal, regional",
+		},
+		"default accelerator": {
+			conf: CostimatorConfig{ResourceConf: ResourceConfig{DefaultAccelerator: "nvidia-tesla-t5"}},
+			want: "Accelerator 'nvidia-tesla-t5' in 'resourceConf.defaultAccelerator' not supported.",
+		},
+		"committed use machine family": {
+			conf: CostimatorConfig{DiscountConf: DiscountConfig{CommittedUse: []CommittedUseDiscount{{MachineFamily: "Z9"}}}},
+			want: "Machine family 'Z9' in 'discountConf.committedUse[0].machineFamily' not supported.",
+		},
+		"budget scope": {
+			conf: CostimatorConfig{BudgetConf: BudgetConfig{Budgets: []Budget{{Name: "all", MonthlyUSD: 100}}}},
+			want: "Invalid 'budgetConf.budgets[0]'. Either namespace or selector must be provided",
+		},
+		"budget selector": {
+			conf: CostimatorConfig{BudgetConf: BudgetConfig{Budgets: []Budget{{Selector: "team in payments", MonthlyUSD: 100}}}},
+			want: "Invalid 'budgetConf.budgets[0].selector'.",
+		},
+		"budget monthly USD": {
+			conf: CostimatorConfig{BudgetConf: BudgetConfig{Budgets: []Budget{{Namespace: "shop"}}}},
+			want: "Invalid 'budgetConf.budgets[0].monthlyUSD'. It must be greater than 0",
+		},
+	}
+	for name, tt := range tests {
+		err := ValidateConfig(tt.conf)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%s: error should start with '%s', got '%+v'", name, tt.want, err)
+		}
+	}
+}
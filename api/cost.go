@@ -17,6 +17,7 @@ package api
 import (
 	"fmt"
 	"math"
+	"sort"
 	"strings"
 
 	"github.com/leekchan/accounting"
@@ -29,14 +30,44 @@ const (
 )
 
 var headers = []string{"MIN REQUESTED",
-	"MIN REQ + HPA CPU BUFFER",
+	"MIN REQ + HPA BUFFER",
 	"MAX REQUESTED",
 	"MIN LIMITED",
 	"MAX LIMITED"}
 
 // Cost groups cost range by kinda
 type Cost struct {
-	MonthlyRanges []CostRange
+	MonthlyRanges       []CostRange
+	MonthlyObjectRanges []ObjectCostRange
+	SpotComparisons     []SpotComparison       // empty when Spot VM prices are not available
+	Discounts           *Discounts             // nil when there is no discount to apply
+	Nodes               []NodeEstimate         // one per simulated node pool (see ClusterConfig.NodePools)
+	GPUs                []GPUCost              // GPU share of the cost above. Empty when no workload requests GPUs
+	EphemeralStorage    []EphemeralStorageCost // ephemeral storage share of the cost above. Empty when no workload uses it
+}
+
+// ObjectCostRange represent the range of estimated value for a single k8s object
+type ObjectCostRange struct {
+	APIVersionKindName string `json:"apiVersionKindName"`
+	Namespace          string `json:"namespace"`
+	Kind               string `json:"kind"`
+	Name               string `json:"name"`
+
+	Replicas     int32        `json:"replicas"`
+	MinReplicas  int32        `json:"minReplicas"` // HPA bounds. Same as Replicas when there is no HPA
+	MaxReplicas  int32        `json:"maxReplicas"`
+	Requests     Resource     `json:"requests"`               // per replica
+	Limits       Resource     `json:"limits"`                 // per replica
+	Spot         bool         `json:"spot"`                   // priced at Spot VM rates
+	ComputeClass ComputeClass `json:"computeClass,omitempty"` // Autopilot compute class. Empty for Standard clusters
+	NodePool     string       `json:"nodePool,omitempty"`     // node pool the workload is placed on (see ClusterConfig.NodePools)
+
+	Labels map[string]string `json:"labels,omitempty"` // object labels, completed with pod template labels. Used to match budgets
+
+	HPAMetrics      []HPAMetric `json:"hpaMetrics,omitempty"`      // all HPA metric targets. Empty when there is no HPA
+	HPABufferMetric string      `json:"hpaBufferMetric,omitempty"` // the most restrictive cpu or memory target, which drives HPABuffer
+
+	MonthlyRange CostRange `json:"monthlyRange"`
 }
 
 // CostRange represent the range of estimated value
@@ -46,7 +77,7 @@ type CostRange struct {
 	MinRequested float64 `json:"minRequested"`
 	MaxRequested float64 `json:"maxRequested"`
 
-	HPABuffer float64 `json:"hpaBuffer"` //Note: currently only supports CPU Target utilizaiton
+	HPABuffer float64 `json:"hpaBuffer"` // min requested plus the headroom kept by the most restrictive HPA cpu or memory target
 
 	MinLimited float64 `json:"minLimited"`
 	MaxLimited float64 `json:"maxLimited"`
@@ -59,15 +90,16 @@ type DiffCost struct {
 	CostCurr         Cost
 	CostPrev         Cost
 	MonthlyDiffRange DiffCostRange
+	ObjectDiffs      []ObjectDiff
 }
 
 // DiffCostRange holds the total difference between two costs
 type DiffCostRange struct {
-	Kind           string
-	CostCurr       CostRange
-	CostPrev       CostRange
-	DiffValue      CostRange
-	DiffPercentage CostRange
+	Kind           string    `json:"kind"`
+	CostCurr       CostRange `json:"costCurr"`
+	CostPrev       CostRange `json:"costPrev"`
+	DiffValue      CostRange `json:"diffValue"`
+	DiffPercentage CostRange `json:"diffPercentage"`
 }
 
 // MonthlyTotal returns the sum for all MonthlyRanges
@@ -79,6 +111,17 @@ func (c *Cost) MonthlyTotal() CostRange {
 	return totalMonthlyRange
 }
 
+// MonthlyEffectiveTotal returns the sum for all MonthlyRanges minus committed use and sustained use discounts
+func (c *Cost) MonthlyEffectiveTotal() CostRange {
+	total := c.MonthlyTotal()
+	if c.Discounts == nil {
+		return total
+	}
+	effective := c.Discounts.Effective(total)
+	effective.Kind = "MonthlyEffectiveTotal"
+	return effective
+}
+
 // Subtract current total cost from previous total cost
 func (c *Cost) Subtract(costPrev Cost) DiffCost {
 	cr := c.MonthlyTotal()
@@ -90,11 +133,45 @@ func (c *Cost) Subtract(costPrev Cost) DiffCost {
 		CostCurr:         *c,
 		CostPrev:         costPrev,
 		MonthlyDiffRange: diff,
+		ObjectDiffs:      diffObjects(c.MonthlyObjectRanges, costPrev.MonthlyObjectRanges),
 	}
 }
 
+// Key returns the object identification in the namespace/kind/name format
+func (o *ObjectCostRange) Key() string {
+	return fmt.Sprintf("%s/%s/%s", o.Namespace, o.Kind, o.Name)
+}
+
 // ToMarkdown convert to Markdown string
+// Kind totals are followed by the GPU and ephemeral storage shares, discounts, the simulated nodes, the cost of each k8s object and the Spot VMs savings
 func (c *Cost) ToMarkdown() string {
+	summary := c.kindsToMarkdown()
+	if len(c.GPUs) > 0 {
+		summary = fmt.Sprintf("%s\n**GPUs (included in the costs above):**\n\n%s", summary, gpuCostsToMarkdown(c.GPUs))
+	}
+	if len(c.EphemeralStorage) > 0 {
+		summary = fmt.Sprintf("%s\n**Ephemeral storage (included in the costs above):**\n\n%s", summary, ephemeralStorageCostsToMarkdown(c.EphemeralStorage))
+	}
+	if c.Discounts != nil {
+		summary = fmt.Sprintf("%s\n**List vs Effective (with discounts):**\n\n%s", summary, discountsToMarkdown(c.MonthlyTotal(), c.Discounts))
+	}
+	if len(c.Nodes) > 0 {
+		summary = fmt.Sprintf("%s\n**Node pools (bin packing):**\n\n%s", summary, nodeEstimatesToMarkdown(c.Nodes))
+	}
+	if len(c.MonthlyObjectRanges) == 0 {
+		return summary
+	}
+	summary = fmt.Sprintf("%s\n**Cost per object:**\n\n%s", summary, c.objectsToMarkdown())
+	if c.hasHPAMetrics() {
+		summary = fmt.Sprintf("%s\n**HPA buffer:**\n\n%s", summary, hpaMetricsToMarkdown(c.sortedObjectRanges()))
+	}
+	if len(c.SpotComparisons) == 0 {
+		return summary
+	}
+	return fmt.Sprintf("%s\n**Spot VMs vs On-Demand:**\n\n%s", summary, spotComparisonsToMarkdown(c.SpotComparisons))
+}
+
+func (c *Cost) kindsToMarkdown() string {
 	data := [][]string{}
 	total := CostRange{Kind: bold("TOTAL")}
 	for _, mr := range c.MonthlyRanges {
@@ -137,6 +214,60 @@ func (c *Cost) ToMarkdown() string {
 	return out.String()
 }
 
+func (c *Cost) hasHPAMetrics() bool {
+	for _, or := range c.MonthlyObjectRanges {
+		if len(or.HPAMetrics) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Cost) sortedObjectRanges() []ObjectCostRange {
+	objectRanges := make([]ObjectCostRange, len(c.MonthlyObjectRanges))
+	copy(objectRanges, c.MonthlyObjectRanges)
+	sort.SliceStable(objectRanges, func(i, j int) bool {
+		return objectRanges[i].Key() < objectRanges[j].Key()
+	})
+	return objectRanges
+}
+
+func (c *Cost) objectsToMarkdown() string {
+	objectRanges := c.sortedObjectRanges()
+
+	data := [][]string{}
+	for _, or := range objectRanges {
+		mr := or.MonthlyRange
+		data = append(data,
+			[]string{or.Namespace,
+				or.Kind,
+				or.Name,
+				currency(mr.MinRequested),
+				currency(mr.HPABuffer),
+				currency(mr.MaxRequested),
+				currency(mr.MinLimited),
+				currency(mr.MaxLimited)})
+	}
+
+	out := &strings.Builder{}
+	table := tablewriter.NewWriter(out)
+	table.SetHeader(
+		[]string{"Namespace",
+			"Kind",
+			"Name",
+			headers[0] + " (USD)",
+			headers[1] + " (USD)",
+			headers[2] + " (USD)",
+			headers[3] + " (USD)",
+			headers[4] + " (USD)"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.SetColumnAlignment([]int{0, 0, 0, 2, 2, 2, 2, 2})
+	table.AppendBulk(data)
+	table.Render()
+	return out.String()
+}
+
 func bold(val string) string {
 	return fmt.Sprintf("**%s**", val)
 }
@@ -162,12 +293,13 @@ func (c *CostRange) Subtract(costRangePrev CostRange) DiffCostRange {
 	diff.MinLimited = c.MinLimited - costRangePrev.MinLimited
 	diff.MaxLimited = c.MaxLimited - costRangePrev.MaxLimited
 
+	// percentages are relative to the previous cost. A new cost (zero previous cost) is 100%, since JSON doesn't support infinity
 	diffP := CostRange{Kind: c.Kind}
-	diffP.MinRequested = diff.MinRequested * 100 / c.MinRequested
-	diffP.MaxRequested = diff.MaxRequested * 100 / c.MaxRequested
-	diffP.HPABuffer = diff.HPABuffer * 100 / c.HPABuffer
-	diffP.MinLimited = diff.MinLimited * 100 / c.MinLimited
-	diffP.MaxLimited = diff.MaxLimited * 100 / c.MaxLimited
+	diffP.MinRequested = jsonPercentage(percentageIncrease(costRangePrev.MinRequested, c.MinRequested))
+	diffP.MaxRequested = jsonPercentage(percentageIncrease(costRangePrev.MaxRequested, c.MaxRequested))
+	diffP.HPABuffer = jsonPercentage(percentageIncrease(costRangePrev.HPABuffer, c.HPABuffer))
+	diffP.MinLimited = jsonPercentage(percentageIncrease(costRangePrev.MinLimited, c.MinLimited))
+	diffP.MaxLimited = jsonPercentage(percentageIncrease(costRangePrev.MaxLimited, c.MaxLimited))
 
 	return DiffCostRange{
 		Kind:           c.Kind,
@@ -202,7 +334,11 @@ func (d *DiffCost) ToMarkdown() string {
 	previous := fmt.Sprintf("## Previous Monthly Cost\n\n%s", d.CostPrev.ToMarkdown())
 	diff := d.MonthlyDiffRange.ToMarkdown()
 	total := fmt.Sprintf("## Difference in Costs\n\n**Summary:** %s\n\n%s", d.Summary, diff)
-	return fmt.Sprintf("%s\n\n%s\n\n%s", previous, current, total)
+	if len(d.ObjectDiffs) == 0 {
+		return fmt.Sprintf("%s\n\n%s\n\n%s", previous, current, total)
+	}
+	objects := fmt.Sprintf("## Difference in Costs per Object\n\n%s", objectDiffsToMarkdown(d.ObjectDiffs))
+	return fmt.Sprintf("%s\n\n%s\n\n%s\n\n%s", previous, current, total, objects)
 }
 
 //---
@@ -267,11 +403,11 @@ func (d *DiffCostRange) ToPriceDetails() PriceDetails {
 // ToMarkdown convert to Markdown string
 func (d *DiffCostRange) ToMarkdown() string {
 	data := [][]string{
-		{bold(headers[0]), currency(d.CostPrev.MinRequested), currency(d.CostCurr.MinRequested), currencyDiff(d.DiffValue.MinRequested), percDiff(d.DiffPercentage.MinRequested)},
-		{bold(headers[1]), currency(d.CostPrev.HPABuffer), currency(d.CostCurr.HPABuffer), currencyDiff(d.DiffValue.HPABuffer), percDiff(d.DiffPercentage.HPABuffer)},
-		{bold(headers[2]), currency(d.CostPrev.MaxRequested), currency(d.CostCurr.MaxRequested), currencyDiff(d.DiffValue.MaxRequested), percDiff(d.DiffPercentage.MaxRequested)},
-		{bold(headers[3]), currency(d.CostPrev.MinLimited), currency(d.CostCurr.MinLimited), currencyDiff(d.DiffValue.MinLimited), percDiff(d.DiffPercentage.MinLimited)},
-		{bold(headers[4]), currency(d.CostPrev.MaxLimited), currency(d.CostCurr.MaxLimited), currencyDiff(d.DiffValue.MaxLimited), percDiff(d.DiffPercentage.MaxLimited)},
+		{bold(headers[0]), currency(d.CostPrev.MinRequested), currency(d.CostCurr.MinRequested), currencyDiff(d.DiffValue.MinRequested), percDiff(percentageIncrease(d.CostPrev.MinRequested, d.CostCurr.MinRequested))},
+		{bold(headers[1]), currency(d.CostPrev.HPABuffer), currency(d.CostCurr.HPABuffer), currencyDiff(d.DiffValue.HPABuffer), percDiff(percentageIncrease(d.CostPrev.HPABuffer, d.CostCurr.HPABuffer))},
+		{bold(headers[2]), currency(d.CostPrev.MaxRequested), currency(d.CostCurr.MaxRequested), currencyDiff(d.DiffValue.MaxRequested), percDiff(percentageIncrease(d.CostPrev.MaxRequested, d.CostCurr.MaxRequested))},
+		{bold(headers[3]), currency(d.CostPrev.MinLimited), currency(d.CostCurr.MinLimited), currencyDiff(d.DiffValue.MinLimited), percDiff(percentageIncrease(d.CostPrev.MinLimited, d.CostCurr.MinLimited))},
+		{bold(headers[4]), currency(d.CostPrev.MaxLimited), currency(d.CostCurr.MaxLimited), currencyDiff(d.DiffValue.MaxLimited), percDiff(percentageIncrease(d.CostPrev.MaxLimited, d.CostCurr.MaxLimited))},
 	}
 
 	out := &strings.Builder{}
@@ -288,35 +424,35 @@ func (d *DiffCostRange) ToMarkdown() string {
 func (d *DiffCostRange) status() (summary string, costIncrease bool) {
 	var costInc, costDec []string
 
-	if d.DiffPercentage.MinRequested > 0 {
+	if d.DiffValue.MinRequested > 0 {
 		costInc = append(costInc, headers[0])
 	}
-	if d.DiffPercentage.HPABuffer > 0 {
+	if d.DiffValue.HPABuffer > 0 {
 		costInc = append(costInc, headers[1])
 	}
-	if d.DiffPercentage.MaxRequested > 0 {
+	if d.DiffValue.MaxRequested > 0 {
 		costInc = append(costInc, headers[2])
 	}
-	if d.DiffPercentage.MinLimited > 0 {
+	if d.DiffValue.MinLimited > 0 {
 		costInc = append(costInc, headers[3])
 	}
-	if d.DiffPercentage.MaxLimited > 0 {
+	if d.DiffValue.MaxLimited > 0 {
 		costInc = append(costInc, headers[4])
 	}
 
-	if d.DiffPercentage.MinRequested < 0 {
+	if d.DiffValue.MinRequested < 0 {
 		costDec = append(costDec, headers[0])
 	}
-	if d.DiffPercentage.HPABuffer < 0 {
+	if d.DiffValue.HPABuffer < 0 {
 		costDec = append(costDec, headers[1])
 	}
-	if d.DiffPercentage.MaxRequested < 0 {
+	if d.DiffValue.MaxRequested < 0 {
 		costDec = append(costDec, headers[2])
 	}
-	if d.DiffPercentage.MinLimited < 0 {
+	if d.DiffValue.MinLimited < 0 {
 		costDec = append(costDec, headers[3])
 	}
-	if d.DiffPercentage.MaxLimited < 0 {
+	if d.DiffValue.MaxLimited < 0 {
 		costDec = append(costDec, headers[4])
 	}
 
@@ -355,7 +491,30 @@ func currencyDiff(value float64) string {
 	return valueFormated
 }
 
+// percentageIncrease returns the increase from previous to current in percentage of previous
+// It is +Inf for a new cost, ie. when previous is zero and current is not (eg. the first workload is added)
+func percentageIncrease(previous, current float64) float64 {
+	if previous == 0 {
+		if current > 0 {
+			return math.Inf(1)
+		}
+		return 0
+	}
+	return (current - previous) * 100 / previous
+}
+
+// jsonPercentage returns 100 for a new cost (+Inf percentage), since JSON doesn't support infinity
+func jsonPercentage(perc float64) float64 {
+	if math.IsInf(perc, 1) {
+		return 100
+	}
+	return perc
+}
+
 func percDiff(perc float64) string {
+	if math.IsInf(perc, 1) {
+		return fmt.Sprintf("**new (%s)**", upArrow)
+	}
 	if perc != 0 {
 		percFormated := fmt.Sprintf("%.2f%%", perc)
 		if perc > 0 {
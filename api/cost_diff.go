@@ -0,0 +1,229 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+const rightArrow = "&#8594;"
+
+// ObjectDiffStatus tells how a k8s object changed between previous and current manifests
+type ObjectDiffStatus string
+
+const (
+	// ObjectAdded object only exists in current manifests
+	ObjectAdded ObjectDiffStatus = "added"
+	// ObjectRemoved object only exists in previous manifests
+	ObjectRemoved ObjectDiffStatus = "removed"
+	// ObjectChanged object exists in both manifests, but its cost or fields changed
+	ObjectChanged ObjectDiffStatus = "changed"
+)
+
+// ObjectDiff holds the difference in cost of a single k8s object between previous and current manifests
+// Objects are matched by APIVersionKindName
+type ObjectDiff struct {
+	APIVersionKindName string           `json:"apiVersionKindName"`
+	Namespace          string           `json:"namespace"`
+	Kind               string           `json:"kind"`
+	Name               string           `json:"name"`
+	Status             ObjectDiffStatus `json:"status"`
+	CostCurr           CostRange        `json:"costCurr"`
+	CostPrev           CostRange        `json:"costPrev"`
+	DiffValue          CostRange        `json:"diffValue"`
+	ChangedFields      []FieldChange    `json:"changedFields,omitempty"`
+}
+
+// FieldChange holds previous and current values of a field that impacts the cost
+type FieldChange struct {
+	Field    string `json:"field"`
+	Previous string `json:"previous"`
+	Current  string `json:"current"`
+}
+
+// Key returns the object identification in the namespace/kind/name format
+func (o *ObjectDiff) Key() string {
+	return fmt.Sprintf("%s/%s/%s", o.Namespace, o.Kind, o.Name)
+}
+
+// diffObjects matches objects by APIVersionKindName and returns the ones added, removed or changed
+// Objects sharing the same APIVersionKindName (eg. StatefulSet volume claim templates) are matched in load order
+func diffObjects(curr, prev []ObjectCostRange) []ObjectDiff {
+	prevByKey := make(map[string][]ObjectCostRange)
+	for _, p := range prev {
+		prevByKey[p.APIVersionKindName] = append(prevByKey[p.APIVersionKindName], p)
+	}
+
+	diffs := []ObjectDiff{}
+	for _, c := range curr {
+		matches := prevByKey[c.APIVersionKindName]
+		if len(matches) == 0 {
+			diffs = append(diffs, newObjectDiff(ObjectAdded, c, CostRange{Kind: c.MonthlyRange.Kind}, c.MonthlyRange))
+			continue
+		}
+		p := matches[0]
+		prevByKey[c.APIVersionKindName] = matches[1:]
+
+		changedFields := diffFields(c, p)
+		if len(changedFields) == 0 && c.MonthlyRange == p.MonthlyRange {
+			continue
+		}
+		diff := newObjectDiff(ObjectChanged, c, p.MonthlyRange, c.MonthlyRange)
+		diff.ChangedFields = changedFields
+		diffs = append(diffs, diff)
+	}
+	for _, p := range prev {
+		matches := prevByKey[p.APIVersionKindName]
+		if len(matches) == 0 {
+			continue
+		}
+		prevByKey[p.APIVersionKindName] = matches[1:]
+		diffs = append(diffs, newObjectDiff(ObjectRemoved, matches[0], matches[0].MonthlyRange, CostRange{Kind: matches[0].MonthlyRange.Kind}))
+	}
+
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return diffs[i].Key() < diffs[j].Key()
+	})
+	return diffs
+}
+
+func newObjectDiff(status ObjectDiffStatus, o ObjectCostRange, costPrev, costCurr CostRange) ObjectDiff {
+	diff := costCurr.Subtract(costPrev)
+	return ObjectDiff{
+		APIVersionKindName: o.APIVersionKindName,
+		Namespace:          o.Namespace,
+		Kind:               o.Kind,
+		Name:               o.Name,
+		Status:             status,
+		CostCurr:           costCurr,
+		CostPrev:           costPrev,
+		DiffValue:          diff.DiffValue,
+	}
+}
+
+func diffFields(curr, prev ObjectCostRange) []FieldChange {
+	changes := []FieldChange{}
+	addInt := func(field string, p, c int32) {
+		if p != c {
+			changes = append(changes, FieldChange{Field: field, Previous: fmt.Sprintf("%d", p), Current: fmt.Sprintf("%d", c)})
+		}
+	}
+	addCPU := func(field string, p, c int64) {
+		if p != c {
+			changes = append(changes, FieldChange{Field: field, Previous: formatCPU(p), Current: formatCPU(c)})
+		}
+	}
+	addBytes := func(field string, p, c int64) {
+		if p != c {
+			changes = append(changes, FieldChange{Field: field, Previous: formatBytes(p), Current: formatBytes(c)})
+		}
+	}
+
+	if prev.Spot != curr.Spot {
+		changes = append(changes, FieldChange{Field: "spot", Previous: fmt.Sprintf("%t", prev.Spot), Current: fmt.Sprintf("%t", curr.Spot)})
+	}
+	if prev.ComputeClass != curr.ComputeClass {
+		changes = append(changes, FieldChange{Field: "computeClass", Previous: string(prev.ComputeClass), Current: string(curr.ComputeClass)})
+	}
+	if prev.NodePool != curr.NodePool {
+		changes = append(changes, FieldChange{Field: "nodePool", Previous: prev.NodePool, Current: curr.NodePool})
+	}
+	addInt("replicas", prev.Replicas, curr.Replicas)
+	addInt("hpa.minReplicas", prev.MinReplicas, curr.MinReplicas)
+	addInt("hpa.maxReplicas", prev.MaxReplicas, curr.MaxReplicas)
+	if prev.HPABufferMetric != curr.HPABufferMetric {
+		changes = append(changes, FieldChange{Field: "hpa.bufferMetric", Previous: prev.HPABufferMetric, Current: curr.HPABufferMetric})
+	}
+	addCPU("requests.cpu", prev.Requests.CPU, curr.Requests.CPU)
+	addBytes("requests.memory", prev.Requests.Memory, curr.Requests.Memory)
+	addBytes("requests.storage", prev.Requests.Storage, curr.Requests.Storage)
+	addCPU("limits.cpu", prev.Limits.CPU, curr.Limits.CPU)
+	addBytes("limits.memory", prev.Limits.Memory, curr.Limits.Memory)
+	addBytes("limits.storage", prev.Limits.Storage, curr.Limits.Storage)
+	if prev.Requests.GPU != curr.Requests.GPU {
+		changes = append(changes, FieldChange{Field: "requests.gpu", Previous: fmt.Sprintf("%d", prev.Requests.GPU), Current: fmt.Sprintf("%d", curr.Requests.GPU)})
+	}
+	if prev.Requests.Accelerator != curr.Requests.Accelerator {
+		changes = append(changes, FieldChange{Field: "accelerator", Previous: prev.Requests.Accelerator, Current: curr.Requests.Accelerator})
+	}
+	return changes
+}
+
+func objectDiffsToMarkdown(diffs []ObjectDiff) string {
+	data := [][]string{}
+	for _, d := range diffs {
+		fields := []string{}
+		for _, f := range d.ChangedFields {
+			fields = append(fields, fmt.Sprintf("%s: %s %s %s", f.Field, f.Previous, rightArrow, f.Current))
+		}
+		data = append(data,
+			[]string{d.Key(),
+				string(d.Status),
+				strings.Join(fields, "<br>"),
+				currencyDiff(d.DiffValue.MinRequested),
+				currencyDiff(d.DiffValue.HPABuffer),
+				currencyDiff(d.DiffValue.MaxRequested),
+				currencyDiff(d.DiffValue.MinLimited),
+				currencyDiff(d.DiffValue.MaxLimited)})
+	}
+
+	out := &strings.Builder{}
+	table := tablewriter.NewWriter(out)
+	table.SetHeader(
+		[]string{"Object",
+			"Change",
+			"Changed Fields",
+			headers[0] + " (USD)",
+			headers[1] + " (USD)",
+			headers[2] + " (USD)",
+			headers[3] + " (USD)",
+			headers[4] + " (USD)"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.SetAutoWrapText(false)
+	table.SetColumnAlignment([]int{0, 0, 0, 2, 2, 2, 2, 2})
+	table.AppendBulk(data)
+	table.Render()
+	return out.String()
+}
+
+func formatCPU(millis int64) string {
+	if millis%1000 == 0 {
+		return fmt.Sprintf("%d", millis/1000)
+	}
+	return fmt.Sprintf("%dm", millis)
+}
+
+func formatBytes(bytes int64) string {
+	units := []struct {
+		suffix string
+		size   int64
+	}{
+		{"Ti", 1024 * 1024 * 1024 * 1024},
+		{"Gi", 1024 * 1024 * 1024},
+		{"Mi", 1024 * 1024},
+		{"Ki", 1024},
+	}
+	for _, u := range units {
+		if bytes != 0 && bytes%u.size == 0 {
+			return fmt.Sprintf("%d%s", bytes/u.size, u.suffix)
+		}
+	}
+	return fmt.Sprintf("%d", bytes)
+}
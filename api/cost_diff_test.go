@@ -0,0 +1,178 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSubtractObjectDiffs(t *testing.T) {
+	prevData := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: frontend
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: frontend
+        image: nginx
+        resources:
+          requests:
+            memory: "10"
+            cpu: "1"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: unchanged
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: unchanged
+        image: nginx
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: legacy
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: legacy
+        image: nginx
+        resources:
+          requests:
+            memory: "10"
+            cpu: "1"`
+
+	currData := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: frontend
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: frontend
+        image: nginx
+        resources:
+          requests:
+            memory: "10"
+            cpu: "250m"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: unchanged
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: unchanged
+        image: nginx
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: backend
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: backend
+        image: nginx
+        resources:
+          requests:
+            memory: "10"
+            cpu: "1"`
+
+	pp := &fixedPriceProvider{cpuPrice: 10, memoryPrice: 0.5}
+	prev := Manifests{}
+	err := prev.LoadObjects([]byte(prevData), CostimatorConfig{})
+	if err != nil {
+		t.Fatalf("Error loading objects: %+v", err)
+	}
+	curr := Manifests{}
+	err = curr.LoadObjects([]byte(currData), CostimatorConfig{})
+	if err != nil {
+		t.Fatalf("Error loading objects: %+v", err)
+	}
+	prevCost := prev.EstimateCost(pp)
+	currCost := curr.EstimateCost(pp)
+	diff := currCost.Subtract(prevCost)
+
+	if len(diff.ObjectDiffs) != 3 {
+		t.Fatalf("Expected 3 object diffs, got %+v", diff.ObjectDiffs)
+	}
+
+	added := diff.ObjectDiffs[0]
+	if added.Key() != "default/Deployment/backend" || added.Status != ObjectAdded || added.DiffValue.MinRequested != 15 {
+		t.Errorf("Expected backend to be added costing 15, got %+v", added)
+	}
+
+	changed := diff.ObjectDiffs[1]
+	if changed.Key() != "default/Deployment/frontend" || changed.Status != ObjectChanged || changed.DiffValue.MinRequested != -7.5 {
+		t.Errorf("Expected frontend to be changed saving 7.5, got %+v", changed)
+	}
+	expectedFields := []FieldChange{
+		{Field: "replicas", Previous: "2", Current: "3"},
+		{Field: "hpa.minReplicas", Previous: "2", Current: "3"},
+		{Field: "hpa.maxReplicas", Previous: "2", Current: "3"},
+		{Field: "requests.cpu", Previous: "1", Current: "250m"},
+		{Field: "limits.cpu", Previous: "3", Current: "750m"},
+	}
+	if !cmp.Equal(changed.ChangedFields, expectedFields) {
+		t.Errorf("ChangedFields should be equal, expected: %+v, got: %+v", expectedFields, changed.ChangedFields)
+	}
+
+	removed := diff.ObjectDiffs[2]
+	if removed.Key() != "default/Deployment/legacy" || removed.Status != ObjectRemoved || removed.DiffValue.MinRequested != -15 {
+		t.Errorf("Expected legacy to be removed saving 15, got %+v", removed)
+	}
+
+	markdown := objectDiffsToMarkdown(diff.ObjectDiffs)
+	if !strings.Contains(markdown, "requests.cpu: 1 "+rightArrow+" 250m") || strings.Contains(markdown, "unchanged") {
+		t.Errorf("Markdown should explain changed fields and skip unchanged objects, got:\n%s", markdown)
+	}
+	if !strings.Contains(diff.ToMarkdown(), "## Difference in Costs per Object") {
+		t.Errorf("Markdown should contain the difference in costs per object")
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:                      "0",
+		1000:                   "1000",
+		64 * 1024 * 1024:       "64Mi",
+		8 * 1024 * 1024 * 1024: "8Gi",
+	}
+	for bytes, want := range tests {
+		if got := formatBytes(bytes); got != want {
+			t.Errorf("formatBytes(%d) = %v, want %v", bytes, got, want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// SpotComparison holds the cost of all workloads of a kind priced at on-demand and at Spot VM rates
+// It allows teams to see the savings of running workloads on Spot VMs
+type SpotComparison struct {
+	Kind     string    `json:"kind"`
+	OnDemand CostRange `json:"onDemand"`
+	Spot     CostRange `json:"spot"`
+}
+
+// Savings returns the percentage saved when running on Spot VMs (based on MinRequested)
+func (s *SpotComparison) Savings() float64 {
+	if s.OnDemand.MinRequested == 0 {
+		return 0
+	}
+	return (s.OnDemand.MinRequested - s.Spot.MinRequested) * 100 / s.OnDemand.MinRequested
+}
+
+func spotComparisonsToMarkdown(comparisons []SpotComparison) string {
+	data := [][]string{}
+	total := SpotComparison{Kind: bold("TOTAL")}
+	for _, c := range comparisons {
+		data = append(data,
+			[]string{c.Kind,
+				currency(c.OnDemand.MinRequested),
+				currency(c.Spot.MinRequested),
+				currency(c.OnDemand.MaxLimited),
+				currency(c.Spot.MaxLimited),
+				fmt.Sprintf("%.2f%%", c.Savings())})
+		total.OnDemand = total.OnDemand.Add(c.OnDemand)
+		total.Spot = total.Spot.Add(c.Spot)
+	}
+	data = append(data,
+		[]string{total.Kind,
+			bold(currency(total.OnDemand.MinRequested)),
+			bold(currency(total.Spot.MinRequested)),
+			bold(currency(total.OnDemand.MaxLimited)),
+			bold(currency(total.Spot.MaxLimited)),
+			bold(fmt.Sprintf("%.2f%%", total.Savings()))})
+
+	out := &strings.Builder{}
+	table := tablewriter.NewWriter(out)
+	table.SetHeader(
+		[]string{"Kind",
+			"On-Demand " + headers[0] + " (USD)",
+			"Spot " + headers[0] + " (USD)",
+			"On-Demand " + headers[4] + " (USD)",
+			"Spot " + headers[4] + " (USD)",
+			"Spot Savings (%)"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.SetColumnAlignment([]int{0, 2, 2, 2, 2, 2})
+	table.AppendBulk(data)
+	table.Render()
+	return out.String()
+}
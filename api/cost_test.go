@@ -0,0 +1,42 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCostToMarkdownPerObject(t *testing.T) {
+	cost := Cost{
+		MonthlyRanges: []CostRange{
+			{Kind: DeploymentKind, MinRequested: 45, MaxRequested: 45, HPABuffer: 45, MinLimited: 45, MaxLimited: 45},
+		},
+		MonthlyObjectRanges: []ObjectCostRange{
+			newObjectCostRange("apps/v1|Deployment|shop|frontend", CostRange{Kind: DeploymentKind, MinRequested: 30, MaxRequested: 30, HPABuffer: 30, MinLimited: 30, MaxLimited: 30}),
+			newObjectCostRange("apps/v1|Deployment|default|backend", CostRange{Kind: DeploymentKind, MinRequested: 15, MaxRequested: 15, HPABuffer: 15, MinLimited: 15, MaxLimited: 15}),
+		},
+	}
+
+	markdown := cost.ToMarkdown()
+	for _, expected := range []string{"| Deployment |", "**$45.00**", "| default   | Deployment | backend  |", "| shop      | Deployment | frontend |"} {
+		if !strings.Contains(markdown, expected) {
+			t.Errorf("Markdown should contain '%s', got:\n%s", expected, markdown)
+		}
+	}
+	if strings.Index(markdown, "backend") > strings.Index(markdown, "frontend") {
+		t.Errorf("Objects should be sorted by namespace/kind/name, got:\n%s", markdown)
+	}
+}
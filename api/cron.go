@@ -0,0 +1,182 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var cronMonthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+var cronDayNames = map[string]int{
+	"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+}
+
+// cronSchedule is the parsed representation of a k8s CronJob schedule (standard 5 fields cron)
+type cronSchedule struct {
+	minutes     map[int]bool
+	hours       map[int]bool
+	daysOfMonth map[int]bool
+	months      map[int]bool
+	daysOfWeek  map[int]bool
+	anyDOM      bool
+	anyDOW      bool
+}
+
+// monthlyRuns returns the average number of runs per month of a cron schedule expression
+// Runs are counted over a whole (non leap) year, so schedules like @yearly are properly averaged
+func monthlyRuns(schedule string) (float64, error) {
+	cs, err := parseCronSchedule(schedule)
+	if err != nil {
+		return 0, err
+	}
+
+	runsPerDay := float64(len(cs.minutes) * len(cs.hours))
+	runs := 0.0
+	day := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for day.Year() == 2021 {
+		if cs.matchDay(day) {
+			runs = runs + runsPerDay
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return runs / 12, nil
+}
+
+func parseCronSchedule(schedule string) (cronSchedule, error) {
+	expr := strings.TrimSpace(schedule)
+	// timezone does not change the number of runs
+	if strings.HasPrefix(expr, "CRON_TZ=") || strings.HasPrefix(expr, "TZ=") {
+		if i := strings.Index(expr, " "); i > 0 {
+			expr = strings.TrimSpace(expr[i:])
+		}
+	}
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return cronSchedule{}, fmt.Errorf("Invalid cron schedule '%s': expected 5 fields, got %d", schedule, len(fields))
+	}
+
+	var err error
+	cs := cronSchedule{
+		anyDOM: fields[2] == "*" || fields[2] == "?",
+		anyDOW: fields[4] == "*" || fields[4] == "?",
+	}
+	if cs.minutes, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return cronSchedule{}, fmt.Errorf("Invalid cron schedule '%s': minute %+v", schedule, err)
+	}
+	if cs.hours, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return cronSchedule{}, fmt.Errorf("Invalid cron schedule '%s': hour %+v", schedule, err)
+	}
+	if cs.daysOfMonth, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return cronSchedule{}, fmt.Errorf("Invalid cron schedule '%s': day of month %+v", schedule, err)
+	}
+	if cs.months, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return cronSchedule{}, fmt.Errorf("Invalid cron schedule '%s': month %+v", schedule, err)
+	}
+	if cs.daysOfWeek, err = parseCronField(fields[4], 0, 7, cronDayNames); err != nil {
+		return cronSchedule{}, fmt.Errorf("Invalid cron schedule '%s': day of week %+v", schedule, err)
+	}
+	// 7 is also Sunday
+	if cs.daysOfWeek[7] {
+		cs.daysOfWeek[0] = true
+	}
+	return cs, nil
+}
+
+func (cs *cronSchedule) matchDay(day time.Time) bool {
+	if !cs.months[int(day.Month())] {
+		return false
+	}
+	dom := cs.daysOfMonth[day.Day()]
+	dow := cs.daysOfWeek[int(day.Weekday())]
+	// when both day of month and day of week are restricted, cron runs when either matches
+	if !cs.anyDOM && !cs.anyDOW {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+func parseCronField(field string, min, max int, names map[string]int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step '%s'", part)
+			}
+			part = part[:i]
+		}
+
+		start, end := min, max
+		if part != "*" && part != "?" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			start, err = parseCronValue(bounds[0], names)
+			if err != nil {
+				return nil, err
+			}
+			end = start
+			if len(bounds) == 2 {
+				end, err = parseCronValue(bounds[1], names)
+				if err != nil {
+					return nil, err
+				}
+			} else if step > 1 {
+				// 'a/n' means from a to max every n
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return nil, fmt.Errorf("value '%s' out of range [%d-%d]", part, min, max)
+		}
+		for v := start; v <= end; v = v + step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+func parseCronValue(value string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToUpper(value)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value '%s'", value)
+	}
+	return v, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+)
+
+func TestMonthlyRuns(t *testing.T) {
+	tests := map[string]float64{
+		"*/15 * * * *":          4 * 24 * 365 / 12.0,
+		"0 2 * * *":             365 / 12.0,
+		"@daily":                365 / 12.0,
+		"@hourly":               24 * 365 / 12.0,
+		"@yearly":               1 / 12.0,
+		"0 0 1 * *":             1,
+		"30 1 * * MON-FRI":      261 / 12.0,
+		"0 9-17/4 * * 1,3,5":    3 * 157 / 12.0,
+		"0 0 * * 0":             52 / 12.0,
+		"0 0 * * 7":             52 / 12.0,
+		"0 0 1 * 0":             (12 + 52 - 1) / 12.0,
+		"0 0 1 JAN,jul *":       2 / 12.0,
+		"CRON_TZ=UTC 0 2 * * *": 365 / 12.0,
+	}
+	for schedule, want := range tests {
+		got, err := monthlyRuns(schedule)
+		if err != nil {
+			t.Errorf("monthlyRuns(%s) returned error %+v", schedule, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("monthlyRuns(%s) = %v, want %v", schedule, got, want)
+		}
+	}
+}
+
+func TestMonthlyRunsInvalidSchedule(t *testing.T) {
+	for _, schedule := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "0 0 * * FOO"} {
+		if _, err := monthlyRuns(schedule); err == nil {
+			t.Errorf("monthlyRuns(%s) should have returned an error", schedule)
+		}
+	}
+}
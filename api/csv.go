@@ -0,0 +1,93 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// costDimensions are the CostRange values, named as in JSON reports
+var costDimensions = []struct {
+	name  string
+	value func(CostRange) float64
+}{
+	{"minRequested", func(c CostRange) float64 { return c.MinRequested }},
+	{"hpaBuffer", func(c CostRange) float64 { return c.HPABuffer }},
+	{"maxRequested", func(c CostRange) float64 { return c.MaxRequested }},
+	{"minLimited", func(c CostRange) float64 { return c.MinLimited }},
+	{"maxLimited", func(c CostRange) float64 { return c.MaxLimited }},
+}
+
+var objectCSVHeader = []string{"namespace", "kind", "name",
+	"replicas", "minReplicas", "maxReplicas",
+	"cpuRequestsMillis", "memoryRequestsBytes", "storageRequestsBytes",
+	"cpuLimitsMillis", "memoryLimitsBytes", "storageLimitsBytes"}
+
+// ToCSV converts the cost of each k8s object to CSV, with one row per object per cost dimension (see costDimensions)
+// The long format lets spreadsheets pivot by namespace, kind or dimension. Costs are monthly USD
+func (c *Cost) ToCSV() (string, error) {
+	rows := [][]string{append(append([]string{}, objectCSVHeader...), "costDimension", "monthlyCostUSD")}
+	for _, or := range c.sortedObjectRanges() {
+		for _, dim := range costDimensions {
+			row := append(objectCSVColumns(or), dim.name, formatCSVFloat(dim.value(or.MonthlyRange)))
+			rows = append(rows, row)
+		}
+	}
+	return writeCSV(rows)
+}
+
+// ToCSV converts the objects added, removed or changed to CSV, with one row per object per cost dimension
+// Unlike Cost.ToCSV, requests and limits are not included. Changed fields are listed instead
+func (d *DiffCost) ToCSV() (string, error) {
+	rows := [][]string{{"namespace", "kind", "name", "status", "changedFields", "costDimension", "previousMonthlyCostUSD", "currentMonthlyCostUSD", "diffMonthlyCostUSD"}}
+	for _, od := range d.ObjectDiffs {
+		fields := []string{}
+		for _, f := range od.ChangedFields {
+			fields = append(fields, fmt.Sprintf("%s: %s -> %s", f.Field, f.Previous, f.Current))
+		}
+		for _, dim := range costDimensions {
+			rows = append(rows, []string{od.Namespace, od.Kind, od.Name, string(od.Status), strings.Join(fields, "; "), dim.name,
+				formatCSVFloat(dim.value(od.CostPrev)),
+				formatCSVFloat(dim.value(od.CostCurr)),
+				formatCSVFloat(dim.value(od.DiffValue))})
+		}
+	}
+	return writeCSV(rows)
+}
+
+func objectCSVColumns(or ObjectCostRange) []string {
+	return []string{or.Namespace, or.Kind, or.Name,
+		strconv.Itoa(int(or.Replicas)), strconv.Itoa(int(or.MinReplicas)), strconv.Itoa(int(or.MaxReplicas)),
+		strconv.FormatInt(or.Requests.CPU, 10), strconv.FormatInt(or.Requests.Memory, 10), strconv.FormatInt(or.Requests.Storage, 10),
+		strconv.FormatInt(or.Limits.CPU, 10), strconv.FormatInt(or.Limits.Memory, 10), strconv.FormatInt(or.Limits.Storage, 10)}
+}
+
+// formatCSVFloat rounds costs to cents, without currency symbols or thousand separators, so spreadsheets parse them as numbers
+func formatCSVFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+func writeCSV(rows [][]string) (string, error) {
+	out := &strings.Builder{}
+	w := csv.NewWriter(out)
+	err := w.WriteAll(rows)
+	if err != nil {
+		return "", fmt.Errorf("Unable to write CSV: %+v", err)
+	}
+	return out.String(), nil
+}
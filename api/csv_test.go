@@ -0,0 +1,67 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+func TestCostToCSV(t *testing.T) {
+	cost := reportTestCost(2)
+	cost.MonthlyObjectRanges[1].Requests = Resource{CPU: 250, Memory: 64 * 1024 * 1024}
+	data, err := cost.ToCSV()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("Invalid CSV: %+v\n%s", err, data)
+	}
+	// header plus one row per object per cost dimension
+	if len(rows) != 1+2*5 {
+		t.Fatalf("CSV should have 11 rows, got %d:\n%s", len(rows), data)
+	}
+	expected := "default,Deployment,backend,2,0,0,250,67108864,0,0,0,0,minLimited,30.00"
+	if got := strings.Join(rows[4], ","); got != expected {
+		t.Errorf("Expected row '%s', got '%s'", expected, got)
+	}
+	if got := rows[6][2]; got != "frontend" {
+		t.Errorf("Objects should be sorted by namespace/kind/name, got '%s' after backend rows", got)
+	}
+}
+
+func TestDiffCostToCSV(t *testing.T) {
+	curr := reportTestCost(2)
+	diff := curr.Subtract(reportTestCost(1))
+	data, err := diff.ToCSV()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("Invalid CSV: %+v\n%s", err, data)
+	}
+	if len(rows) != 1+5 {
+		t.Fatalf("Only changed backend object should be in the diff CSV, got:\n%s", data)
+	}
+	expected := "default,Deployment,backend,changed,replicas: 1 -> 2,minRequested,15.00,15.00,0.00"
+	if got := strings.Join(rows[1], ","); got != expected {
+		t.Errorf("Expected row '%s', got '%s'", expected, got)
+	}
+}
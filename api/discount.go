@@ -0,0 +1,203 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+)
+
+// committedUseDiscounts are the GCP published resource-based CUD rates (%) for general-purpose machine families
+var committedUseDiscounts = map[CommitmentTerm]float64{
+	OneYear:    37,
+	ThreeYears: 55,
+}
+
+// memoryOptimizedCommittedUseDiscounts are the GCP published resource-based CUD rates (%) for memory-optimized machine families
+var memoryOptimizedCommittedUseDiscounts = map[CommitmentTerm]float64{
+	OneYear:    41,
+	ThreeYears: 70,
+}
+
+// sustainedUseDiscounts are the GCP sustained use discount rates (%) for resources running the whole month
+// Machine families not listed (eg. E2) are not eligible
+var sustainedUseDiscounts = map[MachineFamily]float64{
+	N1:  30,
+	N2:  20,
+	N2D: 20,
+	C2:  20,
+	M1:  30,
+}
+
+// Discounts holds the savings (USD) of committed use and sustained use discounts
+// Negative committed use savings mean the commitments are not fully used
+// Spot VMs and storage are not eligible for discounts
+type Discounts struct {
+	CommittedUse CostRange `json:"committedUse"`
+	SustainedUse CostRange `json:"sustainedUse"`
+}
+
+// resourceDiscount applies the discounts to the monthly usage of a single resource (vCPUs or memory bytes)
+type resourceDiscount struct {
+	price        float64
+	commitments  []commitment
+	sustainedUse float64
+}
+
+type commitment struct {
+	quantity float64
+	discount float64
+}
+
+// newDiscounts returns nil when there are neither commitments nor sustained use discount for the configured region and machine family
+func newDiscounts(cpuUsage, memoryUsage CostRange, rp ResourcePrice, conf CostimatorConfig) *Discounts {
+	conf = populateConfigNotProvided(conf)
+	cpu := resourceDiscount{price: float64(rp.CPUMonthlyPrice())}
+	memory := resourceDiscount{price: float64(rp.MemoryMonthlyPrice())}
+	for _, cud := range conf.DiscountConf.CommittedUse {
+		if !cud.matches(conf) {
+			log.Debugf("Skipping committed use discount for another region or machine family: %+v", cud)
+			continue
+		}
+		discount := cud.discount(conf.ResourceConf.MachineFamily)
+		cpu.commitments = append(cpu.commitments, commitment{quantity: cud.VCPUs, discount: discount})
+		memory.commitments = append(memory.commitments, commitment{quantity: cud.MemoryGiB * bytesInGiB, discount: discount})
+	}
+	if !conf.DiscountConf.DisableSustainedUse {
+		cpu.sustainedUse = sustainedUseDiscounts[conf.ResourceConf.MachineFamily]
+		memory.sustainedUse = cpu.sustainedUse
+	}
+	if len(cpu.commitments) == 0 && cpu.sustainedUse == 0 {
+		return nil
+	}
+
+	discounts := Discounts{
+		CommittedUse: CostRange{Kind: "CommittedUse"},
+		SustainedUse: CostRange{Kind: "SustainedUse"},
+	}
+	apply := func(cpuUsage, memoryUsage float64) (committedUse, sustainedUse float64) {
+		cpuCommitted, cpuSustained := cpu.savings(cpuUsage)
+		memoryCommitted, memorySustained := memory.savings(memoryUsage)
+		return cpuCommitted + memoryCommitted, cpuSustained + memorySustained
+	}
+	discounts.CommittedUse.MinRequested, discounts.SustainedUse.MinRequested = apply(cpuUsage.MinRequested, memoryUsage.MinRequested)
+	discounts.CommittedUse.MaxRequested, discounts.SustainedUse.MaxRequested = apply(cpuUsage.MaxRequested, memoryUsage.MaxRequested)
+	discounts.CommittedUse.HPABuffer, discounts.SustainedUse.HPABuffer = apply(cpuUsage.HPABuffer, memoryUsage.HPABuffer)
+	discounts.CommittedUse.MinLimited, discounts.SustainedUse.MinLimited = apply(cpuUsage.MinLimited, memoryUsage.MinLimited)
+	discounts.CommittedUse.MaxLimited, discounts.SustainedUse.MaxLimited = apply(cpuUsage.MaxLimited, memoryUsage.MaxLimited)
+	return &discounts
+}
+
+// savings returns how much is saved from list price for the given usage
+// Usage is covered by the highest discount commitments first. Commitments are paid even if not used
+// Usage not covered by commitments gets the sustained use discount
+func (r *resourceDiscount) savings(usage float64) (committedUse, sustainedUse float64) {
+	commitments := make([]commitment, len(r.commitments))
+	copy(commitments, r.commitments)
+	sort.SliceStable(commitments, func(i, j int) bool {
+		return commitments[i].discount > commitments[j].discount
+	})
+
+	uncovered := usage
+	for _, c := range commitments {
+		covered := c.quantity
+		if covered > uncovered {
+			covered = uncovered
+		}
+		uncovered = uncovered - covered
+		fee := c.quantity * r.price * (100 - c.discount) / 100
+		committedUse = committedUse + (covered * r.price) - fee
+	}
+	sustainedUse = uncovered * r.price * r.sustainedUse / 100
+	return
+}
+
+func (c *CommittedUseDiscount) matches(conf CostimatorConfig) bool {
+	return (c.Region == "" || strings.EqualFold(c.Region, conf.ResourceConf.Region)) &&
+		(c.MachineFamily == "" || strings.EqualFold(string(c.MachineFamily), string(conf.ResourceConf.MachineFamily)))
+}
+
+func (c *CommittedUseDiscount) discount(family MachineFamily) float64 {
+	if c.DiscountPercentage > 0 {
+		return c.DiscountPercentage
+	}
+	discounts := committedUseDiscounts
+	if family == M1 {
+		discounts = memoryOptimizedCommittedUseDiscounts
+	}
+	discount, ok := discounts[c.Term]
+	if !ok {
+		log.Warnf("Commitment term '%s' not supported. Use '%s' or '%s'. Using '%s' discount instead", c.Term, OneYear, ThreeYears, OneYear)
+		return discounts[OneYear]
+	}
+	return discount
+}
+
+// Effective returns the given list cost minus discounts
+func (d *Discounts) Effective(list CostRange) CostRange {
+	effective := CostRange{Kind: "Effective"}
+	effective.MinRequested = list.MinRequested - d.CommittedUse.MinRequested - d.SustainedUse.MinRequested
+	effective.MaxRequested = list.MaxRequested - d.CommittedUse.MaxRequested - d.SustainedUse.MaxRequested
+	effective.HPABuffer = list.HPABuffer - d.CommittedUse.HPABuffer - d.SustainedUse.HPABuffer
+	effective.MinLimited = list.MinLimited - d.CommittedUse.MinLimited - d.SustainedUse.MinLimited
+	effective.MaxLimited = list.MaxLimited - d.CommittedUse.MaxLimited - d.SustainedUse.MaxLimited
+	return effective
+}
+
+func discountsToMarkdown(list CostRange, d *Discounts) string {
+	effective := d.Effective(list)
+	row := func(name string, cr CostRange, format func(float64) string) []string {
+		return []string{name,
+			format(cr.MinRequested),
+			format(cr.HPABuffer),
+			format(cr.MaxRequested),
+			format(cr.MinLimited),
+			format(cr.MaxLimited)}
+	}
+	savings := func(value float64) string {
+		if value == 0 {
+			return currency(0)
+		}
+		return currency(-value)
+	}
+	boldCurrency := func(value float64) string {
+		return bold(currency(value))
+	}
+	data := [][]string{
+		row("List", list, currency),
+		row("Committed use discount", d.CommittedUse, savings),
+		row("Sustained use discount", d.SustainedUse, savings),
+		row(bold("EFFECTIVE"), effective, boldCurrency),
+	}
+
+	out := &strings.Builder{}
+	table := tablewriter.NewWriter(out)
+	table.SetHeader(
+		[]string{"Cost",
+			headers[0] + " (USD)",
+			headers[1] + " (USD)",
+			headers[2] + " (USD)",
+			headers[3] + " (USD)",
+			headers[4] + " (USD)"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.SetColumnAlignment([]int{0, 2, 2, 2, 2, 2})
+	table.AppendBulk(data)
+	table.Render()
+	return out.String()
+}
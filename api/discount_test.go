@@ -0,0 +1,157 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResourceDiscountSavings(t *testing.T) {
+	r := resourceDiscount{
+		price:        10,
+		commitments:  []commitment{{quantity: 1, discount: 37}, {quantity: 2, discount: 55}},
+		sustainedUse: 30,
+	}
+
+	// 3y commitment covers 2, 1y commitment covers 1, remaining 1 gets sustained use discount
+	committedUse, sustainedUse := r.savings(4)
+	if want := 30 - (6.3 + 9); committedUse != want {
+		t.Errorf("Committed use savings is %v, want %v", committedUse, want)
+	}
+	if want := 3.0; sustainedUse != want {
+		t.Errorf("Sustained use savings is %v, want %v", sustainedUse, want)
+	}
+
+	// unused commitments are still paid
+	committedUse, sustainedUse = r.savings(1)
+	if want := 10 - (6.3 + 9); committedUse != want || sustainedUse != 0 {
+		t.Errorf("Savings are %v and %v, want %v and 0", committedUse, sustainedUse, want)
+	}
+}
+
+func TestCommittedUseDiscountRate(t *testing.T) {
+	tests := []struct {
+		cud    CommittedUseDiscount
+		family MachineFamily
+		want   float64
+	}{
+		{cud: CommittedUseDiscount{Term: OneYear}, family: N2, want: 37},
+		{cud: CommittedUseDiscount{Term: ThreeYears}, family: C2, want: 55},
+		{cud: CommittedUseDiscount{Term: OneYear}, family: M1, want: 41},
+		{cud: CommittedUseDiscount{Term: ThreeYears}, family: M1, want: 70},
+		{cud: CommittedUseDiscount{Term: ThreeYears, DiscountPercentage: 60}, family: M1, want: 60},
+	}
+	for _, tt := range tests {
+		if got := tt.cud.discount(tt.family); got != tt.want {
+			t.Errorf("%s commitment for %s should have %v%% discount, got %v%%", tt.cud.Term, tt.family, tt.want, got)
+		}
+	}
+}
+
+func TestNewDiscountsNotApplicable(t *testing.T) {
+	usage := CostRange{MinRequested: 1}
+	rp := &GCPPriceCatalog{cpuPrice: 10, memoryPrice: 1}
+
+	// E2 is not eligible for sustained use discounts
+	if got := newDiscounts(usage, usage, rp, CostimatorConfig{}); got != nil {
+		t.Errorf("Discounts should be nil, got %+v", got)
+	}
+
+	conf := CostimatorConfig{
+		ResourceConf: ResourceConfig{MachineFamily: N1},
+		DiscountConf: DiscountConfig{
+			DisableSustainedUse: true,
+			CommittedUse:        []CommittedUseDiscount{{Region: "europe-west1", Term: OneYear, VCPUs: 1}},
+		},
+	}
+	if got := newDiscounts(usage, usage, rp, conf); got != nil {
+		t.Errorf("Discounts should be nil for commitments in another region, got %+v", got)
+	}
+}
+
+func TestEstimateCostWithDiscounts(t *testing.T) {
+	data := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: my-nginx
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: my-nginx
+        image: nginx
+        resources:
+          requests:
+            memory: "1"
+            cpu: "1"
+          limits:
+            memory: "1"
+            cpu: "1"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: spot
+spec:
+  replicas: 5
+  template:
+    spec:
+      nodeSelector:
+        cloud.google.com/gke-spot: "true"
+      containers:
+      - name: spot
+        image: nginx
+        resources:
+          requests:
+            memory: "1"
+            cpu: "1"
+          limits:
+            memory: "1"
+            cpu: "1"`
+
+	conf := CostimatorConfig{
+		ResourceConf: ResourceConfig{MachineFamily: N1},
+		DiscountConf: DiscountConfig{
+			CommittedUse: []CommittedUseDiscount{{Term: ThreeYears, VCPUs: 1}},
+		},
+	}
+	manifests := Manifests{}
+	err := manifests.LoadObjects([]byte(data), conf)
+	if err != nil {
+		t.Errorf("Error loading objects: %+v", err)
+	}
+
+	pc := &GCPPriceCatalog{cpuPrice: 10, spotCPUPrice: 2, spotMemoryPrice: 1}
+	cost := manifests.EstimateCost(pc)
+	if cost.Discounts == nil {
+		t.Fatalf("Discounts should have been estimated")
+	}
+
+	// spot VMs (5 * 3) are not discounted: 1 vCPU is committed (10 - 4.5) and 1 vCPU gets sustained use discount (10 * 30%)
+	expected := CostRange{Kind: "MonthlyEffectiveTotal", MinRequested: 26.5, MaxRequested: 26.5, HPABuffer: 26.5, MinLimited: 26.5, MaxLimited: 26.5}
+	if got := cost.MonthlyEffectiveTotal(); !cmp.Equal(got, expected) {
+		t.Errorf("MonthlyEffectiveTotal should be equal, expected: %+v, got: %+v", expected, got)
+	}
+	if got := cost.MonthlyTotal().MinRequested; got != 35 {
+		t.Errorf("MonthlyTotal MinRequested is %v, want 35", got)
+	}
+	if !strings.Contains(cost.ToMarkdown(), "**EFFECTIVE**") {
+		t.Errorf("Markdown should contain effective cost, got: %s", cost.ToMarkdown())
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+	coreV1 "k8s.io/api/core/v1"
+)
+
+// LocalSSDNodeLabel is the label GKE sets on nodes using local SSDs as ephemeral storage
+const LocalSSDNodeLabel = "cloud.google.com/gke-ephemeral-storage-local-ssd"
+
+// bootDisk is the GKE default node boot disk, backing ephemeral storage of nodes without local SSDs
+var bootDisk = PersistentDisk{DiskType: PdBalanced, Replication: Zonal}
+
+const (
+	// localSSDPrefix is the on-demand local SSD SKU description prefix
+	localSSDPrefix = "SSD backed Local Storage"
+	// spotLocalSSDPrefix is the local SSD attached to Spot VMs SKU description prefix
+	spotLocalSSDPrefix = "SSD backed Local Storage attached to Spot Preemptible VMs"
+)
+
+// EphemeralStorageMedium is where pod ephemeral storage (container writable layers, logs and emptyDir volumes) lives
+type EphemeralStorageMedium string
+
+const (
+	// BootDiskStorage is ephemeral storage on the node boot disk
+	BootDiskStorage EphemeralStorageMedium = "Boot disk"
+	// LocalSSDStorage is ephemeral storage on node local SSDs
+	LocalSSDStorage EphemeralStorageMedium = "Local SSD"
+	// AutopilotStorage is ephemeral storage requested by GKE Autopilot pods
+	AutopilotStorage EphemeralStorageMedium = "Autopilot"
+)
+
+// NodeStoragePrice interface is implemented by resource prices knowing the prices of the storage backing nodes ephemeral storage
+// found is false if local SSD prices are not available
+type NodeStoragePrice interface {
+	BootDiskMonthlyPrice() float32
+	LocalSSDMonthlyPrice() (price float32, found bool)
+}
+
+// EphemeralStorageCost is the ephemeral storage share of the workloads cost for a given medium
+// It is already included in the cost of each kind and object
+type EphemeralStorageCost struct {
+	Medium       EphemeralStorageMedium `json:"medium"`
+	MonthlyRange CostRange              `json:"monthlyRange"`
+}
+
+// BootDiskMonthlyPrice returns the GCP price in USD of the GKE default boot disk
+func (pc *GCPPriceCatalog) BootDiskMonthlyPrice() float32 {
+	if price, ok := pc.diskPrices[bootDisk]; ok {
+		return price
+	}
+	return pc.diskPrices[defaultPersistentDisk]
+}
+
+// LocalSSDMonthlyPrice returns the GCP local SSD price in USD
+func (pc *GCPPriceCatalog) LocalSSDMonthlyPrice() (float32, bool) {
+	return pc.localSSDPrice, pc.localSSDPrice != 0
+}
+
+// withEphemeralStorage adds emptyDir volumes size limits to the pod resources and flags pods whose ephemeral storage is on local SSDs
+// Memory backed emptyDir volumes are accounted in containers memory, while Autopilot only bills containers requests
+func withEphemeralStorage(containers []Container, spec coreV1.PodSpec, conf CostimatorConfig) []Container {
+	if conf.ClusterConf.Mode != Autopilot {
+		for _, volume := range spec.Volumes {
+			emptyDir := volume.EmptyDir
+			if emptyDir == nil || emptyDir.Medium == coreV1.StorageMediumMemory || emptyDir.SizeLimit == nil {
+				continue
+			}
+			size := Resource{Storage: emptyDir.SizeLimit.Value()}
+			containers = append(containers, Container{Requests: size, Limits: size, Type: EmptyDirVolume})
+		}
+	}
+	if !onLocalSSD(spec, conf) {
+		return containers
+	}
+	for i := range containers {
+		containers[i].Requests.LocalSSD = true
+		containers[i].Limits.LocalSSD = true
+	}
+	return containers
+}
+
+// onLocalSSD tells if pods select nodes using local SSDs as ephemeral storage, either directly or through the node pool they are placed on
+func onLocalSSD(spec coreV1.PodSpec, conf CostimatorConfig) bool {
+	if pool, found := matchNodePool(spec, conf); found {
+		return pool.LocalSSD
+	}
+	return spec.NodeSelector[LocalSSDNodeLabel] == "true"
+}
+
+// ephemeralStoragePrice returns the price of pod ephemeral storage, given where it lives
+// found is false when the resource price doesn't bill ephemeral storage
+func ephemeralStoragePrice(rp ResourcePrice, localSSD bool) (price float32, medium EphemeralStorageMedium, found bool) {
+	if sp, ok := rp.(EphemeralStoragePrice); ok {
+		return sp.EphemeralStorageMonthlyPrice(), AutopilotStorage, true
+	}
+	np, ok := rp.(NodeStoragePrice)
+	if !ok {
+		return 0, "", false
+	}
+	if localSSD {
+		if price, found := np.LocalSSDMonthlyPrice(); found {
+			return price, LocalSSDStorage, true
+		}
+		log.Debugf("Local SSD prices not available in the price catalog. Using boot disk prices instead")
+	}
+	return np.BootDiskMonthlyPrice(), BootDiskStorage, true
+}
+
+// estimateEphemeralStorageCost returns the ephemeral storage share of the workloads cost, by medium
+func (m *Manifests) estimateEphemeralStorageCost(rp ResourcePrice) []EphemeralStorageCost {
+	costs := []EphemeralStorageCost{}
+	indexes := make(map[EphemeralStorageMedium]int)
+	for _, workload := range m.workloads() {
+		requests, limits := sumContainers(workload.getContainers())
+		if requests.Storage == 0 && limits.Storage == 0 {
+			continue
+		}
+		price := workloadResourcePrice(poolResourcePrice(rp, workload.getNodePool()), workload.isSpot(), workload.getComputeClass())
+		storagePrice, medium, found := ephemeralStoragePrice(price, requests.LocalSSD)
+		if !found {
+			continue
+		}
+		cost := workload.estimateCost(&storageResourcePrice{storage: storagePrice})
+		i, ok := indexes[medium]
+		if !ok {
+			i = len(costs)
+			indexes[medium] = i
+			costs = append(costs, EphemeralStorageCost{Medium: medium, MonthlyRange: CostRange{Kind: "EphemeralStorage"}})
+		}
+		costs[i].MonthlyRange = costs[i].MonthlyRange.Add(cost)
+	}
+	if len(costs) == 0 {
+		return nil
+	}
+	return costs
+}
+
+// storageResourcePrice prices ephemeral storage only, so the ephemeral storage share of a workload cost can be estimated
+type storageResourcePrice struct {
+	storage float32
+}
+
+func (s *storageResourcePrice) CPUMonthlyPrice() float32 {
+	return 0
+}
+
+func (s *storageResourcePrice) MemoryMonthlyPrice() float32 {
+	return 0
+}
+
+func (s *storageResourcePrice) EphemeralStorageMonthlyPrice() float32 {
+	return s.storage
+}
+
+func ephemeralStorageCostsToMarkdown(costs []EphemeralStorageCost) string {
+	data := [][]string{}
+	for _, c := range costs {
+		mr := c.MonthlyRange
+		data = append(data,
+			[]string{string(c.Medium),
+				currency(mr.MinRequested),
+				currency(mr.HPABuffer),
+				currency(mr.MaxRequested),
+				currency(mr.MinLimited),
+				currency(mr.MaxLimited)})
+	}
+
+	out := &strings.Builder{}
+	table := tablewriter.NewWriter(out)
+	table.SetHeader(
+		[]string{"Medium",
+			headers[0] + " (USD)",
+			headers[1] + " (USD)",
+			headers[2] + " (USD)",
+			headers[3] + " (USD)",
+			headers[4] + " (USD)"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.SetColumnAlignment([]int{0, 2, 2, 2, 2, 2})
+	table.AppendBulk(data)
+	table.Render()
+	return out.String()
+}
@@ -0,0 +1,154 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEphemeralStorageResources(t *testing.T) {
+	data := `apiVersion: v1
+kind: Pod
+metadata:
+  name: cache
+spec:
+  nodeSelector:
+    cloud.google.com/gke-ephemeral-storage-local-ssd: "true"
+  containers:
+  - name: cache
+    image: cache
+    resources:
+      requests:
+        ephemeral-storage: 1Gi
+      limits:
+        ephemeral-storage: 2Gi
+  volumes:
+  - name: scratch
+    emptyDir:
+      sizeLimit: 4Gi
+  - name: unbounded
+    emptyDir: {}
+  - name: tmpfs
+    emptyDir:
+      medium: Memory
+      sizeLimit: 1Gi`
+
+	pod, err := decodePod([]byte(data), CostimatorConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests, limits := sumContainers(pod.Containers)
+	if requests.Storage != 5*bytesInGiB || limits.Storage != 6*bytesInGiB {
+		t.Errorf("Ephemeral storage should be 5Gi requested and 6Gi limited, got %v and %v", requests.Storage, limits.Storage)
+	}
+	if !requests.LocalSSD || !limits.LocalSSD {
+		t.Errorf("Ephemeral storage should be on local SSDs, got %+v and %+v", requests, limits)
+	}
+
+	// Autopilot only bills containers ephemeral storage requests
+	pod, err = decodePod([]byte(data), CostimatorConfig{ClusterConf: ClusterConfig{Mode: Autopilot}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, container := range pod.Containers {
+		if container.Type == EmptyDirVolume {
+			t.Errorf("emptyDir volumes should not be accounted in Autopilot, got %+v", container)
+		}
+	}
+}
+
+func TestBootDiskMonthlyPrice(t *testing.T) {
+	pc := &GCPPriceCatalog{diskPrices: map[PersistentDisk]float32{defaultPersistentDisk: 1, bootDisk: 2}}
+	if got := pc.BootDiskMonthlyPrice(); got != 2 {
+		t.Errorf("Boot disk price should be %s price, got %v", bootDisk, got)
+	}
+	pc = &GCPPriceCatalog{diskPrices: map[PersistentDisk]float32{defaultPersistentDisk: 1}}
+	if got := pc.BootDiskMonthlyPrice(); got != 1 {
+		t.Errorf("Boot disk price should fall back to %s price, got %v", defaultPersistentDisk, got)
+	}
+	if _, found := pc.LocalSSDMonthlyPrice(); found {
+		t.Errorf("Local SSD price should not have been found")
+	}
+}
+
+func TestEstimateCostEphemeralStorage(t *testing.T) {
+	data := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx
+        resources:
+          requests:
+            cpu: "1"
+            ephemeral-storage: "10"
+          limits:
+            cpu: "1"
+            ephemeral-storage: "20"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: cache
+spec:
+  template:
+    spec:
+      nodeSelector:
+        cloud.google.com/gke-ephemeral-storage-local-ssd: "true"
+      containers:
+      - name: cache
+        image: cache
+        resources:
+          requests:
+            cpu: "1"
+          limits:
+            cpu: "1"
+      volumes:
+      - name: scratch
+        emptyDir:
+          sizeLimit: "100"`
+
+	manifests := Manifests{}
+	err := manifests.LoadObjects([]byte(data), CostimatorConfig{})
+	if err != nil {
+		t.Fatalf("Error loading objects: %+v", err)
+	}
+	pc := &GCPPriceCatalog{
+		cpuPrice:      1,
+		diskPrices:    map[PersistentDisk]float32{defaultPersistentDisk: 0.5, bootDisk: 1},
+		localSSDPrice: 2,
+	}
+	cost := manifests.EstimateCost(pc)
+
+	// web: 2 * (1 + 10 * 1), cache: 1 + 100 * 2
+	want := CostRange{Kind: DeploymentKind, MinRequested: 223, MaxRequested: 223, HPABuffer: 223, MinLimited: 243, MaxLimited: 243}
+	if !cmp.Equal(cost.MonthlyRanges[0], want) {
+		t.Errorf("Deployments cost should be %+v, got %+v", want, cost.MonthlyRanges[0])
+	}
+	wantStorage := []EphemeralStorageCost{
+		{Medium: BootDiskStorage, MonthlyRange: CostRange{Kind: "EphemeralStorage", MinRequested: 20, MaxRequested: 20, HPABuffer: 20, MinLimited: 40, MaxLimited: 40}},
+		{Medium: LocalSSDStorage, MonthlyRange: CostRange{Kind: "EphemeralStorage", MinRequested: 200, MaxRequested: 200, HPABuffer: 200, MinLimited: 200, MaxLimited: 200}},
+	}
+	if !cmp.Equal(cost.EphemeralStorage, wantStorage) {
+		t.Errorf("Ephemeral storage costs should be %+v, got %+v", wantStorage, cost.EphemeralStorage)
+	}
+}
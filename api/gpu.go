@@ -0,0 +1,191 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+	coreV1 "k8s.io/api/core/v1"
+)
+
+// GPUResourceName is the k8s extended resource used to request NVIDIA GPUs
+const GPUResourceName coreV1.ResourceName = "nvidia.com/gpu"
+
+// AcceleratorNodeLabel is the label GKE sets on GPU nodes with the accelerator type (eg. nvidia-tesla-t4)
+const AcceleratorNodeLabel = "cloud.google.com/gke-accelerator"
+
+// gpuPrefixes are the SKU description prefixes of each GKE accelerator type
+var gpuPrefixes = map[string]string{
+	"nvidia-tesla-k80":  "Nvidia Tesla K80 GPU",
+	"nvidia-tesla-p4":   "Nvidia Tesla P4 GPU",
+	"nvidia-tesla-p100": "Nvidia Tesla P100 GPU",
+	"nvidia-tesla-v100": "Nvidia Tesla V100 GPU",
+	"nvidia-tesla-t4":   "Nvidia Tesla T4 GPU",
+	"nvidia-tesla-a100": "Nvidia Tesla A100 GPU",
+	"nvidia-a100-80gb":  "Nvidia A100 80GB GPU",
+	"nvidia-l4":         "Nvidia L4 GPU",
+}
+
+const (
+	// gpuSuffix follows the accelerator prefix in on-demand GPU SKUs
+	gpuSuffix = " running in"
+	// spotGPUSuffix follows the accelerator prefix in Spot VM GPU SKUs
+	spotGPUSuffix = " attached to Spot Preemptible VMs running in"
+)
+
+// AcceleratorPrice interface is implemented by price providers knowing GPU prices
+// found is false if prices for the accelerator type are not available
+type AcceleratorPrice interface {
+	GPUMonthlyPrice(accelerator string) (price float32, found bool)
+}
+
+// GPUCost is the GPU share of the workloads cost for a given accelerator type
+// It is already included in the cost of each kind and object
+type GPUCost struct {
+	Accelerator  string    `json:"accelerator"`
+	MonthlyRange CostRange `json:"monthlyRange"`
+}
+
+// GPUMonthlyPrice returns the GCP price in USD of a single GPU of the given accelerator type
+func (pc *GCPPriceCatalog) GPUMonthlyPrice(accelerator string) (float32, bool) {
+	price, found := pc.gpuPrices[accelerator]
+	return price, found
+}
+
+// withAccelerator sets the accelerator type of containers requesting GPUs
+func withAccelerator(containers []Container, spec coreV1.PodSpec, conf CostimatorConfig) []Container {
+	requests, _ := sumContainers(containers)
+	if requests.GPU == 0 {
+		return containers
+	}
+	acc := accelerator(spec, conf)
+	for i := range containers {
+		if containers[i].Requests.GPU > 0 || containers[i].Limits.GPU > 0 {
+			containers[i].Requests.Accelerator = acc
+			containers[i].Limits.Accelerator = acc
+		}
+	}
+	return containers
+}
+
+// accelerator returns the GPU type pods run on, given by the accelerator nodeSelector (or required node affinity)
+// or by the labels of the node pool pods are placed on. It defaults to ResourceConfig.DefaultAccelerator
+func accelerator(spec coreV1.PodSpec, conf CostimatorConfig) string {
+	if acc, ok := spec.NodeSelector[AcceleratorNodeLabel]; ok {
+		return acc
+	}
+	if spec.Affinity != nil && spec.Affinity.NodeAffinity != nil && spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		for _, term := range spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+			for _, expr := range term.MatchExpressions {
+				if expr.Key == AcceleratorNodeLabel && expr.Operator == coreV1.NodeSelectorOpIn && len(expr.Values) > 0 {
+					return expr.Values[0]
+				}
+			}
+		}
+	}
+	if pool, found := matchNodePool(spec, conf); found {
+		if acc, ok := pool.Labels[AcceleratorNodeLabel]; ok {
+			return acc
+		}
+	}
+	log.Infof("GPUs requested without '%s' nodeSelector. Using resourceConf.defaultAccelerator '%s' instead", AcceleratorNodeLabel, conf.ResourceConf.DefaultAccelerator)
+	return conf.ResourceConf.DefaultAccelerator
+}
+
+// estimateGPUCost returns the GPU share of the workloads cost, by accelerator type
+func (m *Manifests) estimateGPUCost(rp ResourcePrice) []GPUCost {
+	costs := []GPUCost{}
+	indexes := make(map[string]int)
+	for _, workload := range m.workloads() {
+		requests, _ := sumContainers(workload.getContainers())
+		if requests.GPU == 0 {
+			continue
+		}
+		price := workloadResourcePrice(poolResourcePrice(rp, workload.getNodePool()), workload.isSpot(), workload.getComputeClass())
+		ap, ok := price.(AcceleratorPrice)
+		if ok {
+			_, ok = ap.GPUMonthlyPrice(requests.Accelerator)
+		}
+		if !ok {
+			log.Warnf("GPU prices for '%s' not available in the price catalog. GPUs are not priced", requests.Accelerator)
+			continue
+		}
+		cost := workload.estimateCost(&gpuResourcePrice{AcceleratorPrice: ap})
+		i, ok := indexes[requests.Accelerator]
+		if !ok {
+			i = len(costs)
+			indexes[requests.Accelerator] = i
+			costs = append(costs, GPUCost{Accelerator: requests.Accelerator, MonthlyRange: CostRange{Kind: "GPU"}})
+		}
+		costs[i].MonthlyRange = costs[i].MonthlyRange.Add(cost)
+	}
+	if len(costs) == 0 {
+		return nil
+	}
+	return costs
+}
+
+// gpuResourcePrice prices GPUs only, so the GPU share of a workload cost can be estimated
+type gpuResourcePrice struct {
+	AcceleratorPrice
+}
+
+func (g *gpuResourcePrice) CPUMonthlyPrice() float32 {
+	return 0
+}
+
+func (g *gpuResourcePrice) MemoryMonthlyPrice() float32 {
+	return 0
+}
+
+func gpuCostsToMarkdown(costs []GPUCost) string {
+	data := [][]string{}
+	for _, c := range costs {
+		mr := c.MonthlyRange
+		data = append(data,
+			[]string{c.Accelerator,
+				currency(mr.MinRequested),
+				currency(mr.HPABuffer),
+				currency(mr.MaxRequested),
+				currency(mr.MinLimited),
+				currency(mr.MaxLimited)})
+	}
+
+	out := &strings.Builder{}
+	table := tablewriter.NewWriter(out)
+	table.SetHeader(
+		[]string{"Accelerator",
+			headers[0] + " (USD)",
+			headers[1] + " (USD)",
+			headers[2] + " (USD)",
+			headers[3] + " (USD)",
+			headers[4] + " (USD)"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.SetColumnAlignment([]int{0, 2, 2, 2, 2, 2})
+	table.AppendBulk(data)
+	table.Render()
+	return out.String()
+}
+
+// gpuDescription returns the SKU description prefix of the given accelerator type, either on-demand or attached to Spot VMs
+func gpuDescription(accelerator string, spot bool) string {
+	if spot {
+		return gpuPrefixes[accelerator] + spotGPUSuffix
+	}
+	return gpuPrefixes[accelerator] + gpuSuffix
+}
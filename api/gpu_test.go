@@ -0,0 +1,190 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAccelerator(t *testing.T) {
+	data := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: training
+spec:
+  template:
+    spec:
+      affinity:
+        nodeAffinity:
+          requiredDuringSchedulingIgnoredDuringExecution:
+            nodeSelectorTerms:
+            - matchExpressions:
+              - key: cloud.google.com/gke-accelerator
+                operator: In
+                values:
+                - nvidia-tesla-v100
+      containers:
+      - name: training
+        image: trainer
+        resources:
+          limits:
+            nvidia.com/gpu: 2
+      - name: sidecar
+        image: nginx`
+
+	deploy, err := decodeDeployment([]byte(data), CostimatorConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// GPU requests default to limits
+	want := Resource{CPU: 250, Memory: 64000000, GPU: 2, Accelerator: "nvidia-tesla-v100"}
+	if got := deploy.Containers[0].Requests; !cmp.Equal(got, want) {
+		t.Errorf("GPU container requests should be %+v, got %+v", want, got)
+	}
+	if got := deploy.Containers[1].Requests; got.GPU != 0 || got.Accelerator != "" {
+		t.Errorf("Container not requesting GPUs should have no accelerator, got %+v", got)
+	}
+
+	conf := CostimatorConfig{ClusterConf: ClusterConfig{NodePools: []NodePoolConfig{
+		{Name: "default"},
+		{Name: "gpu", Labels: map[string]string{AcceleratorNodeLabel: "nvidia-l4", "workload": "ml"}},
+	}}}
+	tests := map[string]string{
+		"nodeSelector:\n        cloud.google.com/gke-accelerator: nvidia-tesla-t4": "nvidia-tesla-t4",
+		"nodeSelector:\n        workload: ml":                                      "nvidia-l4",
+		"restartPolicy: Never":                                                     "nvidia-tesla-t4",
+	}
+	for spec, want := range tests {
+		data := `apiVersion: v1
+kind: Pod
+metadata:
+  name: inference
+spec:
+  ` + spec + `
+  containers:
+  - name: inference
+    image: server
+    resources:
+      requests:
+        nvidia.com/gpu: 1
+      limits:
+        nvidia.com/gpu: 1`
+		pod, err := decodePod([]byte(data), conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := pod.Containers[0].Requests.Accelerator; got != want {
+			t.Errorf("Accelerator for '%s' should be '%s', got '%s'", spec, want, got)
+		}
+	}
+}
+
+func TestEstimateCostGPU(t *testing.T) {
+	data := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: inference
+spec:
+  replicas: 2
+  template:
+    spec:
+      nodeSelector:
+        cloud.google.com/gke-accelerator: nvidia-tesla-t4
+      containers:
+      - name: inference
+        image: server
+        resources:
+          requests:
+            memory: "1"
+            cpu: "1"
+            nvidia.com/gpu: 1
+          limits:
+            memory: "1"
+            cpu: "1"
+            nvidia.com/gpu: 1
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: training
+spec:
+  template:
+    spec:
+      nodeSelector:
+        cloud.google.com/gke-accelerator: nvidia-tesla-t4
+        cloud.google.com/gke-spot: "true"
+      containers:
+      - name: training
+        image: trainer
+        resources:
+          requests:
+            memory: "1"
+            cpu: "1"
+            nvidia.com/gpu: 4
+          limits:
+            memory: "1"
+            cpu: "1"
+            nvidia.com/gpu: 4
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: unknown
+spec:
+  template:
+    spec:
+      nodeSelector:
+        cloud.google.com/gke-accelerator: nvidia-l4
+      containers:
+      - name: unknown
+        image: server
+        resources:
+          requests:
+            memory: "1"
+            cpu: "1"
+            nvidia.com/gpu: 1
+          limits:
+            memory: "1"
+            cpu: "1"
+            nvidia.com/gpu: 1`
+
+	manifests := Manifests{}
+	err := manifests.LoadObjects([]byte(data), CostimatorConfig{})
+	if err != nil {
+		t.Fatalf("Error loading objects: %+v", err)
+	}
+	pc := &GCPPriceCatalog{
+		cpuPrice:        1,
+		spotCPUPrice:    0.5,
+		spotMemoryPrice: 1,
+		gpuPrices:       map[string]float32{"nvidia-tesla-t4": 100},
+		spotGPUPrices:   map[string]float32{"nvidia-tesla-t4": 30},
+	}
+	cost := manifests.EstimateCost(pc)
+
+	// inference: 2 * (1 + 100), training: 0.5 + 1 + 4 * 30, unknown: 1 (GPU not priced)
+	if got, want := cost.MonthlyRanges[0].MinRequested, 202+121.5+1.0; got != want {
+		t.Errorf("Deployments cost should be %v, got %v", want, got)
+	}
+	want := []GPUCost{{
+		Accelerator:  "nvidia-tesla-t4",
+		MonthlyRange: CostRange{Kind: "GPU", MinRequested: 320, MaxRequested: 320, HPABuffer: 320, MinLimited: 320, MaxLimited: 320},
+	}}
+	if !cmp.Equal(cost.GPUs, want) {
+		t.Errorf("GPU costs should be %+v, got %+v", want, cost.GPUs)
+	}
+}
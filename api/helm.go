@@ -0,0 +1,91 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/getter"
+)
+
+// HelmOptions holds the settings used to render a Helm chart
+type HelmOptions struct {
+	ReleaseName string
+	Namespace   string
+	ValuesFiles []string
+}
+
+// IsHelmChart checks if path is a Helm chart folder (ie. it has a Chart.yaml file)
+func IsHelmChart(path string) bool {
+	f, err := os.Stat(filepath.Join(path, "Chart.yaml"))
+	return err == nil && !f.IsDir()
+}
+
+// LoadObjectsFromHelmChart renders the Helm chart in path and loads the rendered objects
+// Rendering happens in-process and offline (no cluster needed). Chart dependencies must be available in the charts/ folder
+func (m *Manifests) LoadObjectsFromHelmChart(path string, opts HelmOptions, conf CostimatorConfig) error {
+	data, err := renderHelmChart(path, opts)
+	if err != nil {
+		return fmt.Errorf("Error rendering Helm chart '%s'. Root cause %+v", path, err)
+	}
+	return m.LoadObjects(data, conf)
+}
+
+func renderHelmChart(path string, opts HelmOptions) ([]byte, error) {
+	chart, err := loader.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if chart.Metadata.Dependencies != nil {
+		err = action.CheckDependencies(chart, chart.Metadata.Dependencies)
+		if err != nil {
+			return nil, fmt.Errorf("%+v. Run 'helm dependency build' to vendor them", err)
+		}
+	}
+
+	valuesOpts := values.Options{ValueFiles: opts.ValuesFiles}
+	vals, err := valuesOpts.MergeValues(getter.Providers{})
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &action.Configuration{Log: log.Debugf}
+	client := action.NewInstall(cfg)
+	client.DryRun = true
+	client.ClientOnly = true
+	client.Replace = true
+	client.DisableHooks = true
+	client.ReleaseName = opts.ReleaseName
+	if client.ReleaseName == "" {
+		client.ReleaseName = "release-name"
+	}
+	client.Namespace = opts.Namespace
+	if client.Namespace == "" {
+		client.Namespace = "default"
+	}
+
+	log.Tracef("Rendering Helm chart '%s' (release '%s', namespace '%s')", path, client.ReleaseName, client.Namespace)
+	release, err := client.Run(chart, vals)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(release.Manifest), nil
+}
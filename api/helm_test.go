@@ -0,0 +1,87 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+)
+
+func TestIsHelmChart(t *testing.T) {
+	if !IsHelmChart("./testdata/helm/nginx") {
+		t.Errorf("./testdata/helm/nginx should be a Helm chart")
+	}
+	if IsHelmChart("./testdata/manifests") {
+		t.Errorf("./testdata/manifests should not be a Helm chart")
+	}
+}
+
+func TestLoadObjectsFromHelmChart(t *testing.T) {
+	manifests := Manifests{}
+	err := manifests.LoadObjectsFromHelmChart("./testdata/helm/nginx", HelmOptions{}, CostimatorConfig{})
+	if err != nil {
+		t.Fatalf("Error loading Helm chart: %+v", err)
+	}
+	if len(manifests.Deployments) != 1 || len(manifests.hpas) != 0 {
+		t.Fatalf("Incorrect number of objects, deployments: %d, hpas: %d", len(manifests.Deployments), len(manifests.hpas))
+	}
+
+	deploy := manifests.Deployments[0]
+	expectedAPIVersionKindName := "apps/v1|Deployment|default|release-name-nginx"
+	if got := deploy.APIVersionKindName; got != expectedAPIVersionKindName {
+		t.Errorf("Expected APIVersionKindName %+v, got %+v", expectedAPIVersionKindName, got)
+	}
+	if deploy.Replicas != 1 || deploy.Containers[0].Requests.CPU != 250 {
+		t.Errorf("Deployment should have been rendered with default values, got %+v", deploy)
+	}
+}
+
+func TestLoadObjectsFromHelmChartWithValues(t *testing.T) {
+	opts := HelmOptions{
+		ReleaseName: "shop",
+		Namespace:   "prod",
+		ValuesFiles: []string{"./testdata/helm/nginx/values-prod.yaml"},
+	}
+	manifests := Manifests{}
+	err := manifests.LoadObjectsFromHelmChart("./testdata/helm/nginx", opts, CostimatorConfig{})
+	if err != nil {
+		t.Fatalf("Error loading Helm chart: %+v", err)
+	}
+	if len(manifests.Deployments) != 1 || len(manifests.hpas) != 1 {
+		t.Fatalf("Incorrect number of objects, deployments: %d, hpas: %d", len(manifests.Deployments), len(manifests.hpas))
+	}
+
+	deploy := manifests.Deployments[0]
+	expectedAPIVersionKindName := "apps/v1|Deployment|prod|shop-nginx"
+	if got := deploy.APIVersionKindName; got != expectedAPIVersionKindName {
+		t.Errorf("Expected APIVersionKindName %+v, got %+v", expectedAPIVersionKindName, got)
+	}
+	if deploy.Replicas != 3 {
+		t.Errorf("Deployment should have 3 replicas, got %d", deploy.Replicas)
+	}
+
+	manifests.prepareForCostEstimation()
+	if deploy.hpa.MinReplicas != 2 || deploy.hpa.MaxReplicas != 10 {
+		t.Errorf("Should have linked HPA to Deployment, got %+v", deploy.hpa)
+	}
+}
+
+func TestLoadObjectsFromHelmChartMissingValues(t *testing.T) {
+	opts := HelmOptions{ValuesFiles: []string{"./testdata/helm/nginx/missing.yaml"}}
+	manifests := Manifests{}
+	err := manifests.LoadObjectsFromHelmChart("./testdata/helm/nginx", opts, CostimatorConfig{})
+	if err == nil {
+		t.Errorf("Should have returned a missing values file error")
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// HPAMetric is the simplified reprsentation of a k8s HPA metric target
+// Client doesn't need to handle different version and the complexity of k8s.io package
+type HPAMetric struct {
+	Type        string `json:"type"`                  // Resource, ContainerResource, Pods, Object or External
+	Name        string `json:"name"`                  // resource name (eg. cpu, memory) or custom metric name
+	Container   string `json:"container,omitempty"`   // only for ContainerResource metrics
+	TargetType  string `json:"targetType"`            // Utilization, AverageValue or Value
+	Utilization int32  `json:"utilization,omitempty"` // percentage of requests. Only for Utilization targets
+	Value       string `json:"value,omitempty"`       // k8s quantity. Only for AverageValue and Value targets
+	milliValue  int64
+}
+
+const (
+	resourceMetric          = "Resource"
+	containerResourceMetric = "ContainerResource"
+	utilizationTarget       = "Utilization"
+	averageValueTarget      = "AverageValue"
+	valueTarget             = "Value"
+)
+
+func newHPAMetric(metricType, name, container string, utilization *int32, averageValue, value *resource.Quantity) HPAMetric {
+	metric := HPAMetric{Type: metricType, Name: name, Container: container}
+	switch {
+	case utilization != nil:
+		metric.TargetType = utilizationTarget
+		metric.Utilization = *utilization
+	case averageValue != nil:
+		metric.TargetType = averageValueTarget
+		metric.Value = averageValue.String()
+		metric.milliValue = averageValue.MilliValue()
+	case value != nil:
+		metric.TargetType = valueTarget
+		metric.Value = value.String()
+		metric.milliValue = value.MilliValue()
+	}
+	return metric
+}
+
+// String returns a human readable description of the metric target. eg. 'cpu: 60% utilization'
+func (m HPAMetric) String() string {
+	name := m.Name
+	switch m.Type {
+	case resourceMetric:
+	case containerResourceMetric:
+		name = fmt.Sprintf("%s (container %s)", m.Name, m.Container)
+	default:
+		name = fmt.Sprintf("%s/%s", strings.ToLower(m.Type), m.Name)
+	}
+	switch m.TargetType {
+	case utilizationTarget:
+		return fmt.Sprintf("%s: %d%% utilization", name, m.Utilization)
+	case averageValueTarget:
+		return fmt.Sprintf("%s: %s average value", name, m.Value)
+	default:
+		return fmt.Sprintf("%s: %s value", name, m.Value)
+	}
+}
+
+// utilization returns the metric target as a percentage of the requests, so resource targets can be compared
+// Only cpu and memory resource metrics are considered. Custom metrics can't be related to requests
+func (m HPAMetric) utilization(containers []Container) (int32, bool) {
+	if m.Type != resourceMetric && m.Type != containerResourceMetric {
+		return 0, false
+	}
+	if m.Name != "cpu" && m.Name != "memory" {
+		return 0, false
+	}
+	if m.TargetType == utilizationTarget {
+		return m.Utilization, m.Utilization > 0
+	}
+	if m.TargetType != averageValueTarget {
+		return 0, false
+	}
+
+	var requests Resource
+	for _, container := range containers {
+		if container.Type == InitContainer || container.Type == PodOverhead || container.Type == AutopilotAdjustment || container.Type == EmptyDirVolume {
+			continue
+		}
+		if m.Type == resourceMetric || container.Name == m.Container {
+			requests = requests.add(container.Requests)
+		}
+	}
+	requested := requests.CPU
+	if m.Name == "memory" {
+		requested = requests.Memory * 1000 // from bytes to milli bytes
+	}
+	if requested <= 0 {
+		return 0, false
+	}
+	return int32(m.milliValue * 100 / requested), true
+}
+
+// bufferTarget returns the most restrictive cpu or memory target (ie. the lowest utilization of requests)
+// The HPA scales out as soon as any metric reaches its target, so the lowest utilization defines how much
+// headroom (ie. HPA buffer) the workload keeps over its requests. found is false when no resource target is defined
+func (h HPA) bufferTarget(containers []Container) (metric HPAMetric, utilization int32, found bool) {
+	metrics := h.Metrics
+	if len(metrics) == 0 && h.TargetCPUPercentage > 0 {
+		cpu := h.TargetCPUPercentage
+		metrics = []HPAMetric{newHPAMetric(resourceMetric, "cpu", "", &cpu, nil, nil)}
+	}
+	for _, m := range metrics {
+		u, ok := m.utilization(containers)
+		if !ok {
+			continue
+		}
+		if !found || u < utilization {
+			metric, utilization, found = m, u, true
+		}
+	}
+	// targets above requests don't keep any headroom
+	if utilization > 100 {
+		utilization = 100
+	}
+	return
+}
+
+// bufferMetricDescription describes the metric driving the HPA buffer. Empty when no resource target is defined
+func (h HPA) bufferMetricDescription(containers []Container) string {
+	metric, utilization, found := h.bufferTarget(containers)
+	if !found {
+		return ""
+	}
+	if metric.TargetType == utilizationTarget {
+		return metric.String()
+	}
+	return fmt.Sprintf("%s (%d%% of requests)", metric, utilization)
+}
+
+func hpaMetricsToMarkdown(objectRanges []ObjectCostRange) string {
+	data := [][]string{}
+	for _, or := range objectRanges {
+		if len(or.HPAMetrics) == 0 {
+			continue
+		}
+		targets := []string{}
+		for _, m := range or.HPAMetrics {
+			targets = append(targets, m.String())
+		}
+		bufferMetric := or.HPABufferMetric
+		if bufferMetric == "" {
+			bufferMetric = "none (buffer equals min requested)"
+		}
+		data = append(data, []string{or.Key(), bufferMetric, strings.Join(targets, "<br>")})
+	}
+
+	out := &strings.Builder{}
+	table := tablewriter.NewWriter(out)
+	table.SetHeader([]string{"Object", "Buffer driven by", "Metric targets"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.SetAutoWrapText(false)
+	table.SetColumnAlignment([]int{0, 0, 0})
+	table.AppendBulk(data)
+	table.Render()
+	return out.String()
+}
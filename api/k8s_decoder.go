@@ -19,6 +19,7 @@ import (
 	autoscaleV1 "k8s.io/api/autoscaling/v1"
 	autoscaleV2beta1 "k8s.io/api/autoscaling/v2beta1"
 	autoscaleV2beta2 "k8s.io/api/autoscaling/v2beta2"
+	batchV1 "k8s.io/api/batch/v1"
 	coreV1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -48,6 +49,10 @@ func buildScheme() *runtime.Scheme {
 	registryStatefulSetVersions(scheme)
 	registryDeamonSetVersions(scheme)
 	registryVolumeClaimVersions(scheme)
+	registryJobVersions(scheme)
+	registryCronJobVersions(scheme)
+	registryPodVersions(scheme)
+	registryReplicationControllerVersions(scheme)
 	return scheme
 }
 
@@ -189,3 +194,46 @@ func registryVolumeClaimVersions(scheme *runtime.Scheme) {
 	}
 	scheme.AddKnownTypeWithName(gvkV1, &coreV1.PersistentVolumeClaim{})
 }
+
+func registryJobVersions(scheme *runtime.Scheme) {
+	gvkBatchV1 := schema.GroupVersionKind{
+		Group:   "batch",
+		Version: "v1",
+		Kind:    JobKind,
+	}
+	scheme.AddKnownTypeWithName(gvkBatchV1, &batchV1.Job{})
+}
+
+func registryCronJobVersions(scheme *runtime.Scheme) {
+	gvkBatchV1 := schema.GroupVersionKind{
+		Group:   "batch",
+		Version: "v1",
+		Kind:    CronJobKind,
+	}
+	scheme.AddKnownTypeWithName(gvkBatchV1, &batchV1.CronJob{})
+
+	gvkBatchV1beta1 := schema.GroupVersionKind{
+		Group:   "batch",
+		Version: "v1beta1",
+		Kind:    CronJobKind,
+	}
+	// we load v1, once the fields we are interested have in v1
+	// This way, we don't need many implementations in builder_cronjob.go file
+	scheme.AddKnownTypeWithName(gvkBatchV1beta1, &batchV1.CronJob{})
+}
+
+func registryPodVersions(scheme *runtime.Scheme) {
+	gvkV1 := schema.GroupVersionKind{
+		Version: "v1",
+		Kind:    PodKind,
+	}
+	scheme.AddKnownTypeWithName(gvkV1, &coreV1.Pod{})
+}
+
+func registryReplicationControllerVersions(scheme *runtime.Scheme) {
+	gvkV1 := schema.GroupVersionKind{
+		Version: "v1",
+		Kind:    ReplicationControllerKind,
+	}
+	scheme.AddKnownTypeWithName(gvkV1, &coreV1.ReplicationController{})
+}
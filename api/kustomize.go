@@ -0,0 +1,55 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// IsKustomization checks if path is a Kustomize folder (ie. it has a kustomization.yaml file)
+func IsKustomization(path string) bool {
+	for _, name := range konfig.RecognizedKustomizationFileNames() {
+		f, err := os.Stat(filepath.Join(path, name))
+		if err == nil && !f.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadObjectsFromKustomization builds the Kustomize folder in path and loads only the final rendered objects
+// Bases, components and patches referenced by the kustomization are not loaded on their own
+func (m *Manifests) LoadObjectsFromKustomization(path string, conf CostimatorConfig) error {
+	data, err := buildKustomization(path)
+	if err != nil {
+		return fmt.Errorf("Error building kustomization '%s'. Root cause %+v", path, err)
+	}
+	return m.LoadObjects(data, conf)
+}
+
+func buildKustomization(path string) ([]byte, error) {
+	kustomizer := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	resMap, err := kustomizer.Run(filesys.MakeFsOnDisk(), path)
+	if err != nil {
+		return nil, err
+	}
+	return resMap.AsYaml()
+}
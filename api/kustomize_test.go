@@ -0,0 +1,60 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+)
+
+func TestIsKustomization(t *testing.T) {
+	if !IsKustomization("./testdata/kustomize/overlays/prod") {
+		t.Errorf("./testdata/kustomize/overlays/prod should be a kustomization")
+	}
+	if IsKustomization("./testdata/kustomize") {
+		t.Errorf("./testdata/kustomize should not be a kustomization")
+	}
+}
+
+func TestLoadObjectsFromPathWithKustomization(t *testing.T) {
+	manifests := Manifests{}
+	err := manifests.LoadObjectsFromPath("./testdata/kustomize/overlays/prod", CostimatorConfig{})
+	if err != nil {
+		t.Fatalf("Error loading kustomization: %+v", err)
+	}
+	if len(manifests.Deployments) != 1 {
+		t.Fatalf("Base and patch should not be loaded on their own, got %d deployments", len(manifests.Deployments))
+	}
+
+	deploy := manifests.Deployments[0]
+	expectedAPIVersionKindName := "apps/v1|Deployment|prod|prod-my-nginx"
+	if got := deploy.APIVersionKindName; got != expectedAPIVersionKindName {
+		t.Errorf("Expected APIVersionKindName %+v, got %+v", expectedAPIVersionKindName, got)
+	}
+	if deploy.Replicas != 4 || deploy.Containers[0].Requests.CPU != 250 {
+		t.Errorf("Deployment should have been patched, got %+v", deploy)
+	}
+}
+
+func TestLoadObjectsFromPathWithoutKustomization(t *testing.T) {
+	manifests := Manifests{}
+	err := manifests.LoadObjectsFromPath("./testdata/kustomize", CostimatorConfig{})
+	if err != nil {
+		t.Fatalf("Error loading objects: %+v", err)
+	}
+	// plain folders keep loading every yaml file found
+	if len(manifests.Deployments) != 2 {
+		t.Errorf("Expected 2 deployments, got %d", len(manifests.Deployments))
+	}
+}
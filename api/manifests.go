@@ -27,19 +27,31 @@ import (
 
 // Manifests holds all deployments and executes cost estimation
 type Manifests struct {
-	Deployments     []*Deployment
-	deploymentsRef  map[string]*Deployment
-	ReplicaSets     []*ReplicaSet
-	replicaSetsRef  map[string]*ReplicaSet
-	StatefulSets    []*StatefulSet
-	statefulsetsRef map[string]*StatefulSet
-	DaemonSets      []*DaemonSet
-	VolumeClaims    []*VolumeClaim
-	hpas            []HPA
+	Deployments               []*Deployment
+	deploymentsRef            map[string]*Deployment
+	ReplicaSets               []*ReplicaSet
+	replicaSetsRef            map[string]*ReplicaSet
+	ReplicationControllers    []*ReplicationController
+	replicationControllersRef map[string]*ReplicationController
+	StatefulSets              []*StatefulSet
+	statefulsetsRef           map[string]*StatefulSet
+	DaemonSets                []*DaemonSet
+	VolumeClaims              []*VolumeClaim
+	Jobs                      []*Job
+	CronJobs                  []*CronJob
+	Pods                      []*Pod
+	hpas                      []HPA
+	conf                      CostimatorConfig
 }
 
 // LoadObjectsFromPath loads all files from folder and subfolder finishing with yaml or yml
+// If the folder has a kustomization.yaml file, it is built and only the rendered objects are loaded
 func (m *Manifests) LoadObjectsFromPath(path string, conf CostimatorConfig) error {
+	if IsKustomization(path) {
+		log.Infof("Building kustomization in path '%s'...", path)
+		return m.LoadObjectsFromKustomization(path, conf)
+	}
+
 	err := filepath.Walk(path, func(path string, f os.FileInfo, err error) error {
 		if !f.IsDir() {
 			if strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml") {
@@ -64,6 +76,8 @@ func (m *Manifests) LoadObjectsFromPath(path string, conf CostimatorConfig) erro
 // LoadObjects allow you to decode and load into Manifests your k8s objects
 // For now, it only understands Deployment and HPA
 func (m *Manifests) LoadObjects(data []byte, conf CostimatorConfig) error {
+	// kept to apply cluster wide settings, like discounts, when estimating costs
+	m.conf = conf
 	objects := bytes.Split(data, []byte("---"))
 	for _, object := range objects {
 		err := m.loadObject(object, conf)
@@ -75,69 +89,245 @@ func (m *Manifests) LoadObjects(data []byte, conf CostimatorConfig) error {
 }
 
 // EstimateCost loop through all resources and group it by kind
-func (m *Manifests) EstimateCost(pc GCPPriceCatalog) Cost {
+// The cost of each resource is also kept in Cost.MonthlyObjectRanges
+func (m *Manifests) EstimateCost(pp PriceProvider) Cost {
 	m.prepareForCostEstimation()
+	nodes := m.estimateNodes(pp)
 
+	// workloads are grouped by kind, in the order they are listed by Manifests.workloads
 	monthlyRanges := []CostRange{}
-	if len(m.Deployments) > 0 {
-		monthlyRanges = append(monthlyRanges, m.estimateDeploymentCost(&pc))
+	monthlyObjectRanges := []ObjectCostRange{}
+	indexes := make(map[string]int)
+	for _, workload := range m.workloads() {
+		cost := workload.estimateCost(workloadResourcePrice(poolResourcePrice(pp, workload.getNodePool()), workload.isSpot(), workload.getComputeClass()))
+		i, ok := indexes[cost.Kind]
+		if !ok {
+			i = len(monthlyRanges)
+			indexes[cost.Kind] = i
+			monthlyRanges = append(monthlyRanges, CostRange{Kind: cost.Kind})
+		}
+		monthlyRanges[i] = monthlyRanges[i].Add(cost)
+		monthlyObjectRanges = append(monthlyObjectRanges, workload.objectCostRange(cost))
 	}
-	if len(m.ReplicaSets) > 0 {
-		monthlyRanges = append(monthlyRanges, m.estimateReplicaSetCost(&pc))
+	if len(m.VolumeClaims) > 0 {
+		kindRange, objectRanges := m.estimateVolumeClaimCost(pp)
+		monthlyRanges = append(monthlyRanges, kindRange)
+		monthlyObjectRanges = append(monthlyObjectRanges, objectRanges...)
 	}
-	if len(m.StatefulSets) > 0 {
-		monthlyRanges = append(monthlyRanges, m.estimateStatefulSetCost(&pc))
+
+	return Cost{
+		MonthlyRanges:       monthlyRanges,
+		MonthlyObjectRanges: monthlyObjectRanges,
+		SpotComparisons:     m.compareSpotCost(pp),
+		Discounts:           m.estimateDiscounts(pp),
+		Nodes:               nodes,
+		GPUs:                m.estimateGPUCost(pp),
+		EphemeralStorage:    m.estimateEphemeralStorageCost(pp),
 	}
-	if len(m.DaemonSets) > 0 {
-		monthlyRanges = append(monthlyRanges, m.estimateDaemonSetCost(&pc))
+}
+
+// estimateNodes packs the pods placed on each node pool onto its nodes (see ClusterConfig.NodePools) and sets DaemonSets node count accordingly
+// Node pools without machine type or that can't be simulated are skipped. It returns nil when no node pool is simulated
+func (m *Manifests) estimateNodes(rp ResourcePrice) []NodeEstimate {
+	conf := populateConfigNotProvided(m.conf)
+	if len(conf.ClusterConf.NodePools) == 0 {
+		return nil
 	}
-	if len(m.VolumeClaims) > 0 {
-		monthlyRanges = append(monthlyRanges, m.estimateVolumeClaimCost(&pc))
+	if conf.ClusterConf.Mode == Autopilot {
+		log.Infof("Node pools are not simulated for Autopilot clusters, where pods are billed instead of nodes")
+		return nil
 	}
 
-	return Cost{
-		MonthlyRanges: monthlyRanges,
+	// pods are grouped by the node pool they are placed on. Nodes must fit batch pods while they run
+	groups := make(map[string][]podGroup)
+	for _, workload := range m.workloads() {
+		if _, ok := workload.(*DaemonSet); ok {
+			continue
+		}
+		object := workload.objectCostRange(CostRange{})
+		group := podGroup{requests: object.Requests, minCount: object.MinReplicas, maxCount: object.MaxReplicas}
+		groups[object.NodePool] = append(groups[object.NodePool], group)
 	}
+	daemonSetPods := make(map[string][]Resource)
+	for _, daemonset := range m.DaemonSets {
+		requests, _ := sumContainers(daemonset.Containers)
+		for _, pool := range daemonset.NodePools {
+			daemonSetPods[pool] = append(daemonSetPods[pool], requests)
+		}
+	}
+
+	estimates := []NodeEstimate{}
+	simulated := make(map[string]NodeEstimate)
+	for _, pool := range conf.ClusterConf.NodePools {
+		if pool.Name == "" || pool.MachineType == "" {
+			log.Debugf("Node pool '%s' has no name or machine type. Its nodes are not simulated", pool.Name)
+			continue
+		}
+		// nodes are billed with the node pool machine family, region and Spot prices
+		poolPrice := workloadResourcePrice(poolResourcePrice(rp, pool.Name), conf.ClusterConf.SpotVMs || pool.Spot, "")
+		estimate, err := simulateNodes(pool, groups[pool.Name], daemonSetPods[pool.Name], poolPrice)
+		if err != nil {
+			log.Warnf("Unable to simulate node pool '%s': %+v", pool.Name, err)
+			continue
+		}
+		estimate.NodePool = pool.Name
+		estimates = append(estimates, estimate)
+		simulated[pool.Name] = estimate
+	}
+	if len(estimates) == 0 {
+		return nil
+	}
+
+	for _, daemonset := range m.DaemonSets {
+		var minNodes, maxNodes int32
+		allSimulated := len(daemonset.NodePools) > 0
+		for _, pool := range daemonset.NodePools {
+			estimate, ok := simulated[pool]
+			allSimulated = allSimulated && ok
+			minNodes, maxNodes = minNodes+estimate.MinNodes, maxNodes+estimate.MaxNodes
+		}
+		if !allSimulated {
+			log.Warnf("Not all node pools DaemonSet '%s' runs on were simulated. Using clusterConf.nodesCount instead", daemonset.APIVersionKindName)
+			continue
+		}
+		daemonset.NodesCount = minNodes
+		daemonset.MaxNodesCount = maxNodes
+	}
+	return estimates
+}
+
+// workloadEstimator is implemented by all workloads priced by CPU and Memory
+type workloadEstimator interface {
+	podResource
+	estimateCost(rp ResourcePrice) CostRange
+	// objectCostRange describes the workload along with its cost. Batch workloads count parallel pods as replicas
+	objectCostRange(cost CostRange) ObjectCostRange
 }
 
-func (m *Manifests) estimateDeploymentCost(rp ResourcePrice) CostRange {
-	deploymentRange := CostRange{Kind: DeploymentKind}
+func (m *Manifests) workloads() []workloadEstimator {
+	workloads := []workloadEstimator{}
 	for _, deploy := range m.Deployments {
-		deploymentRange = deploymentRange.Add(deploy.estimateCost(rp))
+		workloads = append(workloads, deploy)
+	}
+	for _, replicaset := range m.ReplicaSets {
+		workloads = append(workloads, replicaset)
+	}
+	for _, replicationController := range m.ReplicationControllers {
+		workloads = append(workloads, replicationController)
+	}
+	for _, statefulset := range m.StatefulSets {
+		workloads = append(workloads, statefulset)
 	}
-	return deploymentRange
+	for _, daemonset := range m.DaemonSets {
+		workloads = append(workloads, daemonset)
+	}
+	for _, pod := range m.Pods {
+		workloads = append(workloads, pod)
+	}
+	for _, job := range m.Jobs {
+		workloads = append(workloads, job)
+	}
+	for _, cronjob := range m.CronJobs {
+		workloads = append(workloads, cronjob)
+	}
+	return workloads
 }
 
-func (m *Manifests) estimateReplicaSetCost(rp ResourcePrice) CostRange {
-	replicasetRange := CostRange{Kind: ReplicaSetKind}
-	for _, replicaset := range m.ReplicaSets {
-		replicasetRange = replicasetRange.Add(replicaset.estimateCost(rp))
+// PriceRequirements returns the prices needed by the workloads, besides the ones needed by the config (see NewGCPPriceCatalog)
+func (m *Manifests) PriceRequirements() PriceRequirements {
+	requirements := PriceRequirements{}
+	for _, workload := range m.workloads() {
+		requests, _ := sumContainers(workload.getContainers())
+		if requests.GPU > 0 {
+			requirements.Accelerators = append(requirements.Accelerators, requests.Accelerator)
+		}
+		requirements.LocalSSD = requirements.LocalSSD || requests.LocalSSD
 	}
-	return replicasetRange
+	return requirements
 }
 
-func (m *Manifests) estimateStatefulSetCost(rp ResourcePrice) CostRange {
-	statefulsetRange := CostRange{Kind: StatefulSetKind}
-	for _, statefulset := range m.StatefulSets {
-		statefulsetRange = statefulsetRange.Add(statefulset.estimateCost(rp))
+// estimateDiscounts applies committed use and sustained use discounts to on-demand workloads
+// Autopilot clusters are not priced by Compute Engine instances, so resource-based commitments and sustained use discounts don't apply
+func (m *Manifests) estimateDiscounts(rp ResourcePrice) *Discounts {
+	if populateConfigNotProvided(m.conf).ClusterConf.Mode == Autopilot {
+		return nil
 	}
-	return statefulsetRange
+	// pricing 1 USD per vCPU (or byte) gives the monthly usage
+	cpuUsage := CostRange{}
+	memoryUsage := CostRange{}
+	for _, workload := range m.workloads() {
+		// commitments only cover the resourceConf machine family and region
+		if workload.isSpot() || !pricedWithResourceConf(workload.getNodePool(), m.conf) {
+			continue
+		}
+		cpuUsage = cpuUsage.Add(workload.estimateCost(&unitResourcePrice{cpu: 1}))
+		memoryUsage = memoryUsage.Add(workload.estimateCost(&unitResourcePrice{memory: 1}))
+	}
+	return newDiscounts(cpuUsage, memoryUsage, rp, m.conf)
 }
 
-func (m *Manifests) estimateDaemonSetCost(rp ResourcePrice) CostRange {
-	daemonsetRange := CostRange{Kind: DaemonSetKind}
-	for _, daemonset := range m.DaemonSets {
-		daemonsetRange = daemonsetRange.Add(daemonset.estimateCost(rp))
+type unitResourcePrice struct {
+	cpu    float32
+	memory float32
+}
+
+func (u *unitResourcePrice) CPUMonthlyPrice() float32 {
+	return u.cpu
+}
+
+func (u *unitResourcePrice) MemoryMonthlyPrice() float32 {
+	return u.memory
+}
+
+// compareSpotCost prices all workloads both at on-demand and at Spot VM rates, regardless of where they run
+// In Autopilot clusters, Spot pods prices of the workload compute class are used
+func (m *Manifests) compareSpotCost(rp ResourcePrice) []SpotComparison {
+	comparisons := []SpotComparison{}
+	indexes := make(map[string]int)
+	for _, workload := range m.workloads() {
+		poolPrice := poolResourcePrice(rp, workload.getNodePool())
+		onDemandPrice, found := findResourcePrice(poolPrice, false, workload.getComputeClass())
+		if !found {
+			return nil
+		}
+		spotPrice, found := findResourcePrice(poolPrice, true, workload.getComputeClass())
+		if !found {
+			return nil
+		}
+		onDemand := workload.estimateCost(onDemandPrice)
+		spot := workload.estimateCost(spotPrice)
+		i, ok := indexes[onDemand.Kind]
+		if !ok {
+			i = len(comparisons)
+			indexes[onDemand.Kind] = i
+			comparisons = append(comparisons, SpotComparison{
+				Kind:     onDemand.Kind,
+				OnDemand: CostRange{Kind: onDemand.Kind},
+				Spot:     CostRange{Kind: onDemand.Kind},
+			})
+		}
+		comparisons[i].OnDemand = comparisons[i].OnDemand.Add(onDemand)
+		comparisons[i].Spot = comparisons[i].Spot.Add(spot)
 	}
-	return daemonsetRange
+	return comparisons
 }
 
-func (m *Manifests) estimateVolumeClaimCost(sp StoragePrice) CostRange {
+func (m *Manifests) estimateVolumeClaimCost(sp StoragePrice) (CostRange, []ObjectCostRange) {
 	volumeClaimRange := CostRange{Kind: VolumeClaimKind}
+	objectRanges := []ObjectCostRange{}
 	for _, volumeClaim := range m.VolumeClaims {
-		volumeClaimRange = volumeClaimRange.Add(volumeClaim.estimateCost(sp))
+		cost := volumeClaim.estimateCost(sp)
+		volumeClaimRange = volumeClaimRange.Add(cost)
+		objectRange := newObjectCostRange(volumeClaim.APIVersionKindName, cost)
+		objectRange.Requests = volumeClaim.Requests
+		objectRange.Limits = volumeClaim.Limits
+		objectRange.Labels = volumeClaim.Labels
+		objectRange.Replicas = 1
+		objectRange.MinReplicas = 1
+		objectRange.MaxReplicas = 1
+		objectRanges = append(objectRanges, objectRange)
 	}
-	return volumeClaimRange
+	return volumeClaimRange, objectRanges
 }
 
 func (m *Manifests) prepareForCostEstimation() {
@@ -149,6 +339,9 @@ func (m *Manifests) prepareForCostEstimation() {
 		if replicaset, ok := m.replicaSetsRef[key]; ok {
 			replicaset.hpa = hpa
 		}
+		if replicationController, ok := m.replicationControllersRef[key]; ok {
+			replicationController.hpa = hpa
+		}
 		if statefulset, ok := m.statefulsetsRef[key]; ok {
 			statefulset.hpa = hpa
 		}
@@ -195,6 +388,17 @@ func (m *Manifests) loadObject(data []byte, conf CostimatorConfig) error {
 		}
 		m.replicaSetsRef[replicaset.APIVersionKindName] = &replicaset
 		m.replicaSetsRef[replicaset.getKindName()] = &replicaset
+	case ReplicationControllerKind:
+		replicationController, err := buildReplicationController(obj, groupVersionKind, conf)
+		if err != nil {
+			return err
+		}
+		m.ReplicationControllers = append(m.ReplicationControllers, &replicationController)
+		if m.replicationControllersRef == nil {
+			m.replicationControllersRef = make(map[string]*ReplicationController)
+		}
+		m.replicationControllersRef[replicationController.APIVersionKindName] = &replicationController
+		m.replicationControllersRef[replicationController.getKindName()] = &replicationController
 	case StatefulSetKind:
 		statefulset, err := buildStatefulSet(obj, groupVersionKind, conf)
 		if err != nil {
@@ -222,6 +426,24 @@ func (m *Manifests) loadObject(data []byte, conf CostimatorConfig) error {
 			return err
 		}
 		m.VolumeClaims = append(m.VolumeClaims, &volume)
+	case JobKind:
+		job, err := buildJob(obj, groupVersionKind, conf)
+		if err != nil {
+			return err
+		}
+		m.Jobs = append(m.Jobs, &job)
+	case CronJobKind:
+		cronjob, err := buildCronJob(obj, groupVersionKind, conf)
+		if err != nil {
+			return err
+		}
+		m.CronJobs = append(m.CronJobs, &cronjob)
+	case PodKind:
+		pod, err := buildPod(obj, groupVersionKind, conf)
+		if err != nil {
+			return err
+		}
+		m.Pods = append(m.Pods, &pod)
 	}
 
 	return nil
@@ -15,6 +15,7 @@
 package api
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -170,7 +171,7 @@ spec:
 	}
 
 	mock := GCPPriceCatalog{cpuPrice: 16.227823, memoryPrice: 2.0257258e-09}
-	cost := manifests.EstimateCost(mock)
+	cost := manifests.EstimateCost(&mock)
 
 	actualTotal := cost.MonthlyTotal()
 	expectedTotal := CostRange{
@@ -225,7 +226,7 @@ spec:
 	}
 
 	mock := GCPPriceCatalog{cpuPrice: 16.227823, memoryPrice: 2.0257258e-09}
-	cost := manifests.EstimateCost(mock)
+	cost := manifests.EstimateCost(&mock)
 
 	actualTotal := cost.MonthlyTotal()
 	expectedTotal := CostRange{
@@ -308,7 +309,7 @@ spec:
 	}
 
 	mock := GCPPriceCatalog{cpuPrice: 16.227823, memoryPrice: 2.0257258e-09}
-	cost := manifests.EstimateCost(mock)
+	cost := manifests.EstimateCost(&mock)
 
 	actualTotal := cost.MonthlyTotal()
 	expectedTotal := CostRange{
@@ -323,3 +324,352 @@ spec:
 		t.Errorf("MonthlyTotal should be equal, expected: %+v, got: %+v", expectedTotal, actualTotal)
 	}
 }
+
+type fixedPriceProvider struct {
+	cpuPrice      float32
+	memoryPrice   float32
+	storagePrices map[PersistentDisk]float32
+}
+
+func (p *fixedPriceProvider) CPUMonthlyPrice() float32 {
+	return p.cpuPrice
+}
+
+func (p *fixedPriceProvider) MemoryMonthlyPrice() float32 {
+	return p.memoryPrice
+}
+
+func (p *fixedPriceProvider) StorageMonthlyPrice(disk PersistentDisk) float32 {
+	return p.storagePrices[disk]
+}
+
+func TestEstimateCostWithCustomPriceProvider(t *testing.T) {
+	data := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: my-nginx
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: my-nginx
+        image: nginx
+        resources:
+          requests:
+            memory: "10"
+            cpu: "1"
+          limits:
+            memory: "10"
+            cpu: "1"
+---
+kind: PersistentVolumeClaim
+apiVersion: v1
+metadata:
+  name: my-volumeclaim
+spec:
+  storageClassName: premium-rwo
+  resources:
+    requests:
+      storage: "100"`
+
+	manifests := Manifests{}
+	err := manifests.LoadObjects([]byte(data), CostimatorConfig{})
+	if err != nil {
+		t.Errorf("Error loading objects: %+v", err)
+	}
+
+	pp := &fixedPriceProvider{cpuPrice: 10, memoryPrice: 0.5, storagePrices: map[PersistentDisk]float32{{DiskType: PdSSD, Replication: Zonal}: 0.5}}
+	cost := manifests.EstimateCost(pp)
+
+	expected := []CostRange{
+		{Kind: DeploymentKind, MinRequested: 30, MaxRequested: 30, HPABuffer: 30, MinLimited: 30, MaxLimited: 30},
+		{Kind: VolumeClaimKind, MinRequested: 50, MaxRequested: 50, HPABuffer: 50, MinLimited: 50, MaxLimited: 50},
+	}
+	if !cmp.Equal(cost.MonthlyRanges, expected) {
+		t.Errorf("MonthlyRanges should be equal, expected: %+v, got: %+v", expected, cost.MonthlyRanges)
+	}
+}
+
+func TestEstimateCostPerObject(t *testing.T) {
+	data := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: frontend
+  namespace: shop
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: frontend
+        image: nginx
+        resources:
+          requests:
+            memory: "10"
+            cpu: "1"
+          limits:
+            memory: "10"
+            cpu: "1"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: backend
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: backend
+        image: nginx
+        resources:
+          requests:
+            memory: "10"
+            cpu: "1"
+          limits:
+            memory: "10"
+            cpu: "1"`
+
+	manifests := Manifests{}
+	err := manifests.LoadObjects([]byte(data), CostimatorConfig{})
+	if err != nil {
+		t.Errorf("Error loading objects: %+v", err)
+	}
+
+	pp := &fixedPriceProvider{cpuPrice: 10, memoryPrice: 0.5}
+	cost := manifests.EstimateCost(pp)
+
+	expected := []ObjectCostRange{
+		{
+			APIVersionKindName: "apps/v1|Deployment|shop|frontend",
+			Namespace:          "shop",
+			Kind:               DeploymentKind,
+			Name:               "frontend",
+			Replicas:           2,
+			MinReplicas:        2,
+			MaxReplicas:        2,
+			Requests:           Resource{CPU: 1000, Memory: 10},
+			Limits:             Resource{CPU: 1000, Memory: 10},
+			MonthlyRange:       CostRange{Kind: DeploymentKind, MinRequested: 30, MaxRequested: 30, HPABuffer: 30, MinLimited: 30, MaxLimited: 30},
+		},
+		{
+			APIVersionKindName: "apps/v1|Deployment|default|backend",
+			Namespace:          "default",
+			Kind:               DeploymentKind,
+			Name:               "backend",
+			Replicas:           1,
+			MinReplicas:        1,
+			MaxReplicas:        1,
+			Requests:           Resource{CPU: 1000, Memory: 10},
+			Limits:             Resource{CPU: 1000, Memory: 10},
+			MonthlyRange:       CostRange{Kind: DeploymentKind, MinRequested: 15, MaxRequested: 15, HPABuffer: 15, MinLimited: 15, MaxLimited: 15},
+		},
+	}
+	if !cmp.Equal(cost.MonthlyObjectRanges, expected) {
+		t.Errorf("MonthlyObjectRanges should be equal, expected: %+v, got: %+v", expected, cost.MonthlyObjectRanges)
+	}
+	if got := cost.MonthlyObjectRanges[0].Key(); got != "shop/Deployment/frontend" {
+		t.Errorf("Key is %v, want shop/Deployment/frontend", got)
+	}
+
+	expectedKindRanges := []CostRange{
+		{Kind: DeploymentKind, MinRequested: 45, MaxRequested: 45, HPABuffer: 45, MinLimited: 45, MaxLimited: 45},
+	}
+	if !cmp.Equal(cost.MonthlyRanges, expectedKindRanges) {
+		t.Errorf("MonthlyRanges should be equal, expected: %+v, got: %+v", expectedKindRanges, cost.MonthlyRanges)
+	}
+}
+
+func TestEstimateCostPodAndReplicationController(t *testing.T) {
+	data := `apiVersion: autoscaling/v1
+kind: HorizontalPodAutoscaler
+metadata:
+  name: legacy
+spec:
+  maxReplicas: 4
+  minReplicas: 2
+  scaleTargetRef:
+    apiVersion: v1
+    kind: ReplicationController
+    name: legacy
+---
+apiVersion: v1
+kind: ReplicationController
+metadata:
+  name: legacy
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: legacy
+        image: nginx
+        resources:
+          requests:
+            memory: "10"
+            cpu: "1"
+          limits:
+            memory: "10"
+            cpu: "1"
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: debug
+spec:
+  containers:
+  - name: debug
+    image: busybox
+    resources:
+      requests:
+        memory: "10"
+        cpu: "1"
+      limits:
+        memory: "10"
+        cpu: "1"`
+
+	manifests := Manifests{}
+	err := manifests.LoadObjects([]byte(data), CostimatorConfig{})
+	if err != nil {
+		t.Errorf("Error loading objects: %+v", err)
+	}
+
+	pp := &fixedPriceProvider{cpuPrice: 10, memoryPrice: 0.5}
+	cost := manifests.EstimateCost(pp)
+
+	expected := []CostRange{
+		{Kind: ReplicationControllerKind, MinRequested: 30, MaxRequested: 60, HPABuffer: 30, MinLimited: 30, MaxLimited: 60},
+		{Kind: PodKind, MinRequested: 15, MaxRequested: 15, HPABuffer: 15, MinLimited: 15, MaxLimited: 15},
+	}
+	if !cmp.Equal(cost.MonthlyRanges, expected) {
+		t.Errorf("MonthlyRanges should be equal, expected: %+v, got: %+v", expected, cost.MonthlyRanges)
+	}
+	if got := cost.MonthlyObjectRanges[0].MaxReplicas; got != 4 {
+		t.Errorf("ReplicationController should have been linked to HPA, expected MaxReplicas 4, got: %+v", got)
+	}
+}
+
+func TestEstimateCostSpot(t *testing.T) {
+	data := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: batch
+spec:
+  replicas: 2
+  template:
+    spec:
+      nodeSelector:
+        cloud.google.com/gke-spot: "true"
+      containers:
+      - name: batch
+        image: nginx
+        resources:
+          requests:
+            memory: "1"
+            cpu: "1"
+          limits:
+            memory: "1"
+            cpu: "1"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: frontend
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: frontend
+        image: nginx
+        resources:
+          requests:
+            memory: "1"
+            cpu: "1"
+          limits:
+            memory: "1"
+            cpu: "1"`
+
+	manifests := Manifests{}
+	err := manifests.LoadObjects([]byte(data), CostimatorConfig{})
+	if err != nil {
+		t.Errorf("Error loading objects: %+v", err)
+	}
+
+	pc := &GCPPriceCatalog{cpuPrice: 10, memoryPrice: 2, spotCPUPrice: 3, spotMemoryPrice: 1}
+	cost := manifests.EstimateCost(pc)
+
+	// batch runs on Spot VMs: 2 * (3 + 1), frontend runs on-demand: 10 + 2
+	expected := []CostRange{
+		{Kind: DeploymentKind, MinRequested: 20, MaxRequested: 20, HPABuffer: 20, MinLimited: 20, MaxLimited: 20},
+	}
+	if !cmp.Equal(cost.MonthlyRanges, expected) {
+		t.Errorf("MonthlyRanges should be equal, expected: %+v, got: %+v", expected, cost.MonthlyRanges)
+	}
+	if !cost.MonthlyObjectRanges[0].Spot || cost.MonthlyObjectRanges[1].Spot {
+		t.Errorf("Only batch deployment should be priced at Spot VM rates, got: %+v", cost.MonthlyObjectRanges)
+	}
+
+	expectedComparisons := []SpotComparison{
+		{
+			Kind:     DeploymentKind,
+			OnDemand: CostRange{Kind: DeploymentKind, MinRequested: 36, MaxRequested: 36, HPABuffer: 36, MinLimited: 36, MaxLimited: 36},
+			Spot:     CostRange{Kind: DeploymentKind, MinRequested: 12, MaxRequested: 12, HPABuffer: 12, MinLimited: 12, MaxLimited: 12},
+		},
+	}
+	if !cmp.Equal(cost.SpotComparisons, expectedComparisons) {
+		t.Errorf("SpotComparisons should be equal, expected: %+v, got: %+v", expectedComparisons, cost.SpotComparisons)
+	}
+	if !strings.Contains(cost.ToMarkdown(), "66.67%") {
+		t.Errorf("Markdown should contain Spot VM savings, got: %s", cost.ToMarkdown())
+	}
+
+	// no comparison when Spot VM prices are not available
+	cost = manifests.EstimateCost(&fixedPriceProvider{cpuPrice: 10, memoryPrice: 2})
+	if len(cost.SpotComparisons) != 0 {
+		t.Errorf("SpotComparisons should be empty, got: %+v", cost.SpotComparisons)
+	}
+}
+
+func TestPriceRequirements(t *testing.T) {
+	data := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: inference
+spec:
+  template:
+    spec:
+      nodeSelector:
+        cloud.google.com/gke-accelerator: nvidia-l4
+      containers:
+      - name: inference
+        image: inference
+        resources:
+          limits:
+            nvidia.com/gpu: "1"
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: build
+spec:
+  template:
+    spec:
+      nodeSelector:
+        cloud.google.com/gke-ephemeral-storage-local-ssd: "true"
+      containers:
+      - name: build
+        image: build`
+
+	manifests := Manifests{}
+	err := manifests.LoadObjects([]byte(data), CostimatorConfig{})
+	if err != nil {
+		t.Errorf("Error loading objects: %+v", err)
+	}
+
+	expected := PriceRequirements{Accelerators: []string{"nvidia-l4"}, LocalSSD: true}
+	if got := manifests.PriceRequirements(); !cmp.Equal(got, expected) {
+		t.Errorf("PriceRequirements should be equal, expected: %+v, got: %+v", expected, got)
+	}
+}
@@ -0,0 +1,320 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	coreV1 "k8s.io/api/core/v1"
+)
+
+const (
+	// NodePoolNodeLabel is the label GKE sets with the node pool name
+	NodePoolNodeLabel = "cloud.google.com/gke-nodepool"
+	// MachineFamilyNodeLabel is the label GKE sets with the node machine family (eg. n2)
+	MachineFamilyNodeLabel = "cloud.google.com/machine-family"
+	// DefaultNodePoolName is the name of the deprecated ClusterConfig.NodePool when not provided, as in GKE
+	DefaultNodePoolName = "default-pool"
+)
+
+// nodePool returns the name of the first node pool pods can be scheduled on (see ClusterConfig.NodePools)
+// It is empty when no node pool is configured, for Autopilot clusters and when pods can't be scheduled on any node pool
+func nodePool(spec coreV1.PodSpec, conf CostimatorConfig) string {
+	if len(conf.ClusterConf.NodePools) == 0 || conf.ClusterConf.Mode == Autopilot {
+		return ""
+	}
+	pool, found := matchNodePool(spec, conf)
+	if !found {
+		log.Warnf("Pods can't be scheduled on any of the node pools in clusterConf.nodePools. Using resourceConf prices instead")
+		return ""
+	}
+	return pool.Name
+}
+
+// matchNodePool returns the first node pool whose labels and taints allow pods to be scheduled
+func matchNodePool(spec coreV1.PodSpec, conf CostimatorConfig) (NodePoolConfig, bool) {
+	pools := matchNodePools(spec, conf)
+	if len(pools) == 0 {
+		return NodePoolConfig{}, false
+	}
+	return pools[0], true
+}
+
+// matchNodePools returns all node pools whose labels and taints allow pods to be scheduled, eg. the ones DaemonSet pods run on
+func matchNodePools(spec coreV1.PodSpec, conf CostimatorConfig) []NodePoolConfig {
+	pools := []NodePoolConfig{}
+	if conf.ClusterConf.Mode == Autopilot {
+		return pools
+	}
+	for _, pool := range conf.ClusterConf.NodePools {
+		labels := pool.nodeLabels(conf)
+		if matchNodeSelector(spec.NodeSelector, labels) && matchNodeAffinity(spec.Affinity, labels) && toleratesTaints(spec.Tolerations, pool.Taints) {
+			pools = append(pools, pool)
+		}
+	}
+	return pools
+}
+
+// EffectiveNodePools returns ClusterConfig.NodePools or, for configs using the deprecated ClusterConfig.NodePool, a single node pool
+// named DefaultNodePoolName if it has no name. As the only node pool, it is a Spot one when all nodes are Spot VMs (see ClusterConfig.SpotVMs)
+func (c *ClusterConfig) EffectiveNodePools() []NodePoolConfig {
+	if len(c.NodePools) > 0 || c.NodePool == nil {
+		return c.NodePools
+	}
+	pool := *c.NodePool
+	if pool.Name == "" {
+		pool.Name = DefaultNodePoolName
+	}
+	pool.Spot = pool.Spot || c.SpotVMs
+	return []NodePoolConfig{pool}
+}
+
+func nodePoolNames(pools []NodePoolConfig) []string {
+	names := []string{}
+	for _, pool := range pools {
+		names = append(names, pool.Name)
+	}
+	return names
+}
+
+// PriceConfig returns the config used to retrieve the node pool prices, ie. with the node pool machine family and region
+func (p *NodePoolConfig) PriceConfig(conf CostimatorConfig) CostimatorConfig {
+	conf = populateConfigNotProvided(conf)
+	conf.ResourceConf.MachineFamily = p.machineFamily(conf)
+	if p.Region != "" {
+		conf.ResourceConf.Region = p.Region
+	}
+	return conf
+}
+
+// pricedWithResourceConf tells if workloads placed on the given node pool are priced with resourceConf machine family and region
+func pricedWithResourceConf(pool string, conf CostimatorConfig) bool {
+	if pool == "" {
+		return true
+	}
+	conf = populateConfigNotProvided(conf)
+	for _, p := range conf.ClusterConf.NodePools {
+		if p.Name == pool {
+			priceConf := p.PriceConfig(conf)
+			return priceConf.ResourceConf.MachineFamily == conf.ResourceConf.MachineFamily &&
+				strings.EqualFold(priceConf.ResourceConf.Region, conf.ResourceConf.Region)
+		}
+	}
+	return true
+}
+
+func (p *NodePoolConfig) machineFamily(conf CostimatorConfig) MachineFamily {
+	if p.MachineFamily != "" {
+		return p.MachineFamily
+	}
+	if machineType, err := parseMachineType(p.MachineType); err == nil {
+		return machineType.MachineFamily
+	}
+	return conf.ResourceConf.MachineFamily
+}
+
+// nodeLabels returns the node pool labels, including the ones GKE sets
+func (p *NodePoolConfig) nodeLabels(conf CostimatorConfig) map[string]string {
+	labels := map[string]string{
+		NodePoolNodeLabel:      p.Name,
+		MachineFamilyNodeLabel: strings.ToLower(string(p.machineFamily(populateConfigNotProvided(conf)))),
+	}
+	if p.Spot {
+		labels[SpotNodeLabel] = "true"
+	}
+	if p.LocalSSD {
+		labels[LocalSSDNodeLabel] = "true"
+	}
+	for k, v := range p.Labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+func matchNodeSelector(nodeSelector map[string]string, labels map[string]string) bool {
+	for k, v := range nodeSelector {
+		if value, ok := labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// matchNodeAffinity checks the required node affinity. Terms are ORed and expressions within a term are ANDed
+func matchNodeAffinity(affinity *coreV1.Affinity, labels map[string]string) bool {
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true
+	}
+	terms := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) == 0 {
+		return true
+	}
+	for _, term := range terms {
+		matches := true
+		for _, expr := range term.MatchExpressions {
+			if !matchNodeSelectorRequirement(expr, labels) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+func matchNodeSelectorRequirement(expr coreV1.NodeSelectorRequirement, labels map[string]string) bool {
+	value, ok := labels[expr.Key]
+	switch expr.Operator {
+	case coreV1.NodeSelectorOpIn:
+		return ok && containsValue(expr.Values, value)
+	case coreV1.NodeSelectorOpNotIn:
+		return !ok || !containsValue(expr.Values, value)
+	case coreV1.NodeSelectorOpExists:
+		return ok
+	case coreV1.NodeSelectorOpDoesNotExist:
+		return !ok
+	case coreV1.NodeSelectorOpGt, coreV1.NodeSelectorOpLt:
+		if !ok || len(expr.Values) != 1 {
+			return false
+		}
+		labelValue, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false
+		}
+		exprValue, err := strconv.ParseInt(expr.Values[0], 10, 64)
+		if err != nil {
+			return false
+		}
+		if expr.Operator == coreV1.NodeSelectorOpGt {
+			return labelValue > exprValue
+		}
+		return labelValue < exprValue
+	default:
+		return false
+	}
+}
+
+// toleratesTaints checks pods tolerate all NoSchedule and NoExecute taints
+func toleratesTaints(tolerations []coreV1.Toleration, taints []NodeTaint) bool {
+	for _, taint := range taints {
+		effect := coreV1.TaintEffect(taint.Effect)
+		if effect == "" {
+			effect = coreV1.TaintEffectNoSchedule
+		}
+		if effect == coreV1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		k8sTaint := &coreV1.Taint{Key: taint.Key, Value: taint.Value, Effect: effect}
+		tolerated := false
+		for _, toleration := range tolerations {
+			if toleration.ToleratesTaint(k8sTaint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// -------- Node Pool Price Catalog ---------
+
+// NodePoolPriceProvider interface is implemented by price providers knowing the prices of each node pool
+// found is false if prices for the node pool are not available
+type NodePoolPriceProvider interface {
+	NodePoolResourcePrice(pool string) (rp ResourcePrice, found bool)
+}
+
+// NodePoolPriceCatalog provides the prices of each node pool (see ClusterConfig.NodePools)
+// Workloads not placed on a node pool, as well as storage, are priced with the default price provider
+type NodePoolPriceCatalog struct {
+	PriceProvider
+	pools map[string]PriceProvider
+}
+
+// NewNodePoolPriceCatalog creates a NodePoolPriceCatalog given the default prices and the prices of each node pool, keyed by node pool name
+func NewNodePoolPriceCatalog(defaultPrices PriceProvider, pools map[string]PriceProvider) *NodePoolPriceCatalog {
+	return &NodePoolPriceCatalog{PriceProvider: defaultPrices, pools: pools}
+}
+
+// NodePoolResourcePrice returns the prices of the given node pool
+func (c *NodePoolPriceCatalog) NodePoolResourcePrice(pool string) (ResourcePrice, bool) {
+	pp, found := c.pools[pool]
+	return pp, found
+}
+
+// SpotResourcePrice returns the default Spot VM prices
+func (c *NodePoolPriceCatalog) SpotResourcePrice() (ResourcePrice, bool) {
+	return findResourcePrice(c.PriceProvider, true, "")
+}
+
+// AutopilotResourcePrice returns the default GKE Autopilot pod prices
+func (c *NodePoolPriceCatalog) AutopilotResourcePrice(class ComputeClass, spot bool) (ResourcePrice, bool) {
+	return findResourcePrice(c.PriceProvider, spot, class)
+}
+
+// GPUMonthlyPrice returns the default GPU prices
+func (c *NodePoolPriceCatalog) GPUMonthlyPrice(accelerator string) (float32, bool) {
+	if ap, ok := c.PriceProvider.(AcceleratorPrice); ok {
+		return ap.GPUMonthlyPrice(accelerator)
+	}
+	return 0, false
+}
+
+// BootDiskMonthlyPrice returns the default boot disk prices
+func (c *NodePoolPriceCatalog) BootDiskMonthlyPrice() float32 {
+	if np, ok := c.PriceProvider.(NodeStoragePrice); ok {
+		return np.BootDiskMonthlyPrice()
+	}
+	return c.StorageMonthlyPrice(bootDisk)
+}
+
+// LocalSSDMonthlyPrice returns the default local SSD prices
+func (c *NodePoolPriceCatalog) LocalSSDMonthlyPrice() (float32, bool) {
+	if np, ok := c.PriceProvider.(NodeStoragePrice); ok {
+		return np.LocalSSDMonthlyPrice()
+	}
+	return 0, false
+}
+
+// poolResourcePrice returns the prices of the node pool workloads are placed on
+// It falls back to the given prices when the workload is not placed on a node pool or its prices are not known
+func poolResourcePrice(rp ResourcePrice, pool string) ResourcePrice {
+	if pool == "" {
+		return rp
+	}
+	if pp, ok := rp.(NodePoolPriceProvider); ok {
+		if poolPrice, found := pp.NodePoolResourcePrice(pool); found {
+			return poolPrice
+		}
+	}
+	log.Infof("Node pool '%s' prices not available in the price catalog. Using resourceConf prices instead", pool)
+	return rp
+}
@@ -0,0 +1,304 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+
+	coreV1 "k8s.io/api/core/v1"
+)
+
+var testNodePools = []NodePoolConfig{
+	{Name: "default"},
+	{Name: "spot", Spot: true},
+	{Name: "highmem", MachineType: "n2-highmem-8", Labels: map[string]string{"tier": "memory"}},
+	{Name: "dedicated", Labels: map[string]string{"team": "data"}, Taints: []NodeTaint{{Key: "dedicated", Value: "data"}}},
+}
+
+func TestMatchNodePool(t *testing.T) {
+	conf := CostimatorConfig{ClusterConf: ClusterConfig{NodePools: testNodePools}}
+	affinity := func(expr coreV1.NodeSelectorRequirement) *coreV1.Affinity {
+		return &coreV1.Affinity{NodeAffinity: &coreV1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &coreV1.NodeSelector{
+				NodeSelectorTerms: []coreV1.NodeSelectorTerm{{MatchExpressions: []coreV1.NodeSelectorRequirement{expr}}},
+			},
+		}}
+	}
+	tests := map[string]struct {
+		spec  coreV1.PodSpec
+		want  string
+		found bool
+	}{
+		"no constraints": {
+			spec:  coreV1.PodSpec{},
+			want:  "default",
+			found: true,
+		},
+		"spot node selector": {
+			spec:  coreV1.PodSpec{NodeSelector: map[string]string{SpotNodeLabel: "true"}},
+			want:  "spot",
+			found: true,
+		},
+		"node pool node selector": {
+			spec:  coreV1.PodSpec{NodeSelector: map[string]string{NodePoolNodeLabel: "highmem"}},
+			want:  "highmem",
+			found: true,
+		},
+		"machine family node affinity": {
+			spec:  coreV1.PodSpec{Affinity: affinity(coreV1.NodeSelectorRequirement{Key: MachineFamilyNodeLabel, Operator: coreV1.NodeSelectorOpIn, Values: []string{"n2"}})},
+			want:  "highmem",
+			found: true,
+		},
+		"not in node affinity": {
+			spec:  coreV1.PodSpec{Affinity: affinity(coreV1.NodeSelectorRequirement{Key: NodePoolNodeLabel, Operator: coreV1.NodeSelectorOpNotIn, Values: []string{"default", "spot"}})},
+			want:  "highmem",
+			found: true,
+		},
+		"taint not tolerated": {
+			spec:  coreV1.PodSpec{NodeSelector: map[string]string{"team": "data"}},
+			found: false,
+		},
+		"taint tolerated": {
+			spec: coreV1.PodSpec{
+				NodeSelector: map[string]string{"team": "data"},
+				Tolerations:  []coreV1.Toleration{{Key: "dedicated", Operator: coreV1.TolerationOpEqual, Value: "data", Effect: coreV1.TaintEffectNoSchedule}},
+			},
+			want:  "dedicated",
+			found: true,
+		},
+		"no matching labels": {
+			spec:  coreV1.PodSpec{NodeSelector: map[string]string{"tier": "gpu"}},
+			found: false,
+		},
+	}
+	for name, tt := range tests {
+		got, found := matchNodePool(tt.spec, conf)
+		if found != tt.found || got.Name != tt.want {
+			t.Errorf("%s: node pool is '%s' (found: %v), want '%s' (found: %v)", name, got.Name, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestNodePoolPriceConfig(t *testing.T) {
+	conf := CostimatorConfig{ResourceConf: ResourceConfig{MachineFamily: E2, Region: "us-east1"}}
+	tests := map[string]struct {
+		pool   NodePoolConfig
+		family MachineFamily
+		region string
+	}{
+		"defaults":       {pool: NodePoolConfig{}, family: E2, region: "us-east1"},
+		"machine type":   {pool: NodePoolConfig{MachineType: "n2d-standard-4"}, family: N2D, region: "us-east1"},
+		"machine family": {pool: NodePoolConfig{MachineType: "n2d-standard-4", MachineFamily: N1, Region: "europe-west1"}, family: N1, region: "europe-west1"},
+	}
+	for name, tt := range tests {
+		got := tt.pool.PriceConfig(conf)
+		if got.ResourceConf.MachineFamily != tt.family || got.ResourceConf.Region != tt.region {
+			t.Errorf("%s: price config is %s/%s, want %s/%s", name, got.ResourceConf.MachineFamily, got.ResourceConf.Region, tt.family, tt.region)
+		}
+	}
+}
+
+func TestEstimateCostNodePools(t *testing.T) {
+	data := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx
+        resources:
+          requests:
+            memory: "1"
+            cpu: "1"
+          limits:
+            memory: "1"
+            cpu: "1"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: cache
+spec:
+  template:
+    spec:
+      nodeSelector:
+        tier: memory
+      containers:
+      - name: cache
+        image: redis
+        resources:
+          requests:
+            memory: "1"
+            cpu: "1"
+          limits:
+            memory: "1"
+            cpu: "1"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: batch
+spec:
+  template:
+    spec:
+      nodeSelector:
+        cloud.google.com/gke-spot: "true"
+      containers:
+      - name: batch
+        image: batch
+        resources:
+          requests:
+            memory: "1"
+            cpu: "1"
+          limits:
+            memory: "1"
+            cpu: "1"`
+
+	conf := CostimatorConfig{ClusterConf: ClusterConfig{NodePools: testNodePools}}
+	manifests := Manifests{}
+	err := manifests.LoadObjects([]byte(data), conf)
+	if err != nil {
+		t.Fatalf("Error loading objects: %+v", err)
+	}
+
+	pp := NewNodePoolPriceCatalog(
+		&GCPPriceCatalog{cpuPrice: 1, spotCPUPrice: 1, spotMemoryPrice: 1},
+		map[string]PriceProvider{
+			"default": &GCPPriceCatalog{cpuPrice: 10},
+			"spot":    &GCPPriceCatalog{cpuPrice: 10, spotCPUPrice: 3, spotMemoryPrice: 1},
+			"highmem": &GCPPriceCatalog{cpuPrice: 20},
+		})
+	cost := manifests.EstimateCost(pp)
+
+	want := map[string]struct {
+		pool string
+		spot bool
+		cost float64
+	}{
+		"web":   {pool: "default", cost: 10},
+		"cache": {pool: "highmem", cost: 20},
+		"batch": {pool: "spot", spot: true, cost: 4},
+	}
+	for _, o := range cost.MonthlyObjectRanges {
+		w := want[o.Name]
+		if o.NodePool != w.pool || o.Spot != w.spot || o.MonthlyRange.MinRequested != w.cost {
+			t.Errorf("%s should be placed on '%s' (spot: %v) and cost %v, got '%s' (spot: %v) and %v", o.Name, w.pool, w.spot, w.cost, o.NodePool, o.Spot, o.MonthlyRange.MinRequested)
+		}
+	}
+}
+
+func TestEstimateNodesPerNodePool(t *testing.T) {
+	data := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 5
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx
+        resources:
+          requests:
+            memory: "1Gi"
+            cpu: "1"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: cache
+spec:
+  template:
+    spec:
+      nodeSelector:
+        tier: memory
+      tolerations:
+      - key: dedicated
+        value: memory
+      containers:
+      - name: cache
+        image: redis
+        resources:
+          requests:
+            memory: "1Gi"
+            cpu: "1"
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+spec:
+  template:
+    spec:
+      containers:
+      - name: agent
+        image: agent
+        resources:
+          requests:
+            memory: "100Mi"
+            cpu: "100m"
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: logging
+spec:
+  template:
+    spec:
+      tolerations:
+      - operator: Exists
+      containers:
+      - name: logging
+        image: fluentd
+        resources:
+          requests:
+            memory: "100Mi"
+            cpu: "100m"`
+
+	conf := CostimatorConfig{ClusterConf: ClusterConfig{NodesCount: 10, NodePools: []NodePoolConfig{
+		{Name: "default", MachineType: "e2-standard-4"},
+		{Name: "memory", MachineType: "n2-highmem-4", Labels: map[string]string{"tier": "memory"}, Taints: []NodeTaint{{Key: "dedicated", Value: "memory"}}},
+		{Name: "gpu", Taints: []NodeTaint{{Key: "nvidia.com/gpu", Value: "present"}}}, // no machine type
+
+	}}}
+	manifests := Manifests{}
+	err := manifests.LoadObjects([]byte(data), conf)
+	if err != nil {
+		t.Fatalf("Error loading objects: %+v", err)
+	}
+	cost := manifests.EstimateCost(&GCPPriceCatalog{cpuPrice: 10, memoryPrice: 1.0 / bytesInGiB})
+
+	// 3920m allocatable, minus 200m for both DaemonSets: 3 web pods per node
+	want := map[string]int32{"default": 2, "memory": 1}
+	if len(cost.Nodes) != len(want) {
+		t.Fatalf("Expected %d simulated node pools, got %+v", len(want), cost.Nodes)
+	}
+	for _, nodes := range cost.Nodes {
+		if nodes.MinNodes != want[nodes.NodePool] {
+			t.Errorf("Expected %d nodes in '%s', got %d", want[nodes.NodePool], nodes.NodePool, nodes.MinNodes)
+		}
+	}
+	// DaemonSets run on the nodes of the node pools they can be scheduled on, or on clusterConf.nodesCount when any of them is not simulated
+	wantDaemonSets := map[string]int32{"agent": 2, "logging": 10}
+	for _, o := range cost.MonthlyObjectRanges {
+		if o.Kind == DaemonSetKind && o.Replicas != wantDaemonSets[o.Name] {
+			t.Errorf("DaemonSet '%s' should run on %d nodes, got %d", o.Name, wantDaemonSets[o.Name], o.Replicas)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultMaxPods is the GKE default maximum number of pods per node
+const defaultMaxPods = 110
+
+// evictionThreshold is the memory GKE reserves for kubelet hard eviction
+const evictionThreshold = 100 * 1024 * 1024
+
+// memoryPerVCPU is the GiB of memory per vCPU of predefined machine types
+var memoryPerVCPU = map[string]map[MachineFamily]float64{
+	"standard": {N1: 3.75, E2: 4, N2: 4, N2D: 4, C2: 4, C2D: 4, T2D: 4},
+	"highmem":  {N1: 6.5, E2: 8, N2: 8, N2D: 8, C2D: 8},
+	"highcpu":  {N1: 0.9, E2: 1, N2: 1, N2D: 1, C2D: 2},
+	"megamem":  {M1: 14.9333},
+	"ultramem": {M1: 24.025},
+}
+
+// customMachineFamilies are the machine families supporting custom machine types
+var customMachineFamilies = map[MachineFamily]bool{N1: true, E2: true, N2: true, N2D: true}
+
+// sharedCoreMachineTypes are E2 shared-core machine types. CPU is the fraction of vCPU guaranteed
+var sharedCoreMachineTypes = map[string]Resource{
+	"e2-micro":  {CPU: 250, Memory: 1 * bytesInGiB},
+	"e2-small":  {CPU: 500, Memory: 2 * bytesInGiB},
+	"e2-medium": {CPU: 1000, Memory: 4 * bytesInGiB},
+}
+
+// acceleratorMachineTypes are A2 machine types. Their names tell the number of GPUs, not vCPUs
+var acceleratorMachineTypes = map[string]Resource{
+	"a2-highgpu-1g":  {CPU: 12000, Memory: 85 * bytesInGiB},
+	"a2-highgpu-2g":  {CPU: 24000, Memory: 170 * bytesInGiB},
+	"a2-highgpu-4g":  {CPU: 48000, Memory: 340 * bytesInGiB},
+	"a2-highgpu-8g":  {CPU: 96000, Memory: 680 * bytesInGiB},
+	"a2-megagpu-16g": {CPU: 96000, Memory: 1360 * bytesInGiB},
+	"a2-ultragpu-1g": {CPU: 12000, Memory: 170 * bytesInGiB},
+	"a2-ultragpu-2g": {CPU: 24000, Memory: 340 * bytesInGiB},
+	"a2-ultragpu-4g": {CPU: 48000, Memory: 680 * bytesInGiB},
+	"a2-ultragpu-8g": {CPU: 96000, Memory: 1360 * bytesInGiB},
+}
+
+// MachineType is the shape of a GCE machine type
+type MachineType struct {
+	Name          string        `json:"name"`
+	MachineFamily MachineFamily `json:"machineFamily"`
+	Capacity      Resource      `json:"capacity"`
+}
+
+// NodeEstimate is the number of nodes needed to run all pods, and their monthly cost
+// Pods are packed onto nodes at min replicas (MinNodes) and at max HPA replicas (MaxNodes)
+type NodeEstimate struct {
+	NodePool          string      `json:"nodePool"`
+	MachineType       MachineType `json:"machineType"`
+	Allocatable       Resource    `json:"allocatable"` // per node, after kube-reserved
+	MaxPods           int32       `json:"maxPods"`
+	MinNodes          int32       `json:"minNodes"`
+	MaxNodes          int32       `json:"maxNodes"`
+	UnschedulablePods int32       `json:"unschedulablePods"` // pods not fitting an empty node
+	MinMonthlyCost    float64     `json:"minMonthlyCost"`
+	MaxMonthlyCost    float64     `json:"maxMonthlyCost"`
+}
+
+// podGroup is a set of identical pods
+type podGroup struct {
+	requests Resource
+	minCount int32
+	maxCount int32
+}
+
+type node struct {
+	free Resource
+	pods int32
+}
+
+// parseMachineType returns the shape of predefined (eg. n2-standard-4), shared-core (eg. e2-medium),
+// accelerator-optimized (eg. a2-highgpu-1g) and custom (eg. n2-custom-4-16384) machine types
+func parseMachineType(name string) (MachineType, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if capacity, ok := sharedCoreMachineTypes[name]; ok {
+		return MachineType{Name: name, MachineFamily: E2, Capacity: capacity}, nil
+	}
+	if capacity, ok := acceleratorMachineTypes[name]; ok {
+		return MachineType{Name: name, MachineFamily: A2, Capacity: capacity}, nil
+	}
+
+	parts := strings.Split(name, "-")
+	// N1 custom machine types have no family prefix
+	if len(parts) == 3 && parts[0] == "custom" {
+		parts = append([]string{"n1"}, parts...)
+	}
+	family := MachineFamily(strings.ToUpper(parts[0]))
+	if _, ok := cpuPrefixes[family]; !ok || len(parts) < 3 {
+		return MachineType{}, fmt.Errorf("Machine type '%s' not supported", name)
+	}
+	vcpus, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || vcpus <= 0 {
+		return MachineType{}, fmt.Errorf("Invalid number of vCPUs in machine type '%s'", name)
+	}
+
+	var memory int64
+	switch {
+	case parts[1] == "custom" && len(parts) == 4 && customMachineFamilies[family]:
+		memoryMiB, err := strconv.ParseInt(parts[3], 10, 64)
+		if err != nil || memoryMiB <= 0 {
+			return MachineType{}, fmt.Errorf("Invalid memory in machine type '%s'", name)
+		}
+		memory = memoryMiB * 1024 * 1024
+	case len(parts) == 3 && memoryPerVCPU[parts[1]][family] > 0:
+		memory = int64(float64(vcpus) * memoryPerVCPU[parts[1]][family] * bytesInGiB)
+	default:
+		return MachineType{}, fmt.Errorf("Machine type '%s' not supported", name)
+	}
+	return MachineType{Name: name, MachineFamily: family, Capacity: Resource{CPU: vcpus * 1000, Memory: memory}}, nil
+}
+
+// kubeReserved returns the resources GKE reserves for Kubernetes components and eviction on nodes of the given capacity
+// See https://cloud.google.com/kubernetes-engine/docs/concepts/plan-node-sizes#memory_and_cpu_reservations
+func kubeReserved(capacity Resource) Resource {
+	cpuTiers := []struct {
+		upTo       int64 // millis
+		percentage float64
+	}{
+		{1000, 6}, {2000, 1}, {4000, 0.5}, {math.MaxInt64, 0.25},
+	}
+	memoryTiers := []struct {
+		upTo       int64 // bytes
+		percentage float64
+	}{
+		{4 * bytesInGiB, 25}, {8 * bytesInGiB, 20}, {16 * bytesInGiB, 10}, {128 * bytesInGiB, 6}, {math.MaxInt64, 2},
+	}
+
+	reserved := Resource{}
+	var from int64
+	for _, tier := range cpuTiers {
+		if capacity.CPU > from {
+			reserved.CPU += int64(math.Ceil(float64(minInt64(capacity.CPU, tier.upTo)-from) * tier.percentage / 100))
+		}
+		from = tier.upTo
+	}
+	if capacity.Memory < bytesInGiB {
+		reserved.Memory = 255 * 1024 * 1024
+	} else {
+		from = 0
+		for _, tier := range memoryTiers {
+			if capacity.Memory > from {
+				reserved.Memory += int64(float64(minInt64(capacity.Memory, tier.upTo)-from) * tier.percentage / 100)
+			}
+			from = tier.upTo
+		}
+	}
+	reserved.Memory += evictionThreshold
+	return reserved
+}
+
+// simulateNodes packs pods onto nodes of the configured node pool
+// DaemonSet pods are reserved on every node. Pods are packed first fit, biggest first
+func simulateNodes(pool NodePoolConfig, groups []podGroup, daemonSetPods []Resource, rp ResourcePrice) (NodeEstimate, error) {
+	machineType, err := parseMachineType(pool.MachineType)
+	if err != nil {
+		return NodeEstimate{}, err
+	}
+	reserved := kubeReserved(machineType.Capacity)
+	if pool.KubeReservedCPUinMillis != 0 {
+		reserved.CPU = pool.KubeReservedCPUinMillis
+	}
+	if pool.KubeReservedMemoryinBytes != 0 {
+		reserved.Memory = pool.KubeReservedMemoryinBytes
+	}
+	estimate := NodeEstimate{
+		MachineType: machineType,
+		Allocatable: machineType.Capacity.add(negative(reserved)),
+		MaxPods:     pool.MaxPods,
+	}
+	if estimate.MaxPods == 0 {
+		estimate.MaxPods = defaultMaxPods
+	}
+
+	// DaemonSet pods run on every node
+	empty := node{free: estimate.Allocatable, pods: estimate.MaxPods}
+	for _, requests := range daemonSetPods {
+		empty.free = empty.free.add(negative(requests))
+		empty.pods--
+	}
+	if empty.free.CPU < 0 || empty.free.Memory < 0 || empty.pods < 0 {
+		return NodeEstimate{}, fmt.Errorf("DaemonSets don't fit in a %s node (allocatable %s CPU and %.2fGi memory)", machineType.Name, formatCPU(estimate.Allocatable.CPU), float64(estimate.Allocatable.Memory)/bytesInGiB)
+	}
+
+	minPods := []Resource{}
+	maxPods := []Resource{}
+	for _, g := range groups {
+		for i := int32(0); i < g.maxCount; i++ {
+			if i < g.minCount {
+				minPods = append(minPods, g.requests)
+			}
+			maxPods = append(maxPods, g.requests)
+		}
+	}
+	var unschedulable int32
+	estimate.MinNodes, _ = packPods(minPods, empty)
+	estimate.MaxNodes, unschedulable = packPods(maxPods, empty)
+	estimate.UnschedulablePods = unschedulable
+	// node pools run at least one node (eg. for DaemonSets)
+	if estimate.MinNodes == 0 {
+		estimate.MinNodes = 1
+	}
+	if estimate.MaxNodes == 0 {
+		estimate.MaxNodes = 1
+	}
+	if unschedulable > 0 {
+		log.Warnf("%d pods don't fit in a %s node and were not considered in node count", unschedulable, machineType.Name)
+	}
+
+	nodeMonthlyPrice := (float64(machineType.Capacity.CPU) / 1000 * float64(rp.CPUMonthlyPrice())) + (float64(machineType.Capacity.Memory) * float64(rp.MemoryMonthlyPrice()))
+	estimate.MinMonthlyCost = float64(estimate.MinNodes) * nodeMonthlyPrice
+	estimate.MaxMonthlyCost = float64(estimate.MaxNodes) * nodeMonthlyPrice
+	return estimate, nil
+}
+
+// packPods returns the number of nodes needed to run all pods (first fit decreasing) and the number of pods not fitting an empty node
+func packPods(pods []Resource, empty node) (nodesCount int32, unschedulable int32) {
+	share := func(r Resource) float64 {
+		return math.Max(float64(r.CPU)/float64(empty.free.CPU), float64(r.Memory)/float64(empty.free.Memory))
+	}
+	sort.SliceStable(pods, func(i, j int) bool {
+		return share(pods[i]) > share(pods[j])
+	})
+
+	nodes := []node{}
+	for _, pod := range pods {
+		if !empty.fits(pod) {
+			unschedulable++
+			continue
+		}
+		placed := false
+		for i := range nodes {
+			if nodes[i].fits(pod) {
+				nodes[i].place(pod)
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			n := empty
+			n.place(pod)
+			nodes = append(nodes, n)
+		}
+	}
+	return int32(len(nodes)), unschedulable
+}
+
+func (n *node) fits(pod Resource) bool {
+	return n.pods > 0 && pod.CPU <= n.free.CPU && pod.Memory <= n.free.Memory
+}
+
+func (n *node) place(pod Resource) {
+	n.free = n.free.add(negative(pod))
+	n.pods--
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func nodeEstimatesToMarkdown(estimates []NodeEstimate) string {
+	data := [][]string{}
+	unschedulable := []string{}
+	for _, e := range estimates {
+		data = append(data,
+			[]string{e.NodePool,
+				e.MachineType.Name,
+				fmt.Sprintf("%s CPU / %.2fGi", formatCPU(e.Allocatable.CPU), float64(e.Allocatable.Memory)/bytesInGiB),
+				fmt.Sprintf("%d", e.MinNodes),
+				fmt.Sprintf("%d", e.MaxNodes),
+				currency(e.MinMonthlyCost),
+				currency(e.MaxMonthlyCost)})
+		if e.UnschedulablePods > 0 {
+			unschedulable = append(unschedulable, fmt.Sprintf("%d pods don't fit in a %s node of node pool '%s' and were not considered.", e.UnschedulablePods, e.MachineType.Name, e.NodePool))
+		}
+	}
+
+	out := &strings.Builder{}
+	table := tablewriter.NewWriter(out)
+	table.SetHeader([]string{"Node Pool", "Machine Type", "Node Allocatable", "Min Nodes", "Max Nodes", "Min Cost (USD)", "Max Cost (USD)"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.SetColumnAlignment([]int{0, 0, 0, 2, 2, 2, 2})
+	table.AppendBulk(data)
+	table.Render()
+	for _, u := range unschedulable {
+		fmt.Fprintf(out, "\n%s\n", u)
+	}
+	return out.String()
+}
@@ -0,0 +1,225 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseMachineType(t *testing.T) {
+	tests := map[string]MachineType{
+		"e2-standard-4":    {Name: "e2-standard-4", MachineFamily: E2, Capacity: Resource{CPU: 4000, Memory: 16 * bytesInGiB}},
+		"n1-standard-2":    {Name: "n1-standard-2", MachineFamily: N1, Capacity: Resource{CPU: 2000, Memory: 7.5 * bytesInGiB}},
+		"N2-HIGHMEM-8":     {Name: "n2-highmem-8", MachineFamily: N2, Capacity: Resource{CPU: 8000, Memory: 64 * bytesInGiB}},
+		"n2d-highcpu-16":   {Name: "n2d-highcpu-16", MachineFamily: N2D, Capacity: Resource{CPU: 16000, Memory: 16 * bytesInGiB}},
+		"custom-4-16384":   {Name: "custom-4-16384", MachineFamily: N1, Capacity: Resource{CPU: 4000, Memory: 16 * bytesInGiB}},
+		"n2-custom-2-4096": {Name: "n2-custom-2-4096", MachineFamily: N2, Capacity: Resource{CPU: 2000, Memory: 4 * bytesInGiB}},
+		"e2-medium":        {Name: "e2-medium", MachineFamily: E2, Capacity: Resource{CPU: 1000, Memory: 4 * bytesInGiB}},
+		"c2-standard-8":    {Name: "c2-standard-8", MachineFamily: C2, Capacity: Resource{CPU: 8000, Memory: 32 * bytesInGiB}},
+		"c2d-highcpu-4":    {Name: "c2d-highcpu-4", MachineFamily: C2D, Capacity: Resource{CPU: 4000, Memory: 8 * bytesInGiB}},
+		"t2d-standard-1":   {Name: "t2d-standard-1", MachineFamily: T2D, Capacity: Resource{CPU: 1000, Memory: 4 * bytesInGiB}},
+		"m1-ultramem-40":   {Name: "m1-ultramem-40", MachineFamily: M1, Capacity: Resource{CPU: 40000, Memory: 961 * bytesInGiB}},
+		"a2-highgpu-1g":    {Name: "a2-highgpu-1g", MachineFamily: A2, Capacity: Resource{CPU: 12000, Memory: 85 * bytesInGiB}},
+	}
+	for name, want := range tests {
+		got, err := parseMachineType(name)
+		if err != nil || !cmp.Equal(got, want) {
+			t.Errorf("Machine type '%s' should be %+v, got %+v (err: %+v)", name, want, got, err)
+		}
+	}
+
+	for _, name := range []string{"x9-standard-4", "n2-standard-x", "n2-ultra-4", "n2-custom-4", "c2-highmem-4", "c2-custom-4-16384", "a2-highgpu-3g"} {
+		if _, err := parseMachineType(name); err == nil {
+			t.Errorf("Machine type '%s' should not be supported", name)
+		}
+	}
+}
+
+func TestKubeReserved(t *testing.T) {
+	// e2-standard-4: 6% of 1st core + 1% of 2nd + 0.5% of 3rd and 4th; 25% of 4GiB + 20% of 4GiB + 10% of 8GiB + eviction
+	got := kubeReserved(Resource{CPU: 4000, Memory: 16 * bytesInGiB})
+	want := Resource{CPU: 80, Memory: 2791728742 + evictionThreshold}
+	if got != want {
+		t.Errorf("Kube reserved should be %+v, got %+v", want, got)
+	}
+
+	got = kubeReserved(Resource{CPU: 250, Memory: 512 * 1024 * 1024})
+	want = Resource{CPU: 15, Memory: 255*1024*1024 + evictionThreshold}
+	if got != want {
+		t.Errorf("Kube reserved for small machines should be %+v, got %+v", want, got)
+	}
+}
+
+func TestSimulateNodes(t *testing.T) {
+	pool := NodePoolConfig{
+		MachineType:               "e2-standard-4",
+		KubeReservedCPUinMillis:   1000,
+		KubeReservedMemoryinBytes: 4 * bytesInGiB,
+		MaxPods:                   4,
+	}
+	groups := []podGroup{
+		{requests: Resource{CPU: 1000, Memory: bytesInGiB}, minCount: 2, maxCount: 5},
+		{requests: Resource{CPU: 2000, Memory: bytesInGiB}, minCount: 1, maxCount: 1},
+		{requests: Resource{CPU: 3000, Memory: bytesInGiB}, minCount: 1, maxCount: 1},
+	}
+	// 2500m, 10GiB and 3 pods are left for other pods on each node
+	daemonSetPods := []Resource{{CPU: 500, Memory: 2 * bytesInGiB}}
+	rp := &GCPPriceCatalog{cpuPrice: 10, memoryPrice: 1.0 / bytesInGiB}
+
+	got, err := simulateNodes(pool, groups, daemonSetPods, rp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// min: [2000m] [1000m, 1000m]. max: [2000m] [1000m, 1000m] [1000m, 1000m] [1000m]. 3000m pod doesn't fit
+	if got.MinNodes != 2 || got.MaxNodes != 4 || got.UnschedulablePods != 1 {
+		t.Errorf("Expected 2 to 4 nodes and 1 unschedulable pod, got %d to %d nodes and %d unschedulable pods", got.MinNodes, got.MaxNodes, got.UnschedulablePods)
+	}
+	// 4 vCPU * 10 + 16 GiB * 1 per node
+	if got.MinMonthlyCost != 112 || got.MaxMonthlyCost != 224 {
+		t.Errorf("Expected node cost from 112 to 224, got %v to %v", got.MinMonthlyCost, got.MaxMonthlyCost)
+	}
+
+	daemonSetPods = []Resource{{CPU: 4000}}
+	if _, err = simulateNodes(pool, groups, daemonSetPods, rp); err == nil || !strings.Contains(err.Error(), "DaemonSets") {
+		t.Errorf("Should have returned a DaemonSets error, but returned '%+v'", err)
+	}
+}
+
+func TestEstimateCostNodePool(t *testing.T) {
+	data := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: my-nginx
+spec:
+  template:
+    spec:
+      containers:
+      - name: my-nginx
+        image: nginx
+        resources:
+          requests:
+            memory: "1Gi"
+            cpu: "1"
+---
+apiVersion: autoscaling/v1
+kind: HorizontalPodAutoscaler
+metadata:
+  name: my-nginx
+spec:
+  maxReplicas: 6
+  minReplicas: 2
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: my-nginx
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+spec:
+  template:
+    spec:
+      containers:
+      - name: agent
+        image: agent
+        resources:
+          requests:
+            memory: "1Gi"
+            cpu: "1"`
+
+	conf := CostimatorConfig{
+		ClusterConf: ClusterConfig{
+			NodesCount: 10,
+			NodePool:   &NodePoolConfig{MachineType: "e2-standard-4"},
+		},
+	}
+	manifests := Manifests{}
+	err := manifests.LoadObjects([]byte(data), conf)
+	if err != nil {
+		t.Fatalf("Error loading objects: %+v", err)
+	}
+	cost := manifests.EstimateCost(&GCPPriceCatalog{cpuPrice: 10, memoryPrice: 1.0 / bytesInGiB})
+	if len(cost.Nodes) != 1 {
+		t.Fatalf("Nodes should have been simulated")
+	}
+
+	// 3920m allocatable, minus 1 vCPU for the DaemonSet: 2 pods per node
+	nodes := cost.Nodes[0]
+	if nodes.NodePool != DefaultNodePoolName || nodes.MinNodes != 1 || nodes.MaxNodes != 3 {
+		t.Errorf("Expected 1 to 3 nodes in '%s', got %d to %d in '%s'", DefaultNodePoolName, nodes.MinNodes, nodes.MaxNodes, nodes.NodePool)
+	}
+	for _, o := range cost.MonthlyObjectRanges {
+		if o.Kind == DaemonSetKind && (o.MinReplicas != 1 || o.MaxReplicas != 3) {
+			t.Errorf("DaemonSet should run on 1 to 3 nodes, got %d to %d", o.MinReplicas, o.MaxReplicas)
+		}
+	}
+	if !strings.Contains(cost.ToMarkdown(), "**Node pools (bin packing):**") {
+		t.Errorf("Markdown should contain node pool, got: %s", cost.ToMarkdown())
+	}
+}
+
+func TestEstimateCostNodePoolPrices(t *testing.T) {
+	data := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: my-nginx
+spec:
+  template:
+    spec:
+      containers:
+      - name: my-nginx
+        image: nginx
+        resources:
+          requests:
+            memory: "1Gi"
+            cpu: "1"`
+
+	tests := map[string]struct {
+		pool     NodePoolConfig
+		expected float64
+	}{
+		// n2-standard-4 nodes (4 vCPUs and 16GiB) at the node pool N2 prices, not at resourceConf E2 ones
+		"machine family": {pool: NodePoolConfig{MachineType: "n2-standard-4"}, expected: 4*20 + 16*2},
+		"named":          {pool: NodePoolConfig{Name: "n2-pool", MachineType: "n2-standard-4"}, expected: 4*20 + 16*2},
+		"spot":           {pool: NodePoolConfig{MachineType: "n2-standard-4", Spot: true}, expected: 4*5 + 16*1},
+	}
+	for name, tt := range tests {
+		conf := CostimatorConfig{
+			ResourceConf: ResourceConfig{MachineFamily: E2},
+			ClusterConf:  ClusterConfig{NodePool: &tt.pool},
+		}
+		manifests := Manifests{}
+		err := manifests.LoadObjects([]byte(data), conf)
+		if err != nil {
+			t.Fatalf("Error loading objects: %+v", err)
+		}
+
+		poolPrices := make(map[string]PriceProvider)
+		for _, pool := range conf.ClusterConf.EffectiveNodePools() {
+			poolPrices[pool.Name] = &GCPPriceCatalog{cpuPrice: 20, memoryPrice: 2.0 / bytesInGiB, spotCPUPrice: 5, spotMemoryPrice: 1.0 / bytesInGiB}
+		}
+		cost := manifests.EstimateCost(NewNodePoolPriceCatalog(&GCPPriceCatalog{cpuPrice: 10, memoryPrice: 1.0 / bytesInGiB}, poolPrices))
+		if len(cost.Nodes) != 1 {
+			t.Fatalf("%s: nodes should have been simulated", name)
+		}
+		if nodes := cost.Nodes[0]; nodes.MinNodes != 1 || nodes.MinMonthlyCost != tt.expected {
+			t.Errorf("%s: expected 1 node costing %v, got %d nodes costing %v", name, tt.expected, nodes.MinNodes, nodes.MinMonthlyCost)
+		}
+	}
+}
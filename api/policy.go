@@ -0,0 +1,263 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"sigs.k8s.io/yaml"
+)
+
+// PolicyStatus is the outcome of evaluating a cost policy
+type PolicyStatus string
+
+const (
+	// PolicyPass no threshold exceeded
+	PolicyPass PolicyStatus = "pass"
+	// PolicyWarn at least one warn threshold exceeded, but no fail threshold
+	PolicyWarn PolicyStatus = "warn"
+	// PolicyFail at least one fail threshold exceeded
+	PolicyFail PolicyStatus = "fail"
+)
+
+// Exit codes returned by the binary after evaluating a cost policy and budgets. Other errors exit with -1
+// Codes 1 and 2 are avoided since they are used by the Go runtime and the flag package (eg. invalid flags)
+const (
+	PolicyPassExitCode = 0
+	PolicyWarnExitCode = 10
+	PolicyFailExitCode = 11
+)
+
+// Policy holds the thresholds used to gate cost increases between previous and current k8s manifests
+type Policy struct {
+	Rules []PolicyRule `json:"rules"`
+}
+
+// PolicyRule sets warn and fail thresholds for the cost increase of a cost column within a scope (namespace and kind)
+type PolicyRule struct {
+	Name      string           `json:"name,omitempty"`
+	Column    string           `json:"column,omitempty"`    // minRequested, hpaBuffer, maxRequested, minLimited or maxLimited. All columns if not provided
+	Namespace string           `json:"namespace,omitempty"` // only objects in this namespace. All namespaces if not provided
+	Kind      string           `json:"kind,omitempty"`      // only objects of this kind. All kinds if not provided
+	Warn      *PolicyThreshold `json:"warn,omitempty"`
+	Fail      *PolicyThreshold `json:"fail,omitempty"`
+}
+
+// PolicyThreshold is exceeded when the cost increase is greater than any of its limits
+type PolicyThreshold struct {
+	USD        *float64 `json:"usd,omitempty"`        // monthly increase in USD
+	Percentage *float64 `json:"percentage,omitempty"` // increase in percentage of the previous cost. Any increase over a zero cost exceeds it
+}
+
+// PolicyViolation is a threshold exceeded by the cost increase of a cost column
+type PolicyViolation struct {
+	Rule           string       `json:"rule"`
+	Status         PolicyStatus `json:"status"` // warn or fail
+	Column         string       `json:"column"`
+	Previous       float64      `json:"previous"`
+	Current        float64      `json:"current"`
+	DiffUSD        float64      `json:"diffUSD"`
+	DiffPercentage float64      `json:"diffPercentage"` // 100 when previous cost is zero
+	Message        string       `json:"message"`
+}
+
+// PolicyResult is the outcome of evaluating all policy rules
+type PolicyResult struct {
+	Status     PolicyStatus      `json:"status"`
+	Violations []PolicyViolation `json:"violations"`
+}
+
+// ReadPolicyFile decodes and validates a YAML/JSON cost policy file
+func ReadPolicyFile(data []byte) (Policy, error) {
+	p := Policy{}
+	err := yaml.UnmarshalStrict(data, &p)
+	if err != nil {
+		return Policy{}, fmt.Errorf("Unable to decode policy file: %+v", err)
+	}
+	return p, p.validate()
+}
+
+func (p *Policy) validate() error {
+	if len(p.Rules) == 0 {
+		return fmt.Errorf("Policy must have at least one rule")
+	}
+	for i, r := range p.Rules {
+		field := fmt.Sprintf("rules[%d]", i)
+		if r.Column != "" && !isCostDimension(r.Column) {
+			return fmt.Errorf("Column '%s' in '%s.column' not supported. Supported columns: %s", r.Column, field, strings.Join(costDimensionNames(), ", "))
+		}
+		if r.Warn == nil && r.Fail == nil {
+			return fmt.Errorf("Rule '%s' must have 'warn' or 'fail' thresholds", field)
+		}
+		for _, t := range []struct {
+			name      string
+			threshold *PolicyThreshold
+		}{{"warn", r.Warn}, {"fail", r.Fail}} {
+			name, t := t.name, t.threshold
+			if t == nil {
+				continue
+			}
+			if t.USD == nil && t.Percentage == nil {
+				return fmt.Errorf("Threshold '%s.%s' must have 'usd' or 'percentage'", field, name)
+			}
+			if (t.USD != nil && *t.USD < 0) || (t.Percentage != nil && *t.Percentage < 0) {
+				return fmt.Errorf("Threshold '%s.%s' must not be negative", field, name)
+			}
+		}
+	}
+	return nil
+}
+
+// Evaluate checks the cost increase from previous to current k8s manifests against all rules
+// Only increases are checked, so cost reductions always pass
+func (p *Policy) Evaluate(diff DiffCost) PolicyResult {
+	result := PolicyResult{Status: PolicyPass, Violations: []PolicyViolation{}}
+	for i, r := range p.Rules {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("rules[%d]", i)
+		}
+		prev, curr := r.scopedCost(diff.CostPrev), r.scopedCost(diff.CostCurr)
+		for _, dim := range costDimensions {
+			if r.Column != "" && r.Column != dim.name {
+				continue
+			}
+			v := PolicyViolation{Rule: name, Column: dim.name, Previous: dim.value(prev), Current: dim.value(curr)}
+			v.DiffUSD = v.Current - v.Previous
+			diffPercentage := percentageIncrease(v.Previous, v.Current)
+			if r.Fail.exceeded(v.DiffUSD, diffPercentage) {
+				v.Status = PolicyFail
+			} else if r.Warn.exceeded(v.DiffUSD, diffPercentage) {
+				v.Status = PolicyWarn
+			} else {
+				continue
+			}
+			v.Message = fmt.Sprintf("%s %s increased %s (%s)%s", r.scope(), dim.name, currency(v.DiffUSD), percentage(diffPercentage), r.thresholdDescription(v.Status))
+			v.DiffPercentage = jsonPercentage(diffPercentage)
+			result.Violations = append(result.Violations, v)
+			if v.Status == PolicyFail || result.Status == PolicyPass {
+				result.Status = v.Status
+			}
+		}
+	}
+	return result
+}
+
+// scopedCost sums the cost of the objects matching the rule namespace and kind. Total cost if not provided
+func (r *PolicyRule) scopedCost(cost Cost) CostRange {
+	if r.Namespace == "" && r.Kind == "" {
+		return cost.MonthlyTotal()
+	}
+	total := CostRange{Kind: r.Kind}
+	for _, or := range cost.MonthlyObjectRanges {
+		if (r.Namespace == "" || or.Namespace == r.Namespace) && (r.Kind == "" || strings.EqualFold(or.Kind, r.Kind)) {
+			total = total.Add(or.MonthlyRange)
+		}
+	}
+	return total
+}
+
+func (r *PolicyRule) scope() string {
+	namespace, kind := r.Namespace, r.Kind
+	if namespace == "" {
+		namespace = "*"
+	}
+	if kind == "" {
+		kind = "*"
+	}
+	return fmt.Sprintf("%s/%s", namespace, kind)
+}
+
+func (r *PolicyRule) thresholdDescription(status PolicyStatus) string {
+	t := r.Warn
+	if status == PolicyFail {
+		t = r.Fail
+	}
+	limits := []string{}
+	if t.USD != nil {
+		limits = append(limits, currency(*t.USD))
+	}
+	if t.Percentage != nil {
+		limits = append(limits, percentage(*t.Percentage))
+	}
+	return fmt.Sprintf(", exceeding %s threshold of %s", status, strings.Join(limits, " or "))
+}
+
+func (t *PolicyThreshold) exceeded(diffUSD, diffPercentage float64) bool {
+	if t == nil || diffUSD <= 0 {
+		return false
+	}
+	return (t.USD != nil && diffUSD > *t.USD) || (t.Percentage != nil && diffPercentage > *t.Percentage)
+}
+
+func percentage(value float64) string {
+	if math.IsInf(value, 1) {
+		return "new"
+	}
+	return fmt.Sprintf("%.2f%%", value)
+}
+
+// ExitCode returns the exit code the binary must return for the policy status
+func (r *PolicyResult) ExitCode() int {
+	switch r.Status {
+	case PolicyFail:
+		return PolicyFailExitCode
+	case PolicyWarn:
+		return PolicyWarnExitCode
+	default:
+		return PolicyPassExitCode
+	}
+}
+
+// ToMarkdown convert to Markdown string
+func (r *PolicyResult) ToMarkdown() string {
+	summary := fmt.Sprintf("**Status:** %s", strings.ToUpper(string(r.Status)))
+	if len(r.Violations) == 0 {
+		return summary + "\n"
+	}
+	data := [][]string{}
+	for _, v := range r.Violations {
+		data = append(data, []string{v.Rule, strings.ToUpper(string(v.Status)), v.Message})
+	}
+	out := &strings.Builder{}
+	table := tablewriter.NewWriter(out)
+	table.SetHeader([]string{"Rule", "Status", "Violation"})
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.SetAutoWrapText(false)
+	table.AppendBulk(data)
+	table.Render()
+	return fmt.Sprintf("%s\n\n%s", summary, out.String())
+}
+
+func isCostDimension(name string) bool {
+	for _, dim := range costDimensions {
+		if dim.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func costDimensionNames() []string {
+	names := []string{}
+	for _, dim := range costDimensions {
+		names = append(names, dim.name)
+	}
+	return names
+}
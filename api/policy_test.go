@@ -0,0 +1,171 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func policyTestDiff() DiffCost {
+	prev := Cost{
+		MonthlyRanges: []CostRange{{Kind: DeploymentKind, MinRequested: 100, MaxRequested: 100, HPABuffer: 100, MinLimited: 200, MaxLimited: 200}},
+		MonthlyObjectRanges: []ObjectCostRange{
+			newObjectCostRange("apps/v1|Deployment|shop|frontend", CostRange{Kind: DeploymentKind, MinRequested: 100, MaxRequested: 100, HPABuffer: 100, MinLimited: 200, MaxLimited: 200}),
+		},
+	}
+	curr := Cost{
+		MonthlyRanges: []CostRange{
+			{Kind: DeploymentKind, MinRequested: 110, MaxRequested: 150, HPABuffer: 110, MinLimited: 220, MaxLimited: 300},
+			{Kind: StatefulSetKind, MinRequested: 20, MaxRequested: 20, HPABuffer: 20, MinLimited: 20, MaxLimited: 20},
+		},
+		MonthlyObjectRanges: []ObjectCostRange{
+			newObjectCostRange("apps/v1|Deployment|shop|frontend", CostRange{Kind: DeploymentKind, MinRequested: 110, MaxRequested: 150, HPABuffer: 110, MinLimited: 220, MaxLimited: 300}),
+			newObjectCostRange("apps/v1|StatefulSet|db|mysql", CostRange{Kind: StatefulSetKind, MinRequested: 20, MaxRequested: 20, HPABuffer: 20, MinLimited: 20, MaxLimited: 20}),
+		},
+	}
+	return curr.Subtract(prev)
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	tests := []struct {
+		name           string
+		policy         string
+		wantStatus     PolicyStatus
+		wantExitCode   int
+		wantViolations []string
+	}{
+		{"pass", `
+rules:
+- warn:
+    usd: 1000`, PolicyPass, PolicyPassExitCode, []string{}},
+		{"warn on total percentage", `
+rules:
+- column: maxRequested
+  warn:
+    percentage: 50`, PolicyWarn, PolicyWarnExitCode, []string{"*/* maxRequested increased $70.00 (70.00%), exceeding warn threshold of 50.00%"}},
+		{"fail wins over warn", `
+rules:
+- column: maxLimited
+  warn:
+    usd: 10
+  fail:
+    usd: 100`, PolicyFail, PolicyFailExitCode, []string{"*/* maxLimited increased $120.00 (60.00%), exceeding fail threshold of $100.00"}},
+		{"per namespace", `
+rules:
+- namespace: shop
+  column: minRequested
+  fail:
+    usd: 5`, PolicyFail, PolicyFailExitCode, []string{"shop/* minRequested increased $10.00 (10.00%), exceeding fail threshold of $5.00"}},
+		{"per kind with new cost", `
+rules:
+- name: statefulsets
+  kind: statefulset
+  column: minRequested
+  warn:
+    percentage: 1000`, PolicyWarn, PolicyWarnExitCode, []string{"*/statefulset minRequested increased $20.00 (new), exceeding warn threshold of 1000.00%"}},
+		{"no object in scope", `
+rules:
+- namespace: db
+  kind: Deployment
+  fail:
+    usd: 0`, PolicyPass, PolicyPassExitCode, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := ReadPolicyFile([]byte(tt.policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := policy.Evaluate(policyTestDiff())
+			if result.Status != tt.wantStatus || result.ExitCode() != tt.wantExitCode {
+				t.Errorf("Expected status %s (exit code %d), got %s (exit code %d)", tt.wantStatus, tt.wantExitCode, result.Status, result.ExitCode())
+			}
+			messages := []string{}
+			for _, v := range result.Violations {
+				messages = append(messages, v.Message)
+			}
+			if strings.Join(messages, "\n") != strings.Join(tt.wantViolations, "\n") {
+				t.Errorf("Expected violations %+v, got %+v", tt.wantViolations, messages)
+			}
+		})
+	}
+}
+
+func TestPolicyNewCostPercentage(t *testing.T) {
+	policy, _ := ReadPolicyFile([]byte(`
+rules:
+- kind: StatefulSet
+  column: minRequested
+  fail:
+    percentage: 10`))
+	result := policy.Evaluate(policyTestDiff())
+	if len(result.Violations) != 1 || result.Violations[0].DiffPercentage != 100 {
+		t.Errorf("New cost should be reported as a 100%% increase, got %+v", result.Violations)
+	}
+	report := Report{Policy: &result}
+	if _, err := report.ToJSON(); err != nil {
+		t.Errorf("Policy result should be marshaled to JSON, got %+v", err)
+	}
+}
+
+func TestReadPolicyFileErrors(t *testing.T) {
+	tests := []struct {
+		policy  string
+		wantErr string
+	}{
+		{`rules: []`, "at least one rule"},
+		{`
+rules:
+- column: total
+  warn:
+    usd: 1`, "Column 'total' in 'rules[0].column' not supported"},
+		{`
+rules:
+- namespace: shop`, "must have 'warn' or 'fail' thresholds"},
+		{`
+rules:
+- fail: {}`, "Threshold 'rules[0].fail' must have 'usd' or 'percentage'"},
+		{`
+rules:
+- warn:
+    usd: -1`, "must not be negative"},
+		{`
+rules:
+- warn:
+    dollars: 1`, "Unable to decode policy file"},
+	}
+	for _, tt := range tests {
+		_, err := ReadPolicyFile([]byte(tt.policy))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Expected error containing '%s', got '%+v'", tt.wantErr, err)
+		}
+	}
+}
+
+func TestPolicyResultToMarkdown(t *testing.T) {
+	policy, _ := ReadPolicyFile([]byte(`
+rules:
+- name: total
+  fail:
+    usd: 100`))
+	result := policy.Evaluate(policyTestDiff())
+	markdown := result.ToMarkdown()
+	for _, expected := range []string{"**Status:** FAIL", "| total | FAIL   |"} {
+		if !strings.Contains(markdown, expected) {
+			t.Errorf("Markdown should contain '%s', got:\n%s", expected, markdown)
+		}
+	}
+}
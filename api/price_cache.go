@@ -0,0 +1,129 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/yaml"
+)
+
+// PriceCache stores GCP prices on disk, keyed by region and machine family (and cluster mode for Autopilot)
+// and by the requested SKUs, so prices cached without eg. some accelerator are not reused when it is required
+// Entries are written in the offline price catalog file format (see PriceCatalogFile)
+type PriceCache struct {
+	Dir string
+	TTL time.Duration
+}
+
+// DefaultPriceCacheDir returns the folder used to cache prices when none is provided
+func DefaultPriceCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "k8s-cost-estimator"), nil
+}
+
+// NewCachedGCPPriceCatalog returns the cached prices when they are not older than the cache TTL
+// Otherwise, it calls GCP CloudCatalog (see NewGCPPriceCatalog) and refreshes the cache
+// If refresh is true, cached prices are ignored
+func NewCachedGCPPriceCatalog(credentials []byte, conf CostimatorConfig, requirements PriceRequirements, cache PriceCache, refresh bool) (GCPPriceCatalog, error) {
+	conf = populateConfigNotProvided(conf)
+	if !refresh {
+		pc, found, err := cache.Load(conf, requirements)
+		if err != nil {
+			log.Warnf("Ignoring price cache: %+v", err)
+		} else if found {
+			log.Infof("Using cached GCP price catalog '%s'", cache.path(conf, requirements))
+			return pc, nil
+		}
+	}
+
+	log.Infof("Using live GCP price catalog for region '%s' and machine family '%s'", conf.ResourceConf.Region, conf.ResourceConf.MachineFamily)
+	pc, err := NewGCPPriceCatalog(credentials, conf, requirements)
+	if err != nil {
+		return GCPPriceCatalog{}, err
+	}
+	err = cache.Store(pc, conf, requirements)
+	if err != nil {
+		log.Warnf("Unable to cache prices: %+v", err)
+	}
+	return pc, nil
+}
+
+// Load returns the cached prices for the configured region and machine family, and the given requirements
+// found is false when there is no cached prices or they are older than the cache TTL
+func (c *PriceCache) Load(conf CostimatorConfig, requirements PriceRequirements) (pc GCPPriceCatalog, found bool, err error) {
+	path := c.path(conf, requirements)
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		log.Debugf("Price cache '%s' not found", path)
+		return GCPPriceCatalog{}, false, nil
+	}
+	if err != nil {
+		return GCPPriceCatalog{}, false, err
+	}
+	if age := time.Since(info.ModTime()); age > c.TTL {
+		log.Debugf("Price cache '%s' expired %s ago", path, age-c.TTL)
+		return GCPPriceCatalog{}, false, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return GCPPriceCatalog{}, false, err
+	}
+	pc, err = NewGCPPriceCatalogFromFile(data, conf)
+	if err != nil {
+		return GCPPriceCatalog{}, false, fmt.Errorf("Invalid price cache '%s': %+v", path, err)
+	}
+	return pc, true, nil
+}
+
+// Store writes the given prices into the cache for the configured region and machine family, and the given requirements
+func (c *PriceCache) Store(pc GCPPriceCatalog, conf CostimatorConfig, requirements PriceRequirements) error {
+	f := PriceCatalogFile{}
+	f.AddEntry(NewPriceCatalogEntry(pc, conf))
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(c.Dir, 0755)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path(conf, requirements), data, 0644)
+}
+
+func (c *PriceCache) path(conf CostimatorConfig, requirements PriceRequirements) string {
+	conf = populateConfigNotProvided(conf)
+	name := fmt.Sprintf("prices-%s-%s", conf.ResourceConf.Region, conf.ResourceConf.MachineFamily)
+	// Autopilot prices are only retrieved for Autopilot clusters
+	if conf.ClusterConf.Mode == Autopilot {
+		name = fmt.Sprintf("%s-%s", name, Autopilot)
+	}
+	// prices are only retrieved for the requested SKUs (see newRequestedSKUs)
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%+v", newRequestedSKUs(conf, requirements))
+	name = fmt.Sprintf("%s-%08x.yaml", name, h.Sum32())
+	return filepath.Join(c.Dir, strings.ToLower(name))
+}
@@ -0,0 +1,131 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPriceCacheStoreAndLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "price-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache := PriceCache{Dir: dir, TTL: time.Hour}
+	conf := CostimatorConfig{ResourceConf: ResourceConfig{MachineFamily: N2, Region: "europe-west1"}}
+	pc := GCPPriceCatalog{cpuPrice: 10, memoryPrice: 1.0 / bytesInGiB, diskPrices: map[PersistentDisk]float32{defaultPersistentDisk: 0.5 / bytesInGiB}, gpuPrices: map[string]float32{"nvidia-tesla-t4": 260}}
+	err = cache.Store(pc, conf, PriceRequirements{})
+	if err != nil {
+		t.Fatalf("Error storing prices: %+v", err)
+	}
+
+	got, found, err := cache.Load(conf, PriceRequirements{})
+	if err != nil || !found {
+		t.Fatalf("Prices should have been found in cache, found: %v, err: %+v", found, err)
+	}
+	if !cmp.Equal(got, pc, cmp.AllowUnexported(GCPPriceCatalog{})) {
+		t.Errorf("Cached prices should be equal, expected: %+v, got: %+v", pc, got)
+	}
+
+	_, found, err = cache.Load(ConfigDefaults(), PriceRequirements{})
+	if err != nil || found {
+		t.Errorf("Prices should not have been found for another region and machine family, found: %v, err: %+v", found, err)
+	}
+}
+
+func TestPriceCacheRequirements(t *testing.T) {
+	dir, err := ioutil.TempDir("", "price-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache := PriceCache{Dir: dir, TTL: time.Hour}
+	conf := ConfigDefaults()
+	err = cache.Store(GCPPriceCatalog{cpuPrice: 10}, conf, PriceRequirements{})
+	if err != nil {
+		t.Fatalf("Error storing prices: %+v", err)
+	}
+
+	for _, requirements := range []PriceRequirements{
+		{Accelerators: []string{"nvidia-tesla-a100"}},
+		{LocalSSD: true},
+		{All: true},
+	} {
+		_, found, err := cache.Load(conf, requirements)
+		if err != nil || found {
+			t.Errorf("Prices should not have been found for requirements %+v, found: %v, err: %+v", requirements, found, err)
+		}
+	}
+
+	// the default accelerator is always requested
+	_, found, err := cache.Load(conf, PriceRequirements{Accelerators: []string{conf.ResourceConf.DefaultAccelerator}})
+	if err != nil || !found {
+		t.Errorf("Prices should have been found for the default accelerator, found: %v, err: %+v", found, err)
+	}
+}
+
+func TestPriceCacheExpired(t *testing.T) {
+	dir, err := ioutil.TempDir("", "price-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache := PriceCache{Dir: dir, TTL: time.Hour}
+	conf := ConfigDefaults()
+	err = cache.Store(GCPPriceCatalog{cpuPrice: 10}, conf, PriceRequirements{})
+	if err != nil {
+		t.Fatalf("Error storing prices: %+v", err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	err = os.Chtimes(cache.path(conf, PriceRequirements{}), old, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, found, err := cache.Load(conf, PriceRequirements{})
+	if err != nil || found {
+		t.Errorf("Expired prices should not have been found, found: %v, err: %+v", found, err)
+	}
+}
+
+func TestNewCachedGCPPriceCatalogUsesCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "price-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache := PriceCache{Dir: dir, TTL: time.Hour}
+	pc := GCPPriceCatalog{cpuPrice: 10, diskPrices: map[PersistentDisk]float32{defaultPersistentDisk: 0}}
+	err = cache.Store(pc, CostimatorConfig{}, PriceRequirements{})
+	if err != nil {
+		t.Fatalf("Error storing prices: %+v", err)
+	}
+
+	// no GCP call is made when prices are cached
+	got, err := NewCachedGCPPriceCatalog(nil, CostimatorConfig{}, PriceRequirements{}, cache, false)
+	if err != nil || !cmp.Equal(got, pc, cmp.AllowUnexported(GCPPriceCatalog{})) {
+		t.Errorf("Cached prices should have been used, expected: %+v, got: %+v, err: %+v", pc, got, err)
+	}
+}
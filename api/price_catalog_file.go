@@ -0,0 +1,239 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// PriceCatalogFileVersion is the current version of the offline price catalog file format
+const PriceCatalogFileVersion = "v1"
+
+const bytesInGiB = 1024 * 1024 * 1024
+
+// PriceCatalogFile is the offline representation of the GCP prices used by the estimator
+// It allows estimating costs without reaching GCP Cloud Billing API (eg. air-gapped environments)
+// Both YAML and JSON formats are accepted
+type PriceCatalogFile struct {
+	Version string              `json:"version"`
+	Prices  []PriceCatalogEntry `json:"prices"`
+}
+
+// PriceCatalogEntry holds the monthly prices (USD) for a given region and machine family
+type PriceCatalogEntry struct {
+	Region                      string           `json:"region"`
+	MachineFamily               MachineFamily    `json:"machineFamily"`
+	CPUMonthlyPrice             float32          `json:"cpuMonthlyPrice"`
+	MemoryGiBMonthlyPrice       float32          `json:"memoryGiBMonthlyPrice"`
+	SpotCPUMonthlyPrice         float32          `json:"spotCPUMonthlyPrice,omitempty"`
+	SpotMemoryGiBMonthlyPrice   float32          `json:"spotMemoryGiBMonthlyPrice,omitempty"`
+	PdStandardGiBMonthlyPrice   float32          `json:"pdStandardGiBMonthlyPrice"`
+	DiskPrices                  []DiskPrice      `json:"diskPrices,omitempty"`
+	AutopilotPrices             []AutopilotPrice `json:"autopilotPrices,omitempty"`
+	GPUPrices                   []GPUPrice       `json:"gpuPrices,omitempty"`
+	LocalSSDGiBMonthlyPrice     float32          `json:"localSSDGiBMonthlyPrice,omitempty"`
+	SpotLocalSSDGiBMonthlyPrice float32          `json:"spotLocalSSDGiBMonthlyPrice,omitempty"`
+}
+
+// DiskPrice holds the monthly price (USD) per GiB of a GCE Persistent Disk other than zonal pd-standard
+type DiskPrice struct {
+	DiskType        DiskType        `json:"diskType"`
+	Replication     DiskReplication `json:"replication"`
+	GiBMonthlyPrice float32         `json:"gibMonthlyPrice"`
+}
+
+// AutopilotPrice holds the monthly prices (USD) of GKE Autopilot pods for a given compute class
+type AutopilotPrice struct {
+	ComputeClass                    ComputeClass `json:"computeClass"`
+	Spot                            bool         `json:"spot,omitempty"`
+	CPUMonthlyPrice                 float32      `json:"cpuMonthlyPrice"`
+	MemoryGiBMonthlyPrice           float32      `json:"memoryGiBMonthlyPrice"`
+	EphemeralStorageGiBMonthlyPrice float32      `json:"ephemeralStorageGiBMonthlyPrice"`
+}
+
+// GPUPrice holds the monthly price (USD) of a single GPU of a given accelerator type
+type GPUPrice struct {
+	Accelerator  string  `json:"accelerator"`
+	Spot         bool    `json:"spot,omitempty"`
+	MonthlyPrice float32 `json:"monthlyPrice"`
+}
+
+// NewGCPPriceCatalogFromFile creates a GCPPriceCatalog from an offline price catalog file content
+// The entry matching the configured region and machine family is used
+func NewGCPPriceCatalogFromFile(data []byte, conf CostimatorConfig) (GCPPriceCatalog, error) {
+	f, err := ReadPriceCatalogFile(data)
+	if err != nil {
+		return GCPPriceCatalog{}, err
+	}
+	return f.PriceCatalog(conf)
+}
+
+// ReadPriceCatalogFile decodes an offline price catalog file content (YAML or JSON)
+func ReadPriceCatalogFile(data []byte) (PriceCatalogFile, error) {
+	f := PriceCatalogFile{}
+	err := yaml.Unmarshal(data, &f)
+	if err != nil {
+		return PriceCatalogFile{}, fmt.Errorf("Unable to decode price catalog file: %+v", err)
+	}
+	if f.Version != PriceCatalogFileVersion {
+		return PriceCatalogFile{}, fmt.Errorf("Price catalog file version '%s' not supported. Expected '%s'", f.Version, PriceCatalogFileVersion)
+	}
+	return f, nil
+}
+
+// PriceCatalog returns the GCPPriceCatalog for the configured region and machine family
+func (f *PriceCatalogFile) PriceCatalog(conf CostimatorConfig) (GCPPriceCatalog, error) {
+	conf = populateConfigNotProvided(conf)
+	for _, e := range f.Prices {
+		if e.matches(conf) {
+			return e.toGCPPriceCatalog(), nil
+		}
+	}
+	return GCPPriceCatalog{}, fmt.Errorf("Price catalog file has no prices for region '%s' and machine family '%s'", conf.ResourceConf.Region, conf.ResourceConf.MachineFamily)
+}
+
+// AddEntry adds the given entry to the file, replacing any entry for the same region and machine family
+func (f *PriceCatalogFile) AddEntry(entry PriceCatalogEntry) {
+	if f.Version == "" {
+		f.Version = PriceCatalogFileVersion
+	}
+	for i, e := range f.Prices {
+		if strings.EqualFold(e.Region, entry.Region) && strings.EqualFold(string(e.MachineFamily), string(entry.MachineFamily)) {
+			f.Prices[i] = entry
+			return
+		}
+	}
+	f.Prices = append(f.Prices, entry)
+}
+
+// NewPriceCatalogEntry creates the file entry representing the given price catalog for the configured region and machine family
+func NewPriceCatalogEntry(pc GCPPriceCatalog, conf CostimatorConfig) PriceCatalogEntry {
+	conf = populateConfigNotProvided(conf)
+	diskPrices := []DiskPrice{}
+	for disk, price := range pc.diskPrices {
+		if disk != defaultPersistentDisk {
+			diskPrices = append(diskPrices, DiskPrice{DiskType: disk.DiskType, Replication: disk.Replication, GiBMonthlyPrice: price * bytesInGiB})
+		}
+	}
+	sort.Slice(diskPrices, func(i, j int) bool {
+		return diskPrices[i].disk().String() < diskPrices[j].disk().String()
+	})
+	autopilotPrices := []AutopilotPrice{}
+	for key, price := range pc.autopilotPrices {
+		autopilotPrices = append(autopilotPrices, AutopilotPrice{
+			ComputeClass:                    key.class,
+			Spot:                            key.spot,
+			CPUMonthlyPrice:                 price.cpuPrice,
+			MemoryGiBMonthlyPrice:           price.memoryPrice * bytesInGiB,
+			EphemeralStorageGiBMonthlyPrice: price.ephemeralStoragePrice * bytesInGiB,
+		})
+	}
+	sort.Slice(autopilotPrices, func(i, j int) bool {
+		if autopilotPrices[i].ComputeClass != autopilotPrices[j].ComputeClass {
+			return autopilotPrices[i].ComputeClass < autopilotPrices[j].ComputeClass
+		}
+		return !autopilotPrices[i].Spot && autopilotPrices[j].Spot
+	})
+	gpuPrices := []GPUPrice{}
+	for _, spot := range []bool{false, true} {
+		prices := pc.gpuPrices
+		if spot {
+			prices = pc.spotGPUPrices
+		}
+		for accelerator, price := range prices {
+			gpuPrices = append(gpuPrices, GPUPrice{Accelerator: accelerator, Spot: spot, MonthlyPrice: price})
+		}
+	}
+	sort.Slice(gpuPrices, func(i, j int) bool {
+		if gpuPrices[i].Accelerator != gpuPrices[j].Accelerator {
+			return gpuPrices[i].Accelerator < gpuPrices[j].Accelerator
+		}
+		return !gpuPrices[i].Spot && gpuPrices[j].Spot
+	})
+	return PriceCatalogEntry{
+		Region:                      conf.ResourceConf.Region,
+		MachineFamily:               conf.ResourceConf.MachineFamily,
+		CPUMonthlyPrice:             pc.cpuPrice,
+		MemoryGiBMonthlyPrice:       pc.memoryPrice * bytesInGiB,
+		SpotCPUMonthlyPrice:         pc.spotCPUPrice,
+		SpotMemoryGiBMonthlyPrice:   pc.spotMemoryPrice * bytesInGiB,
+		PdStandardGiBMonthlyPrice:   pc.diskPrices[defaultPersistentDisk] * bytesInGiB,
+		DiskPrices:                  diskPrices,
+		AutopilotPrices:             autopilotPrices,
+		GPUPrices:                   gpuPrices,
+		LocalSSDGiBMonthlyPrice:     pc.localSSDPrice * bytesInGiB,
+		SpotLocalSSDGiBMonthlyPrice: pc.spotLocalSSDPrice * bytesInGiB,
+	}
+}
+
+func (e *PriceCatalogEntry) matches(conf CostimatorConfig) bool {
+	return strings.EqualFold(e.Region, conf.ResourceConf.Region) &&
+		strings.EqualFold(string(e.MachineFamily), string(conf.ResourceConf.MachineFamily))
+}
+
+func (e *PriceCatalogEntry) toGCPPriceCatalog() GCPPriceCatalog {
+	// catalog keeps memory and storage prices per byte
+	diskPrices := map[PersistentDisk]float32{
+		defaultPersistentDisk: e.PdStandardGiBMonthlyPrice / bytesInGiB,
+	}
+	for _, d := range e.DiskPrices {
+		diskPrices[d.disk()] = d.GiBMonthlyPrice / bytesInGiB
+	}
+	var autopilotPrices map[autopilotPriceKey]autopilotPrice
+	for _, a := range e.AutopilotPrices {
+		if autopilotPrices == nil {
+			autopilotPrices = make(map[autopilotPriceKey]autopilotPrice)
+		}
+		autopilotPrices[autopilotPriceKey{class: a.ComputeClass, spot: a.Spot}] = autopilotPrice{
+			cpuPrice:              a.CPUMonthlyPrice,
+			memoryPrice:           a.MemoryGiBMonthlyPrice / bytesInGiB,
+			ephemeralStoragePrice: a.EphemeralStorageGiBMonthlyPrice / bytesInGiB,
+		}
+	}
+	var gpuPrices, spotGPUPrices map[string]float32
+	for _, g := range e.GPUPrices {
+		if g.Spot {
+			if spotGPUPrices == nil {
+				spotGPUPrices = make(map[string]float32)
+			}
+			spotGPUPrices[g.Accelerator] = g.MonthlyPrice
+		} else {
+			if gpuPrices == nil {
+				gpuPrices = make(map[string]float32)
+			}
+			gpuPrices[g.Accelerator] = g.MonthlyPrice
+		}
+	}
+	return GCPPriceCatalog{
+		cpuPrice:          e.CPUMonthlyPrice,
+		memoryPrice:       e.MemoryGiBMonthlyPrice / bytesInGiB,
+		spotCPUPrice:      e.SpotCPUMonthlyPrice,
+		spotMemoryPrice:   e.SpotMemoryGiBMonthlyPrice / bytesInGiB,
+		diskPrices:        diskPrices,
+		autopilotPrices:   autopilotPrices,
+		gpuPrices:         gpuPrices,
+		spotGPUPrices:     spotGPUPrices,
+		localSSDPrice:     e.LocalSSDGiBMonthlyPrice / bytesInGiB,
+		spotLocalSSDPrice: e.SpotLocalSSDGiBMonthlyPrice / bytesInGiB,
+	}
+}
+
+func (d *DiskPrice) disk() PersistentDisk {
+	return PersistentDisk{DiskType: d.DiskType, Replication: d.Replication}.withDefaults()
+}
@@ -0,0 +1,134 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestPriceCatalogFromYAMLFile(t *testing.T) {
+	data, err := ioutil.ReadFile("./testdata/prices/catalog.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := CostimatorConfig{ResourceConf: ResourceConfig{MachineFamily: N1, Region: "us-east1"}}
+	pc, err := NewGCPPriceCatalogFromFile(data, conf)
+	if err != nil {
+		t.Fatalf("Error loading price catalog file: %+v", err)
+	}
+	if got, want := pc.CPUMonthlyPrice(), float32(23.55); got != want {
+		t.Errorf("CPUMonthlyPrice is %v, want %v", got, want)
+	}
+	if got, want := pc.MemoryMonthlyPrice()*bytesInGiB, float32(3.16); got != want {
+		t.Errorf("MemoryMonthlyPrice per GiB is %v, want %v", got, want)
+	}
+	if got, want := pc.PdStandardMonthlyPrice()*bytesInGiB, float32(0.04); got != want {
+		t.Errorf("PdStandardMonthlyPrice per GiB is %v, want %v", got, want)
+	}
+	spot, found := pc.SpotResourcePrice()
+	if !found || spot.CPUMonthlyPrice() != 7.07 || spot.MemoryMonthlyPrice()*bytesInGiB != 0.95 {
+		t.Errorf("Spot VM prices should have been loaded, found: %v, got %+v", found, spot)
+	}
+	ssd := PersistentDisk{DiskType: PdSSD, Replication: Regional}
+	if got, want := pc.StorageMonthlyPrice(ssd)*bytesInGiB, float32(0.34); got != want {
+		t.Errorf("StorageMonthlyPrice for %s per GiB is %v, want %v", ssd, got, want)
+	}
+	autopilot, found := pc.AutopilotResourcePrice(GeneralPurpose, true)
+	if !found || autopilot.CPUMonthlyPrice() != 10.04 || autopilot.(EphemeralStoragePrice).EphemeralStorageMonthlyPrice()*bytesInGiB != 0.04 {
+		t.Errorf("Autopilot Spot prices should have been loaded, found: %v, got %+v", found, autopilot)
+	}
+	if _, found := pc.AutopilotResourcePrice(Balanced, false); found {
+		t.Errorf("Autopilot Balanced prices should not have been found")
+	}
+	if got, found := pc.GPUMonthlyPrice("nvidia-tesla-t4"); !found || got != 260.4 {
+		t.Errorf("GPU prices should have been loaded, found: %v, got %v", found, got)
+	}
+	if got, found := spot.(AcceleratorPrice).GPUMonthlyPrice("nvidia-tesla-t4"); !found || got != 81.84 {
+		t.Errorf("Spot GPU prices should have been loaded, found: %v, got %v", found, got)
+	}
+	if got, found := pc.LocalSSDMonthlyPrice(); !found || got*bytesInGiB != 0.08 {
+		t.Errorf("Local SSD prices should have been loaded, found: %v, got %v", found, got)
+	}
+	if got, found := spot.(NodeStoragePrice).LocalSSDMonthlyPrice(); !found || got*bytesInGiB != 0.048 {
+		t.Errorf("Spot local SSD prices should have been loaded, found: %v, got %v", found, got)
+	}
+	if _, found := pc.GPUMonthlyPrice("nvidia-l4"); found {
+		t.Errorf("nvidia-l4 GPU prices should not have been found")
+	}
+}
+
+func TestPriceCatalogFromJSONFileUsesDefaults(t *testing.T) {
+	data := `{"version": "v1", "prices": [{"region": "US-CENTRAL1", "machineFamily": "E2", "cpuMonthlyPrice": 16.22, "memoryGiBMonthlyPrice": 2.17, "pdStandardGiBMonthlyPrice": 0.08}]}`
+
+	pc, err := NewGCPPriceCatalogFromFile([]byte(data), CostimatorConfig{})
+	if err != nil {
+		t.Fatalf("Error loading price catalog file: %+v", err)
+	}
+	if got, want := pc.CPUMonthlyPrice(), float32(16.22); got != want {
+		t.Errorf("CPUMonthlyPrice is %v, want %v", got, want)
+	}
+}
+
+func TestPriceCatalogFileMissingEntry(t *testing.T) {
+	data, err := ioutil.ReadFile("./testdata/prices/catalog.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := CostimatorConfig{ResourceConf: ResourceConfig{MachineFamily: N2, Region: "europe-west1"}}
+	_, err = NewGCPPriceCatalogFromFile(data, conf)
+	if err == nil || !strings.Contains(err.Error(), "europe-west1") {
+		t.Errorf("Should have returned a missing entry error, but returned '%+v'", err)
+	}
+}
+
+func TestPriceCatalogFileWrongVersion(t *testing.T) {
+	data := `version: v99
+prices: []`
+
+	_, err := ReadPriceCatalogFile([]byte(data))
+	if err == nil || !strings.Contains(err.Error(), "v99") {
+		t.Errorf("Should have returned a version error, but returned '%+v'", err)
+	}
+}
+
+func TestPriceCatalogFileAddEntry(t *testing.T) {
+	ssd := PersistentDisk{DiskType: PdSSD, Replication: Zonal}
+	pc := GCPPriceCatalog{cpuPrice: 10, memoryPrice: 1.0 / bytesInGiB, diskPrices: map[PersistentDisk]float32{defaultPersistentDisk: 0.5 / bytesInGiB, ssd: 2.0 / bytesInGiB}}
+	conf := CostimatorConfig{ResourceConf: ResourceConfig{MachineFamily: N2, Region: "europe-west1"}}
+
+	f := PriceCatalogFile{}
+	f.AddEntry(NewPriceCatalogEntry(pc, ConfigDefaults()))
+	f.AddEntry(NewPriceCatalogEntry(pc, conf))
+	pc.cpuPrice = 20
+	f.AddEntry(NewPriceCatalogEntry(pc, conf))
+
+	if f.Version != PriceCatalogFileVersion || len(f.Prices) != 2 {
+		t.Fatalf("Expected 2 entries with version %s, got %+v", PriceCatalogFileVersion, f)
+	}
+	got, err := f.PriceCatalog(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.CPUMonthlyPrice() != 20 || got.MemoryMonthlyPrice()*bytesInGiB != 1 || got.PdStandardMonthlyPrice()*bytesInGiB != 0.5 {
+		t.Errorf("Entry should have been replaced, got %+v", got)
+	}
+	if got.StorageMonthlyPrice(ssd)*bytesInGiB != 2 {
+		t.Errorf("Disk prices should have been kept, got %+v", got)
+	}
+}
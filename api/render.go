@@ -0,0 +1,150 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"sigs.k8s.io/yaml"
+)
+
+// RenderInput is the estimate or diff data model available to renderers and user-supplied templates
+type RenderInput struct {
+	Report  Report         // versioned data model, the same written by the json and yaml renderers
+	Cost    Cost           // current k8s manifests cost
+	Diff    *DiffCost      // current vs previous k8s manifests. nil for estimate runs
+	Policy  *PolicyResult  // cost policy evaluation. nil when no policy is provided
+	Budgets []BudgetResult // current k8s manifests cost per budget. Empty when no budget is configured
+}
+
+// IsDiff tells whether the input compares current against previous k8s manifests
+func (in *RenderInput) IsDiff() bool {
+	return in.Diff != nil
+}
+
+// Renderer converts estimate or diff results into an output format
+type Renderer interface {
+	Render(in RenderInput) ([]byte, error)
+}
+
+// RendererFunc adapts a function to the Renderer interface
+type RendererFunc func(in RenderInput) ([]byte, error)
+
+// Render calls f(in)
+func (f RendererFunc) Render(in RenderInput) ([]byte, error) {
+	return f(in)
+}
+
+var renderers = map[string]Renderer{
+	"markdown": RendererFunc(renderMarkdown),
+	"json":     RendererFunc(renderJSON),
+	"yaml":     RendererFunc(renderYAML),
+	"csv":      RendererFunc(renderCSV),
+}
+
+// RegisterRenderer makes a renderer available by name (case insensitive), replacing any renderer with the same name
+func RegisterRenderer(name string, r Renderer) {
+	renderers[strings.ToLower(name)] = r
+}
+
+// GetRenderer returns the renderer registered with the given name (case insensitive)
+func GetRenderer(name string) (Renderer, error) {
+	r, found := renderers[strings.ToLower(name)]
+	if !found {
+		return nil, fmt.Errorf("Renderer '%s' not supported. Supported renderers: %s", name, strings.Join(RendererNames(), ", "))
+	}
+	return r, nil
+}
+
+// RendererNames returns the names of all registered renderers, sorted
+func RendererNames() []string {
+	names := []string{}
+	for name := range renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func renderMarkdown(in RenderInput) ([]byte, error) {
+	markdown := in.Cost.ToMarkdown()
+	if in.IsDiff() {
+		markdown = in.Diff.ToMarkdown()
+	}
+	if in.Policy != nil {
+		markdown = fmt.Sprintf("%s\n\n## Cost Policy\n\n%s", markdown, in.Policy.ToMarkdown())
+	}
+	if len(in.Budgets) > 0 {
+		markdown = fmt.Sprintf("%s\n\n## Budgets\n\n%s", markdown, budgetsToMarkdown(in.Budgets))
+	}
+	return []byte(markdown), nil
+}
+
+func renderJSON(in RenderInput) ([]byte, error) {
+	return in.Report.ToJSON()
+}
+
+func renderYAML(in RenderInput) ([]byte, error) {
+	return in.Report.ToYAML()
+}
+
+// renderCSV renders the current cost of each object. Use DiffCost.ToCSV for the objects changed in diff runs
+func renderCSV(in RenderInput) ([]byte, error) {
+	data, err := in.Cost.ToCSV()
+	return []byte(data), err
+}
+
+// templateFuncs are available to user-supplied templates, besides Go text/template builtin functions
+var templateFuncs = template.FuncMap{
+	"currency":     currency,
+	"currencyDiff": currencyDiff,
+	"percDiff":     percDiff,
+	"cpu":          formatCPU,
+	"bytes":        formatBytes,
+	"join":         strings.Join,
+	"upper":        strings.ToUpper,
+	"lower":        strings.ToLower,
+	"replace":      strings.ReplaceAll,
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"yaml": func(v interface{}) (string, error) {
+		data, err := yaml.Marshal(v)
+		return string(data), err
+	},
+}
+
+// NewTemplateRenderer parses a Go text/template rendering RenderInput (eg. {{ currency .Report.Estimate.Total.MinRequested }})
+// Besides builtin functions, templates can use: currency, currencyDiff, percDiff, cpu, bytes, join, upper, lower, replace, json and yaml
+func NewTemplateRenderer(name, text string) (Renderer, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse template '%s': %+v", name, err)
+	}
+	return RendererFunc(func(in RenderInput) ([]byte, error) {
+		out := &strings.Builder{}
+		// a pointer allows templates to call pointer methods, such as {{ .Cost.ToMarkdown }}
+		err := tmpl.Execute(out, &in)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to execute template '%s': %+v", name, err)
+		}
+		return []byte(out.String()), nil
+	}), nil
+}
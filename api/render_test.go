@@ -0,0 +1,129 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestGetRenderer(t *testing.T) {
+	for _, name := range []string{"markdown", "JSON", "Yaml", "csv"} {
+		if _, err := GetRenderer(name); err != nil {
+			t.Errorf("Renderer '%s' should be registered, got: %+v", name, err)
+		}
+	}
+	_, err := GetRenderer("html")
+	if err == nil || !strings.Contains(err.Error(), "csv, json, markdown, yaml") {
+		t.Errorf("Should have returned a not supported error listing renderers, but returned '%+v'", err)
+	}
+
+	RegisterRenderer("HTML", RendererFunc(func(in RenderInput) ([]byte, error) {
+		return []byte("<p>html</p>"), nil
+	}))
+	defer delete(renderers, "html")
+	r, err := GetRenderer("html")
+	if err != nil {
+		t.Fatalf("Registered renderer should have been found: %+v", err)
+	}
+	if got, _ := r.Render(RenderInput{}); string(got) != "<p>html</p>" {
+		t.Errorf("Registered renderer should have been used, got '%s'", got)
+	}
+}
+
+func TestMarkdownRenderer(t *testing.T) {
+	cost := reportTestCost(1)
+	r, _ := GetRenderer("markdown")
+	got, err := r.Render(RenderInput{Cost: cost})
+	if err != nil || string(got) != cost.ToMarkdown() {
+		t.Errorf("Markdown renderer should render the cost, got '%s', err: %+v", got, err)
+	}
+
+	diff := cost.Subtract(reportTestCost(2))
+	got, err = r.Render(RenderInput{Cost: cost, Diff: &diff})
+	if err != nil || !strings.Contains(string(got), "## Previous Monthly Cost") {
+		t.Errorf("Markdown renderer should render the diff, got '%s', err: %+v", got, err)
+	}
+}
+
+func TestTemplateRenderer(t *testing.T) {
+	tmpl := `{{ .Report.Type }} {{ currency .Report.Estimate.Total.MinRequested }}
+{{- range .Report.Estimate.Objects }} {{ .Key }}={{ .Replicas }}{{ end }}
+{{- if .IsDiff }} diff={{ .Report.Diff.Total.DiffValue.MinRequested }}{{ end }}`
+	r, err := NewTemplateRenderer("test", tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cost := reportTestCost(2)
+	got, err := r.Render(RenderInput{Report: NewEstimateReport(cost, CostimatorConfig{}, PriceCatalogFile{}, "v1"), Cost: cost})
+	expected := "estimate $45.00 default/Deployment/backend=2 shop/Deployment/frontend=0"
+	if err != nil || string(got) != expected {
+		t.Errorf("Expected '%s', got '%s', err: %+v", expected, got, err)
+	}
+
+	cost.MonthlyRanges[0].MinRequested = 50
+	diff := cost.Subtract(reportTestCost(1))
+	got, err = r.Render(RenderInput{Report: NewDiffReport(diff, CostimatorConfig{}, PriceCatalogFile{}, "v1"), Cost: cost, Diff: &diff})
+	if err != nil || !strings.HasSuffix(string(got), "diff=5") {
+		t.Errorf("Template should render the diff, got '%s', err: %+v", got, err)
+	}
+}
+
+func TestTemplateRendererPointerMethods(t *testing.T) {
+	r, err := NewTemplateRenderer("test", `{{ .Cost.ToMarkdown }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cost := reportTestCost(1)
+	got, err := r.Render(RenderInput{Cost: cost})
+	if err != nil || string(got) != cost.ToMarkdown() {
+		t.Errorf("Template should be able to call Cost.ToMarkdown, got '%s', err: %+v", got, err)
+	}
+}
+
+func TestTemplateRendererErrors(t *testing.T) {
+	_, err := NewTemplateRenderer("test", `{{ .Report `)
+	if err == nil || !strings.HasPrefix(err.Error(), "Unable to parse template 'test'") {
+		t.Errorf("Should have returned a parse error, but returned '%+v'", err)
+	}
+
+	r, err := NewTemplateRenderer("test", `{{ .Report.Unknown }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.Render(RenderInput{})
+	if err == nil || !strings.HasPrefix(err.Error(), "Unable to execute template 'test'") {
+		t.Errorf("Should have returned an execution error, but returned '%+v'", err)
+	}
+}
+
+func TestExampleTemplate(t *testing.T) {
+	data, err := ioutil.ReadFile("../samples/k8s-cost-estimator-local/example-template.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewTemplateRenderer("example", string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cost := reportTestCost(2)
+	diff := cost.Subtract(reportTestCost(1))
+	got, err := r.Render(RenderInput{Report: NewDiffReport(diff, ConfigDefaults(), PriceCatalogFile{}, "v1"), Cost: cost, Diff: &diff})
+	if err != nil || !strings.Contains(string(got), "default/Deployment/backend changed") {
+		t.Errorf("Example template should render the diff, got '%s', err: %+v", got, err)
+	}
+}
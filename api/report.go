@@ -0,0 +1,151 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ReportSchemaVersion is the current version of the JSON/YAML report schema
+// It only changes when fields are renamed or removed. New fields may be added within the same version
+const ReportSchemaVersion = "v1"
+
+// ReportType tells whether the report holds a single estimate or the comparison of two estimates
+type ReportType string
+
+const (
+	// EstimateReportType report of a single k8s manifests path
+	EstimateReportType ReportType = "estimate"
+	// DiffReportType report comparing current against previous k8s manifests (see 'k8s-prev' parameter)
+	DiffReportType ReportType = "diff"
+)
+
+// Report is the machine-readable representation of an estimate or diff run. It is written as JSON or YAML
+// Monetary values are monthly USD. Resource values follow Resource units (CPU in millis, memory and storage in bytes)
+type Report struct {
+	SchemaVersion string           `json:"schemaVersion"`      // see ReportSchemaVersion
+	ToolVersion   string           `json:"toolVersion"`        // k8s-cost-estimator version that produced the report
+	Type          ReportType       `json:"type"`               // estimate or diff
+	Config        CostimatorConfig `json:"config"`             // config in effect, including defaults not provided
+	Prices        PriceCatalogFile `json:"prices"`             // prices used, in the offline price catalog format (see 'price-catalog' parameter)
+	Estimate      EstimateReport   `json:"estimate"`           // current k8s manifests
+	Previous      *EstimateReport  `json:"previous,omitempty"` // previous k8s manifests. Only for diff reports
+	Diff          *DiffReport      `json:"diff,omitempty"`     // current minus previous. Only for diff reports
+	Policy        *PolicyResult    `json:"policy,omitempty"`   // cost policy evaluation. Only when a policy is provided
+	Budgets       []BudgetResult   `json:"budgets,omitempty"`  // current k8s manifests cost per budget. Only when budgets are configured
+}
+
+// EstimateReport holds the estimated cost ranges of a single k8s manifests path
+type EstimateReport struct {
+	Total            CostRange              `json:"total"`                      // sum of all kinds
+	EffectiveTotal   CostRange              `json:"effectiveTotal"`             // total minus committed and sustained use discounts
+	Kinds            []CostRange            `json:"kinds"`                      // one per k8s kind
+	Objects          []ObjectCostRange      `json:"objects"`                    // one per k8s object, sorted by namespace/kind/name
+	SpotComparisons  []SpotComparison       `json:"spotComparisons,omitempty"`  // empty when Spot VM prices are not available
+	Discounts        *Discounts             `json:"discounts,omitempty"`        // nil when there is no discount to apply
+	Nodes            []NodeEstimate         `json:"nodes,omitempty"`            // one per simulated node pool
+	GPUs             []GPUCost              `json:"gpus,omitempty"`             // GPU share of the costs above
+	EphemeralStorage []EphemeralStorageCost `json:"ephemeralStorage,omitempty"` // ephemeral storage share of the costs above
+}
+
+// DiffReport holds the difference between current and previous estimates
+type DiffReport struct {
+	Summary              string        `json:"summary"`
+	PossiblyCostIncrease bool          `json:"possiblyCostIncrease"`
+	Total                DiffCostRange `json:"total"`
+	Objects              []ObjectDiff  `json:"objects"` // only objects added, removed or changed
+}
+
+// NewEstimateReport creates the report of a single estimate
+func NewEstimateReport(cost Cost, conf CostimatorConfig, prices PriceCatalogFile, toolVersion string) Report {
+	return Report{
+		SchemaVersion: ReportSchemaVersion,
+		ToolVersion:   toolVersion,
+		Type:          EstimateReportType,
+		Config:        populateConfigNotProvided(conf),
+		Prices:        prices,
+		Estimate:      newEstimateReport(cost),
+	}
+}
+
+// NewDiffReport creates the report comparing current against previous estimates
+func NewDiffReport(diff DiffCost, conf CostimatorConfig, prices PriceCatalogFile, toolVersion string) Report {
+	report := NewEstimateReport(diff.CostCurr, conf, prices, toolVersion)
+	report.Type = DiffReportType
+	previous := newEstimateReport(diff.CostPrev)
+	report.Previous = &previous
+	report.Diff = &DiffReport{
+		Summary:              diff.Summary,
+		PossiblyCostIncrease: diff.hascostIncd,
+		Total:                diff.MonthlyDiffRange,
+		Objects:              diff.ObjectDiffs,
+	}
+	if report.Diff.Objects == nil {
+		report.Diff.Objects = []ObjectDiff{}
+	}
+	return report
+}
+
+func newEstimateReport(cost Cost) EstimateReport {
+	kinds := cost.MonthlyRanges
+	if kinds == nil {
+		kinds = []CostRange{}
+	}
+	return EstimateReport{
+		Total:            cost.MonthlyTotal(),
+		EffectiveTotal:   cost.MonthlyEffectiveTotal(),
+		Kinds:            kinds,
+		Objects:          cost.sortedObjectRanges(),
+		SpotComparisons:  cost.SpotComparisons,
+		Discounts:        cost.Discounts,
+		Nodes:            cost.Nodes,
+		GPUs:             cost.GPUs,
+		EphemeralStorage: cost.EphemeralStorage,
+	}
+}
+
+// ToJSON returns the indented JSON representation of the report
+func (r *Report) ToJSON() ([]byte, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("Unable to marshal report to JSON: %+v", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// ToYAML returns the YAML representation of the report. Field names are the same as in JSON
+func (r *Report) ToYAML() ([]byte, error) {
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to marshal report to YAML: %+v", err)
+	}
+	return data, nil
+}
+
+// ReadReport decodes a JSON or YAML report, checking its schema version
+func ReadReport(data []byte) (Report, error) {
+	r := Report{}
+	err := yaml.Unmarshal(data, &r)
+	if err != nil {
+		return Report{}, fmt.Errorf("Unable to decode report: %+v", err)
+	}
+	if r.SchemaVersion != ReportSchemaVersion {
+		return Report{}, fmt.Errorf("Report schema version '%s' not supported. Expected '%s'", r.SchemaVersion, ReportSchemaVersion)
+	}
+	return r, nil
+}
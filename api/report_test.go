@@ -0,0 +1,144 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func reportTestCost(replicas int32) Cost {
+	object := newObjectCostRange("apps/v1|Deployment|default|backend", CostRange{Kind: DeploymentKind, MinRequested: 15, MaxRequested: 15, HPABuffer: 15, MinLimited: 30, MaxLimited: 30})
+	object.Replicas = replicas
+	other := newObjectCostRange("apps/v1|Deployment|shop|frontend", CostRange{Kind: DeploymentKind, MinRequested: 30, MaxRequested: 30, HPABuffer: 30, MinLimited: 30, MaxLimited: 30})
+	return Cost{
+		MonthlyRanges: []CostRange{
+			{Kind: DeploymentKind, MinRequested: 45, MaxRequested: 45, HPABuffer: 45, MinLimited: 60, MaxLimited: 60},
+		},
+		MonthlyObjectRanges: []ObjectCostRange{other, object},
+	}
+}
+
+func TestNewEstimateReport(t *testing.T) {
+	prices := PriceCatalogFile{}
+	prices.AddEntry(PriceCatalogEntry{Region: "us-central1", MachineFamily: E2, CPUMonthlyPrice: 16})
+	report := NewEstimateReport(reportTestCost(1), CostimatorConfig{}, prices, "v1.2.3")
+
+	if report.SchemaVersion != ReportSchemaVersion || report.ToolVersion != "v1.2.3" || report.Type != EstimateReportType {
+		t.Errorf("Unexpected report header, got schema: %s, tool: %s, type: %s", report.SchemaVersion, report.ToolVersion, report.Type)
+	}
+	if got := report.Config.ResourceConf.MachineFamily; got != E2 {
+		t.Errorf("Config in effect should include defaults, got machine family '%s'", got)
+	}
+	if got := report.Estimate.Total.MinRequested; got != 45 {
+		t.Errorf("Total MinRequested should be 45, got %v", got)
+	}
+	if got := report.Estimate.Objects[0].Name; got != "backend" {
+		t.Errorf("Objects should be sorted by namespace/kind/name, got '%s' first", got)
+	}
+	if report.Previous != nil || report.Diff != nil {
+		t.Errorf("Estimate report should not have previous and diff sections")
+	}
+
+	for _, format := range []string{"json", "yaml"} {
+		var data []byte
+		var err error
+		if format == "json" {
+			data, err = report.ToJSON()
+		} else {
+			data, err = report.ToYAML()
+		}
+		if err != nil {
+			t.Fatalf("Error marshaling %s report: %+v", format, err)
+		}
+		for _, field := range []string{"schemaVersion", "toolVersion", "resourceConf", "cpuMonthlyPrice", "monthlyRange", "effectiveTotal"} {
+			if !strings.Contains(string(data), field) {
+				t.Errorf("%s report should contain field '%s', got:\n%s", format, field, data)
+			}
+		}
+		got, err := ReadReport(data)
+		if err != nil {
+			t.Fatalf("Error reading %s report: %+v", format, err)
+		}
+		if !cmp.Equal(got, report) {
+			t.Errorf("%s report should be read back unchanged, diff: %s", format, cmp.Diff(report, got))
+		}
+	}
+}
+
+func TestNewDiffReport(t *testing.T) {
+	curr := reportTestCost(2)
+	curr.MonthlyRanges[0].MinRequested = 60
+	diff := curr.Subtract(reportTestCost(1))
+	report := NewDiffReport(diff, CostimatorConfig{}, PriceCatalogFile{}, "v1.2.3")
+
+	if report.Type != DiffReportType || report.Previous == nil || report.Diff == nil {
+		t.Fatalf("Diff report should have previous and diff sections, got: %+v", report)
+	}
+	if got := report.Diff.Total.DiffValue.MinRequested; got != 15 {
+		t.Errorf("Diff MinRequested should be 15, got %v", got)
+	}
+	if !report.Diff.PossiblyCostIncrease {
+		t.Errorf("Diff should report a possible cost increase")
+	}
+	if len(report.Diff.Objects) != 1 || report.Diff.Objects[0].ChangedFields[0].Field != "replicas" {
+		t.Errorf("Only backend replicas should have changed, got: %+v", report.Diff.Objects)
+	}
+}
+
+func TestNewDiffReportZeroCost(t *testing.T) {
+	tests := map[string]struct {
+		curr, prev     Cost
+		wantPercentage float64
+		wantIncrease   bool
+		wantMarkdown   string
+	}{
+		"all workloads removed": {curr: Cost{}, prev: reportTestCost(1), wantPercentage: -100, wantIncrease: false, wantMarkdown: "-100.00%"},
+		"workloads added":       {curr: reportTestCost(1), prev: Cost{}, wantPercentage: 100, wantIncrease: true, wantMarkdown: "**new (&#8593;)**"},
+	}
+	for name, tt := range tests {
+		diff := tt.curr.Subtract(tt.prev)
+		report := NewDiffReport(diff, CostimatorConfig{}, PriceCatalogFile{}, "v1.2.3")
+
+		if got := report.Diff.Total.DiffPercentage.MinRequested; got != tt.wantPercentage {
+			t.Errorf("%s: diff percentage should be %v, got %v", name, tt.wantPercentage, got)
+		}
+		if got := report.Diff.PossiblyCostIncrease; got != tt.wantIncrease {
+			t.Errorf("%s: possibly cost increase should be %t, got %t. Summary: %s", name, tt.wantIncrease, got, report.Diff.Summary)
+		}
+		if _, err := report.ToJSON(); err != nil {
+			t.Errorf("%s: report should be marshaled to JSON, got: %+v", name, err)
+		}
+		if _, err := report.ToYAML(); err != nil {
+			t.Errorf("%s: report should be marshaled to YAML, got: %+v", name, err)
+		}
+		md := diff.ToMarkdown()
+		if strings.Contains(md, "Inf") || strings.Contains(md, "NaN") {
+			t.Errorf("%s: markdown should not have infinite percentages, got:\n%s", name, md)
+		}
+		if !strings.Contains(md, tt.wantMarkdown) {
+			t.Errorf("%s: markdown should have percentage '%s', got:\n%s", name, tt.wantMarkdown, md)
+		}
+	}
+}
+
+func TestReadReportUnsupportedVersion(t *testing.T) {
+	_, err := ReadReport([]byte(`{"schemaVersion": "v0"}`))
+	if err == nil || !strings.Contains(err.Error(), "'v0' not supported") {
+		t.Errorf("Should have returned a schema version error, but returned '%+v'", err)
+	}
+}
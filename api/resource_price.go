@@ -17,6 +17,7 @@ package api
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	billing "cloud.google.com/go/billing/apiv1"
@@ -29,7 +30,12 @@ var cpuPrefixes = map[MachineFamily]string{
 	N1:  "N1 Predefined Instance Core",
 	N2:  "N2 Instance Core",
 	E2:  "E2 Instance Core",
-	N2D: "N2D AMD Custom Instance Core",
+	N2D: "N2D AMD Instance Core",
+	C2:  "Compute optimized Core",
+	C2D: "C2D AMD Instance Core",
+	T2D: "T2D AMD Instance Core",
+	M1:  "Memory-optimized Instance Core",
+	A2:  "A2 Instance Core",
 }
 
 var memoryPrefixes = map[MachineFamily]string{
@@ -37,13 +43,66 @@ var memoryPrefixes = map[MachineFamily]string{
 	N2:  "N2 Instance Ram",
 	E2:  "E2 Instance Ram",
 	N2D: "N2D AMD Instance Ram",
+	C2:  "Compute optimized Ram",
+	C2D: "C2D AMD Instance Ram",
+	T2D: "T2D AMD Instance Ram",
+	M1:  "Memory-optimized Instance Ram",
+	A2:  "A2 Instance Ram",
 }
 
-var pdStandardPrefix = "Regional Storage PD Capacity"
+// spotPrefix is prepended to on-demand descriptions in Spot VM SKUs
+const spotPrefix = "Spot Preemptible "
+
+var diskPrefixes = map[PersistentDisk]string{
+	{DiskType: PdStandard, Replication: Zonal}:    "Storage PD Capacity",
+	{DiskType: PdStandard, Replication: Regional}: "Regional Storage PD Capacity",
+	{DiskType: PdBalanced, Replication: Zonal}:    "Balanced PD Capacity",
+	{DiskType: PdBalanced, Replication: Regional}: "Regional Balanced PD Capacity",
+	{DiskType: PdSSD, Replication: Zonal}:         "SSD backed PD Capacity",
+	{DiskType: PdSSD, Replication: Regional}:      "Regional SSD backed PD Capacity",
+	{DiskType: PdExtreme, Replication: Zonal}:     "Extreme PD Capacity",
+}
+
+// autopilotPrefixes are the Autopilot SKU description prefixes for each compute class
+var autopilotPrefixes = map[ComputeClass]string{
+	GeneralPurpose: "Autopilot ",
+	Balanced:       "Autopilot Balanced ",
+	ScaleOut:       "Autopilot Scale-Out x86 ",
+}
+
+const (
+	autopilotCPUSuffix              = "Pod mCPU Requests"
+	autopilotMemorySuffix           = "Pod Memory Requests"
+	autopilotEphemeralStorageSuffix = "Pod Ephemeral Storage Requests"
+	// autopilotSpotInfix is inserted between class prefix and resource suffix in Spot pod SKUs
+	autopilotSpotInfix = "Spot "
+)
+
+const (
+	computeEngineService    = "services/6F81-5844-456A"
+	kubernetesEngineService = "services/CCD8-9BF1-090E"
+)
+
+// PriceRequirements are the prices needed by k8s objects, besides the ones needed by the config (see Manifests.PriceRequirements)
+// GCP SKUs are listed until all needed prices are found, so k8s objects must be loaded before prices are retrieved
+type PriceRequirements struct {
+	Accelerators []string // accelerators pods run on
+	LocalSSD     bool     // pods select nodes using local SSDs as ephemeral storage
+	All          bool     // all prices offered in the region, eg. to export an offline price catalog
+}
+
+// Add returns the prices needed by both requirements
+func (r PriceRequirements) Add(o PriceRequirements) PriceRequirements {
+	return PriceRequirements{
+		Accelerators: append(append([]string{}, r.Accelerators...), o.Accelerators...),
+		LocalSSD:     r.LocalSSD || o.LocalSSD,
+		All:          r.All || o.All,
+	}
+}
 
 // NewGCPPriceCatalog creates a gcpResourcePrice struct with Monthly prices for cpu and memory
 // If credentials is nil, then the default service account will be used
-func NewGCPPriceCatalog(credentials []byte, conf CostimatorConfig) (GCPPriceCatalog, error) {
+func NewGCPPriceCatalog(credentials []byte, conf CostimatorConfig, requirements PriceRequirements) (GCPPriceCatalog, error) {
 	conf = populateConfigNotProvided(conf)
 	var client *billing.CloudCatalogClient
 	var err error
@@ -55,57 +114,275 @@ func NewGCPPriceCatalog(credentials []byte, conf CostimatorConfig) (GCPPriceCata
 	if err != nil {
 		return GCPPriceCatalog{}, err
 	}
-	return retrievePrices(client, conf)
+	return retrievePrices(client, conf, requirements)
 }
 
-func retrievePrices(client *billing.CloudCatalogClient, conf CostimatorConfig) (GCPPriceCatalog, error) {
-	skuIter, err := retrieveAllSKUs(client)
+func retrievePrices(client *billing.CloudCatalogClient, conf CostimatorConfig, requirements PriceRequirements) (GCPPriceCatalog, error) {
+	if err := validateMachineFamily("resourceConf.machineFamily", conf.ResourceConf.MachineFamily); err != nil {
+		return GCPPriceCatalog{}, err
+	}
+	skuIter, err := retrieveAllSKUs(client, computeEngineService)
+	if err != nil {
+		return GCPPriceCatalog{}, err
+	}
+	pis, err := listComputeEnginePricingInfos(skuIter, conf, newRequestedSKUs(conf, requirements))
+	if err != nil {
+		return GCPPriceCatalog{}, err
+	}
+	// not all disk types (nor Spot VMs) are available in all regions, but standard must be
+	if pis.cpu == nil || pis.memory == nil || pis.disks[defaultPersistentDisk] == nil {
+		return GCPPriceCatalog{}, fmt.Errorf("Couldn't find all Price Infos: %+v", conf)
+	}
 
-	var cpuPi, memoryPi, storagePdPi *billingpb.PricingInfo
-	for {
-		sku, err := skuIter.Next()
-		if err == iterator.Done ||
-			(cpuPi != nil && memoryPi != nil && storagePdPi != nil) {
-			break
+	cpuPrice, err := calculateMonthlyPrice(pis.cpu)
+	if err != nil {
+		return GCPPriceCatalog{}, err
+	}
+	memoryPrice, err := calculateMonthlyPrice(pis.memory)
+	if err != nil {
+		return GCPPriceCatalog{}, err
+	}
+	var spotCPUPrice, spotMemoryPrice float32
+	if pis.spotCPU != nil && pis.spotMemory != nil {
+		spotCPUPrice, err = calculateMonthlyPrice(pis.spotCPU)
+		if err != nil {
+			return GCPPriceCatalog{}, err
 		}
+		spotMemoryPrice, err = calculateMonthlyPrice(pis.spotMemory)
 		if err != nil {
 			return GCPPriceCatalog{}, err
 		}
-
-		if cpuPi == nil && matchCPU(sku, conf) {
-			cpuPi = sku.GetPricingInfo()[0]
-		} else if memoryPi == nil && matchMemory(sku, conf) {
-			memoryPi = sku.GetPricingInfo()[0]
-		} else if storagePdPi == nil && matchGCEPersistentDisk(sku, conf) {
-			storagePdPi = sku.GetPricingInfo()[0]
+	}
+	diskPrices := make(map[PersistentDisk]float32)
+	for disk, pi := range pis.disks {
+		diskPrices[disk], err = calculateMonthlyPrice(pi)
+		if err != nil {
+			return GCPPriceCatalog{}, err
 		}
 	}
-	if err == nil && (cpuPi == nil || memoryPi == nil || storagePdPi == nil) {
-		return GCPPriceCatalog{}, fmt.Errorf("Couldn't find all Price Infos: %+v", conf)
+	var localSSDPrice, spotLocalSSDPrice float32
+	if pis.localSSD != nil {
+		localSSDPrice, err = calculateMonthlyPrice(pis.localSSD)
+		if err != nil {
+			return GCPPriceCatalog{}, err
+		}
 	}
-
-	cpuPrice, err := calculateMonthlyPrice(cpuPi)
-	if err != nil {
-		return GCPPriceCatalog{}, err
+	if pis.spotLocalSSD != nil {
+		spotLocalSSDPrice, err = calculateMonthlyPrice(pis.spotLocalSSD)
+		if err != nil {
+			return GCPPriceCatalog{}, err
+		}
 	}
-	memoryPrice, err := calculateMonthlyPrice(memoryPi)
+	// not all accelerator types are available in all regions
+	gpuPrices, err := calculateMonthlyPrices(pis.gpus)
 	if err != nil {
 		return GCPPriceCatalog{}, err
 	}
-	pdStandardPrice, err := calculateMonthlyPrice(storagePdPi)
+	spotGPUPrices, err := calculateMonthlyPrices(pis.spotGPUs)
 	if err != nil {
 		return GCPPriceCatalog{}, err
 	}
+	var autopilotPrices map[autopilotPriceKey]autopilotPrice
+	if conf.ClusterConf.Mode == Autopilot {
+		autopilotPrices, err = retrieveAutopilotPrices(client, conf)
+		if err != nil {
+			return GCPPriceCatalog{}, err
+		}
+	}
 	return GCPPriceCatalog{
-		cpuPrice:        cpuPrice,
-		memoryPrice:     memoryPrice,
-		pdStandardPrice: pdStandardPrice}, nil
+		cpuPrice:          cpuPrice,
+		memoryPrice:       memoryPrice,
+		spotCPUPrice:      spotCPUPrice,
+		spotMemoryPrice:   spotMemoryPrice,
+		diskPrices:        diskPrices,
+		autopilotPrices:   autopilotPrices,
+		gpuPrices:         gpuPrices,
+		spotGPUPrices:     spotGPUPrices,
+		localSSDPrice:     localSSDPrice,
+		spotLocalSSDPrice: spotLocalSSDPrice}, nil
+}
+
+// requestedSKUs are the Compute Engine SKUs needed by the config and the k8s objects. The SKU listing stops as soon as they are
+// found, so prices don't depend on the order SKUs are listed in. Spot VM prices are always requested, since any pod can select
+// Spot nodes and on-demand workloads are compared with Spot VMs. SKUs not offered in the region make the whole list be paged through
+type requestedSKUs struct {
+	disks    []PersistentDisk // GKE default and configured StorageClasses, and node boot disks
+	localSSD bool             // on-demand and Spot local SSDs, used by a node pool or selected by pods
+	gpus     []string         // on-demand and Spot accelerators of the node pools and pods, and the default accelerator
+	all      bool             // never found, so all SKUs are listed
+}
+
+func newRequestedSKUs(conf CostimatorConfig, requirements PriceRequirements) requestedSKUs {
+	r := requestedSKUs{disks: []PersistentDisk{bootDisk}, localSSD: requirements.LocalSSD, all: requirements.All}
+	for _, storageClasses := range [][]StorageClassConfig{gkeStorageClasses, conf.ClusterConf.StorageClasses} {
+		for _, sc := range storageClasses {
+			// disks without SKU (eg. regional pd-extreme) are priced as pd-standard
+			if disk := sc.PersistentDisk.withDefaults(); diskPrefixes[disk] != "" {
+				r.disks = append(r.disks, disk)
+			}
+		}
+	}
+	accelerators := append([]string{conf.ResourceConf.DefaultAccelerator}, requirements.Accelerators...)
+	for _, pool := range conf.ClusterConf.EffectiveNodePools() {
+		r.localSSD = r.localSSD || pool.LocalSSD
+		if accelerator, ok := pool.Labels[AcceleratorNodeLabel]; ok {
+			accelerators = append(accelerators, accelerator)
+		}
+	}
+	// accelerators without SKU are not priced
+	found := make(map[string]bool)
+	for _, accelerator := range accelerators {
+		if _, ok := gpuPrefixes[accelerator]; ok && !found[accelerator] {
+			found[accelerator] = true
+			r.gpus = append(r.gpus, accelerator)
+		}
+	}
+	sort.Strings(r.gpus)
+	return r
 }
 
-func retrieveAllSKUs(client *billing.CloudCatalogClient) (*billing.SkuIterator, error) {
+// skuIterator lists SKUs, until iterator.Done is returned
+type skuIterator interface {
+	Next() (*billingpb.Sku, error)
+}
+
+// computeEnginePricingInfos are the pricing infos of the Compute Engine SKUs found in the region
+type computeEnginePricingInfos struct {
+	cpu, memory, spotCPU, spotMemory, localSSD, spotLocalSSD *billingpb.PricingInfo
+	disks                                                    map[PersistentDisk]*billingpb.PricingInfo
+	gpus, spotGPUs                                           map[string]*billingpb.PricingInfo
+}
+
+// found tells if all requested SKUs were found
+func (p *computeEnginePricingInfos) found(requested requestedSKUs) bool {
+	if requested.all || p.cpu == nil || p.memory == nil || p.spotCPU == nil || p.spotMemory == nil ||
+		(requested.localSSD && (p.localSSD == nil || p.spotLocalSSD == nil)) {
+		return false
+	}
+	for _, disk := range requested.disks {
+		if p.disks[disk] == nil {
+			return false
+		}
+	}
+	for _, accelerator := range requested.gpus {
+		if p.gpus[accelerator] == nil || p.spotGPUs[accelerator] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// listComputeEnginePricingInfos lists SKUs until the requested ones are found. Other SKUs listed before that are kept too
+func listComputeEnginePricingInfos(skuIter skuIterator, conf CostimatorConfig, requested requestedSKUs) (computeEnginePricingInfos, error) {
+	pis := computeEnginePricingInfos{
+		disks:    make(map[PersistentDisk]*billingpb.PricingInfo),
+		gpus:     make(map[string]*billingpb.PricingInfo),
+		spotGPUs: make(map[string]*billingpb.PricingInfo),
+	}
+	for !pis.found(requested) {
+		sku, err := skuIter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return computeEnginePricingInfos{}, err
+		}
+
+		if pis.cpu == nil && matchCPU(sku, conf) {
+			pis.cpu = sku.GetPricingInfo()[0]
+		} else if pis.memory == nil && matchMemory(sku, conf) {
+			pis.memory = sku.GetPricingInfo()[0]
+		} else if pis.spotCPU == nil && matchSpotCPU(sku, conf) {
+			pis.spotCPU = sku.GetPricingInfo()[0]
+		} else if pis.spotMemory == nil && matchSpotMemory(sku, conf) {
+			pis.spotMemory = sku.GetPricingInfo()[0]
+		} else if disk, ok := matchGCEPersistentDisk(sku, conf); ok {
+			if _, found := pis.disks[disk]; !found {
+				pis.disks[disk] = sku.GetPricingInfo()[0]
+			}
+		} else if pis.localSSD == nil && matchLocalSSD(sku, conf) {
+			pis.localSSD = sku.GetPricingInfo()[0]
+		} else if pis.spotLocalSSD == nil && matchSpotLocalSSD(sku, conf) {
+			pis.spotLocalSSD = sku.GetPricingInfo()[0]
+		} else if accelerator, spot, ok := matchGPU(sku, conf); ok {
+			gpuPis := pis.gpus
+			if spot {
+				gpuPis = pis.spotGPUs
+			}
+			if _, found := gpuPis[accelerator]; !found {
+				gpuPis[accelerator] = sku.GetPricingInfo()[0]
+			}
+		}
+	}
+	return pis, nil
+}
+
+// retrieveAutopilotPrices retrieves GKE Autopilot pod prices of all compute classes, both regular and Spot
+// Not all compute classes are available in all regions, so missing ones are just left out
+func retrieveAutopilotPrices(client *billing.CloudCatalogClient, conf CostimatorConfig) (map[autopilotPriceKey]autopilotPrice, error) {
+	skuIter, err := retrieveAllSKUs(client, kubernetesEngineService)
+
+	pis := make(map[string]*billingpb.PricingInfo)
+	for {
+		sku, err := skuIter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for class := range autopilotPrefixes {
+			for _, spot := range []bool{false, true} {
+				for _, description := range autopilotDescriptions(class, spot) {
+					if _, found := pis[description]; !found && skuMatcher(sku, description, conf) {
+						pis[description] = sku.GetPricingInfo()[0]
+					}
+				}
+			}
+		}
+	}
+
+	prices := make(map[autopilotPriceKey]autopilotPrice)
+	for class := range autopilotPrefixes {
+		for _, spot := range []bool{false, true} {
+			descriptions := autopilotDescriptions(class, spot)
+			cpuPi, memoryPi, storagePi := pis[descriptions[0]], pis[descriptions[1]], pis[descriptions[2]]
+			if cpuPi == nil || memoryPi == nil || storagePi == nil {
+				continue
+			}
+			price := autopilotPrice{}
+			if price.cpuPrice, err = calculateMonthlyPrice(cpuPi); err != nil {
+				return nil, err
+			}
+			if price.memoryPrice, err = calculateMonthlyPrice(memoryPi); err != nil {
+				return nil, err
+			}
+			if price.ephemeralStoragePrice, err = calculateMonthlyPrice(storagePi); err != nil {
+				return nil, err
+			}
+			prices[autopilotPriceKey{class: class, spot: spot}] = price
+		}
+	}
+	return prices, nil
+}
+
+// autopilotDescriptions returns CPU, Memory and Ephemeral Storage SKU description prefixes
+func autopilotDescriptions(class ComputeClass, spot bool) []string {
+	prefix := autopilotPrefixes[class]
+	if spot {
+		prefix = prefix + autopilotSpotInfix
+	}
+	return []string{
+		prefix + autopilotCPUSuffix,
+		prefix + autopilotMemorySuffix,
+		prefix + autopilotEphemeralStorageSuffix,
+	}
+}
+
+func retrieveAllSKUs(client *billing.CloudCatalogClient, service string) (*billing.SkuIterator, error) {
 	ctx := context.Background()
 	req := &billingpb.ListSkusRequest{
-		Parent: "services/6F81-5844-456A",
+		Parent: service,
 	}
 	return client.ListSkus(ctx, req), nil
 }
@@ -120,8 +397,43 @@ func matchMemory(sku *billingpb.Sku, conf CostimatorConfig) bool {
 	return skuMatcher(sku, prefix, conf)
 }
 
-func matchGCEPersistentDisk(sku *billingpb.Sku, conf CostimatorConfig) bool {
-	return skuMatcher(sku, pdStandardPrefix, conf)
+func matchSpotCPU(sku *billingpb.Sku, conf CostimatorConfig) bool {
+	prefix, _ := cpuPrefixes[conf.ResourceConf.MachineFamily]
+	return skuMatcher(sku, spotPrefix+prefix, conf)
+}
+
+func matchSpotMemory(sku *billingpb.Sku, conf CostimatorConfig) bool {
+	prefix, _ := memoryPrefixes[conf.ResourceConf.MachineFamily]
+	return skuMatcher(sku, spotPrefix+prefix, conf)
+}
+
+func matchGCEPersistentDisk(sku *billingpb.Sku, conf CostimatorConfig) (PersistentDisk, bool) {
+	for disk, prefix := range diskPrefixes {
+		if skuMatcher(sku, prefix, conf) {
+			return disk, true
+		}
+	}
+	return PersistentDisk{}, false
+}
+
+// matchLocalSSD matches on-demand local SSDs only, as all local SSD SKUs share the same prefix
+func matchLocalSSD(sku *billingpb.Sku, conf CostimatorConfig) bool {
+	return skuMatcher(sku, localSSDPrefix, conf) && !strings.Contains(sku.GetDescription(), " attached to ")
+}
+
+func matchSpotLocalSSD(sku *billingpb.Sku, conf CostimatorConfig) bool {
+	return skuMatcher(sku, spotLocalSSDPrefix, conf)
+}
+
+func matchGPU(sku *billingpb.Sku, conf CostimatorConfig) (accelerator string, spot bool, ok bool) {
+	for accelerator := range gpuPrefixes {
+		for _, spot := range []bool{false, true} {
+			if skuMatcher(sku, gpuDescription(accelerator, spot), conf) {
+				return accelerator, spot, true
+			}
+		}
+	}
+	return "", false, false
 }
 
 func skuMatcher(sku *billingpb.Sku, skuPrefix string, conf CostimatorConfig) bool {
@@ -129,6 +441,22 @@ func skuMatcher(sku *billingpb.Sku, skuPrefix string, conf CostimatorConfig) boo
 		contains(sku.GetServiceRegions(), conf.ResourceConf.Region)
 }
 
+// calculateMonthlyPrices returns nil when there is no price info, so catalogs without them stay comparable
+func calculateMonthlyPrices(pis map[string]*billingpb.PricingInfo) (map[string]float32, error) {
+	if len(pis) == 0 {
+		return nil, nil
+	}
+	prices := make(map[string]float32)
+	for key, pi := range pis {
+		price, err := calculateMonthlyPrice(pi)
+		if err != nil {
+			return nil, err
+		}
+		prices[key] = price
+	}
+	return prices, nil
+}
+
 func calculateMonthlyPrice(pi *billingpb.PricingInfo) (float32, error) {
 	pe := pi.GetPricingExpression()
 	pu := pe.GetTieredRates()[0].GetUnitPrice()
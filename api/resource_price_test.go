@@ -17,6 +17,10 @@ package api
 import (
 	"io/ioutil"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/iterator"
+	billingpb "google.golang.org/genproto/googleapis/cloud/billing/v1"
 )
 
 func TestResourcePrice(t *testing.T) {
@@ -25,10 +29,94 @@ func TestResourcePrice(t *testing.T) {
 		t.Logf("No credentials found in ./testdata/credentials.json, using default service account.")
 	}
 
-	rp, err := NewGCPPriceCatalog(credentials, CostimatorConfig{})
+	rp, err := NewGCPPriceCatalog(credentials, CostimatorConfig{}, PriceRequirements{})
 	if err != nil || rp.CPUMonthlyPrice() == 0 || rp.MemoryMonthlyPrice() == 0 || rp.PdStandardMonthlyPrice() == 0 {
 		t.Errorf("Error calling GCP. Make sure you have download your service account to ./testdata/credentials.json "+
 			"or run 'gcloud auth application-default login; gcloud services enable cloudbilling.googleapis.com' prior "+
 			"executing this specific test. Note enabling billing api can take some time. Cause: %+v", err)
 	}
 }
+
+func TestNewRequestedSKUs(t *testing.T) {
+	gkeDisks := []PersistentDisk{bootDisk, {PdStandard, Zonal}, {PdBalanced, Zonal}, {PdSSD, Zonal}}
+	tests := map[string]struct {
+		conf         CostimatorConfig
+		requirements PriceRequirements
+		want         requestedSKUs
+	}{
+		"defaults":  {conf: ConfigDefaults(), want: requestedSKUs{disks: gkeDisks, gpus: []string{"nvidia-tesla-t4"}}},
+		"all SKUs":  {conf: CostimatorConfig{}, requirements: PriceRequirements{All: true}, want: requestedSKUs{disks: gkeDisks, all: true}},
+		"local SSD": {conf: CostimatorConfig{}, requirements: PriceRequirements{LocalSSD: true}, want: requestedSKUs{disks: gkeDisks, localSSD: true}},
+		"storage classes": {
+			conf: CostimatorConfig{ClusterConf: ClusterConfig{StorageClasses: []StorageClassConfig{
+				{Name: "regional", PersistentDisk: PersistentDisk{DiskType: PdSSD, Replication: Regional}},
+				{Name: "no-sku", PersistentDisk: PersistentDisk{DiskType: PdExtreme, Replication: Regional}},
+			}}},
+			want: requestedSKUs{disks: append(append([]PersistentDisk{}, gkeDisks...), PersistentDisk{PdSSD, Regional})},
+		},
+		"node pools and pod accelerators": {
+			conf: CostimatorConfig{ResourceConf: ResourceConfig{DefaultAccelerator: "nvidia-tesla-t4"}, ClusterConf: ClusterConfig{NodePools: []NodePoolConfig{
+				{Name: "default", LocalSSD: true},
+				{Name: "gpu", Spot: true, Labels: map[string]string{AcceleratorNodeLabel: "nvidia-l4"}},
+			}}},
+			requirements: PriceRequirements{Accelerators: []string{"nvidia-tesla-a100", "nvidia-l4", "unknown-gpu"}},
+			want:         requestedSKUs{disks: gkeDisks, localSSD: true, gpus: []string{"nvidia-l4", "nvidia-tesla-a100", "nvidia-tesla-t4"}},
+		},
+	}
+	for name, tt := range tests {
+		if got := newRequestedSKUs(tt.conf, tt.requirements); !cmp.Equal(got, tt.want, cmp.AllowUnexported(requestedSKUs{})) {
+			t.Errorf("%s: requested SKUs mismatch (-want +got):\n%s", name, cmp.Diff(tt.want, got, cmp.AllowUnexported(requestedSKUs{})))
+		}
+	}
+}
+
+type fakeSKUIterator struct {
+	skus []*billingpb.Sku
+	next int
+}
+
+func (f *fakeSKUIterator) Next() (*billingpb.Sku, error) {
+	if f.next == len(f.skus) {
+		return nil, iterator.Done
+	}
+	f.next++
+	return f.skus[f.next-1], nil
+}
+
+func TestListComputeEnginePricingInfos(t *testing.T) {
+	conf := populateConfigNotProvided(CostimatorConfig{})
+	sku := func(description string) *billingpb.Sku {
+		return &billingpb.Sku{Description: description, ServiceRegions: []string{conf.ResourceConf.Region}, PricingInfo: []*billingpb.PricingInfo{{}}}
+	}
+	// Spot VMs and GPUs are listed after the CPU, memory and disk SKUs
+	skus := &fakeSKUIterator{skus: []*billingpb.Sku{
+		sku(cpuPrefixes[E2]),
+		sku(memoryPrefixes[E2]),
+		sku(diskPrefixes[PersistentDisk{PdStandard, Zonal}]),
+		sku(diskPrefixes[PersistentDisk{PdBalanced, Zonal}]),
+		sku(diskPrefixes[PersistentDisk{PdSSD, Zonal}]),
+		sku(spotPrefix + cpuPrefixes[E2]),
+		sku(spotPrefix + memoryPrefixes[E2]),
+		sku(gpuDescription("nvidia-tesla-t4", false)),
+		sku(gpuDescription("nvidia-tesla-t4", true)),
+		sku(gpuDescription("nvidia-l4", true)),
+		sku(gpuDescription("nvidia-l4", false)),
+		sku(cpuPrefixes[N2]),
+	}}
+
+	pis, err := listComputeEnginePricingInfos(skus, conf, newRequestedSKUs(conf, PriceRequirements{Accelerators: []string{"nvidia-l4"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pis.spotCPU == nil || pis.spotMemory == nil {
+		t.Errorf("Spot VM prices should have been found")
+	}
+	for _, accelerator := range []string{"nvidia-tesla-t4", "nvidia-l4"} {
+		if pis.gpus[accelerator] == nil || pis.spotGPUs[accelerator] == nil {
+			t.Errorf("'%s' GPU prices should have been found", accelerator)
+		}
+	}
+	if skus.next != len(skus.skus)-1 {
+		t.Errorf("Listing should have stopped once requested SKUs were found, but %d out of %d SKUs were listed", skus.next, len(skus.skus))
+	}
+}
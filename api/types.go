@@ -17,6 +17,7 @@ package api
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/fernandorubbo/k8s-cost-estimator/util"
 	log "github.com/sirupsen/logrus"
@@ -37,10 +38,31 @@ const (
 	DaemonSetKind = "DaemonSet"
 	// VolumeClaimKind is just to avoid mispeling
 	VolumeClaimKind = "PersistentVolumeClaim"
+	// JobKind is just to avoid mispeling
+	JobKind = "Job"
+	// CronJobKind is just to avoid mispeling
+	CronJobKind = "CronJob"
+	// PodKind is just to avoid mispeling
+	PodKind = "Pod"
+	// ReplicationControllerKind is just to avoid mispeling
+	ReplicationControllerKind = "ReplicationController"
 )
 
+// RunDurationAnnotation tells how long each Job (or CronJob) pod is expected to run. Eg. '30m' or '2h'
+// If not provided, ResourceConfig.DefaultJobRunDurationInMinutes is used
+const RunDurationAnnotation = "k8s-cost-estimator/run-duration"
+
+// SpotNodeLabel is the label (and taint) GKE sets on Spot VM nodes
+const SpotNodeLabel = "cloud.google.com/gke-spot"
+
+// PreemptibleNodeLabel is the label (and taint) GKE sets on preemptible VM nodes
+const PreemptibleNodeLabel = "cloud.google.com/gke-preemptible"
+
+// hoursInMonth is the number of hours monthly prices are calculated for
+const hoursInMonth = 24 * 31
+
 // SupportedKinds groups all supported kinds
-var SupportedKinds = []string{HPAKind, DeploymentKind, ReplicaSetKind, StatefulSetKind, DaemonSetKind, VolumeClaimKind}
+var SupportedKinds = []string{HPAKind, DeploymentKind, ReplicaSetKind, StatefulSetKind, DaemonSetKind, VolumeClaimKind, JobKind, CronJobKind, PodKind, ReplicationControllerKind}
 
 // GroupVersionKind is the reprsentation of k8s type
 // This object is used to to avoid sprawl of dependent library (eg. apimachinary) across the code
@@ -59,23 +81,59 @@ type HPA struct {
 	MinReplicas         int32
 	MaxReplicas         int32
 	TargetCPUPercentage int32
+	Metrics             []HPAMetric // all metric targets, including memory and custom metrics (see bufferTarget)
 }
 
 // HorizontalScalableResource is a Horizontal Scalable Resource
-// Implemented by Deployment, ReplicaSet and StatefulSet
+// Implemented by Deployment, ReplicaSet, ReplicationController and StatefulSet
 type HorizontalScalableResource interface {
-	getContainers() []Container
+	podResource
 	getReplicas() int32
 	hasHPA() bool
 	getHPA() HPA
 }
 
+// podResource is a resource running pods. Implemented by all workloads
+type podResource interface {
+	getContainers() []Container
+	isSpot() bool
+	getComputeClass() ComputeClass
+	getNodePool() string
+	getLabels() map[string]string
+}
+
+// PodTemplate holds where workload pods run and how they are labeled. It is embedded by all workloads
+// Client doesn't need to handle different version and the complexity of k8s.io package
+type PodTemplate struct {
+	Spot         bool
+	ComputeClass ComputeClass
+	NodePool     string
+	Labels       map[string]string // object labels, completed with pod template labels
+}
+
+func (p *PodTemplate) isSpot() bool {
+	return p.Spot
+}
+
+func (p *PodTemplate) getComputeClass() ComputeClass {
+	return p.ComputeClass
+}
+
+func (p *PodTemplate) getNodePool() string {
+	return p.NodePool
+}
+
+func (p *PodTemplate) getLabels() map[string]string {
+	return p.Labels
+}
+
 // Deployment is the simplified reprsentation of k8s deployment
 // Client doesn't need to handle different version and the complexity of k8s.io package
 type Deployment struct {
 	APIVersionKindName string
 	Replicas           int32
 	Containers         []Container
+	PodTemplate
 	hpa                HPA
 }
 
@@ -83,6 +141,10 @@ func (d *Deployment) estimateCost(rp ResourcePrice) CostRange {
 	return estimateCost(DeploymentKind, d, rp)
 }
 
+func (d *Deployment) objectCostRange(cost CostRange) ObjectCostRange {
+	return newWorkloadCostRange(d.APIVersionKindName, d, cost)
+}
+
 func (d *Deployment) getKindName() string {
 	return buildKindName(d.APIVersionKindName)
 }
@@ -109,6 +171,7 @@ type ReplicaSet struct {
 	APIVersionKindName string
 	Replicas           int32
 	Containers         []Container
+	PodTemplate
 	hpa                HPA
 }
 
@@ -116,6 +179,10 @@ func (r *ReplicaSet) estimateCost(rp ResourcePrice) CostRange {
 	return estimateCost(ReplicaSetKind, r, rp)
 }
 
+func (r *ReplicaSet) objectCostRange(cost CostRange) ObjectCostRange {
+	return newWorkloadCostRange(r.APIVersionKindName, r, cost)
+}
+
 func (r *ReplicaSet) getKindName() string {
 	return buildKindName(r.APIVersionKindName)
 }
@@ -136,12 +203,51 @@ func (r *ReplicaSet) getHPA() HPA {
 	return r.hpa
 }
 
+// ReplicationController is the simplified reprsentation of k8s ReplicationController
+// Client doesn't need to handle different version and the complexity of k8s.io package
+type ReplicationController struct {
+	APIVersionKindName string
+	Replicas           int32
+	Containers         []Container
+	PodTemplate
+	hpa                HPA
+}
+
+func (r *ReplicationController) estimateCost(rp ResourcePrice) CostRange {
+	return estimateCost(ReplicationControllerKind, r, rp)
+}
+
+func (r *ReplicationController) objectCostRange(cost CostRange) ObjectCostRange {
+	return newWorkloadCostRange(r.APIVersionKindName, r, cost)
+}
+
+func (r *ReplicationController) getKindName() string {
+	return buildKindName(r.APIVersionKindName)
+}
+
+func (r *ReplicationController) getContainers() []Container {
+	return r.Containers
+}
+
+func (r *ReplicationController) getReplicas() int32 {
+	return r.Replicas
+}
+
+func (r *ReplicationController) hasHPA() bool {
+	return r.hpa.APIVersionKindName != ""
+}
+
+func (r *ReplicationController) getHPA() HPA {
+	return r.hpa
+}
+
 // StatefulSet is the simplified reprsentation of k8s StatefulSet
 // Client doesn't need to handle different version and the complexity of k8s.io package
 type StatefulSet struct {
 	APIVersionKindName string
 	Replicas           int32
 	Containers         []Container
+	PodTemplate
 	hpa                HPA
 	VolumeClaims       []*VolumeClaim
 }
@@ -150,6 +256,10 @@ func (s *StatefulSet) estimateCost(rp ResourcePrice) CostRange {
 	return estimateCost(StatefulSetKind, s, rp)
 }
 
+func (s *StatefulSet) objectCostRange(cost CostRange) ObjectCostRange {
+	return newWorkloadCostRange(s.APIVersionKindName, s, cost)
+}
+
 func (s *StatefulSet) getKindName() string {
 	return buildKindName(s.APIVersionKindName)
 }
@@ -175,42 +285,140 @@ func (s *StatefulSet) getHPA() HPA {
 type DaemonSet struct {
 	APIVersionKindName string
 	NodesCount         int32
+	MaxNodesCount      int32 // nodes needed at max HPA replicas. Only differs from NodesCount when nodes are simulated
 	Containers         []Container
+	PodTemplate
+	NodePools          []string // all node pools DaemonSet pods run on. NodesCount is the sum of their simulated nodes
+}
+
+func (d *DaemonSet) getContainers() []Container {
+	return d.Containers
+}
+
+func (d *DaemonSet) objectCostRange(cost CostRange) ObjectCostRange {
+	or := newPodsCostRange(d.APIVersionKindName, d, d.NodesCount, cost)
+	or.MaxReplicas = d.getMaxNodesCount()
+	return or
 }
 
 func (d *DaemonSet) estimateCost(rp ResourcePrice) CostRange {
 	cost := CostRange{Kind: DaemonSetKind}
-	cpuReq, cpuLim, memReq, memLim := totalContainers(d.Containers)
-
-	var cpuMonthlyPrice = float64(rp.CPUMonthlyPrice())
-	var memoryMonthlyPrice = float64(rp.MemoryMonthlyPrice())
+	requested, limited := podMonthlyCost(d.Containers, rp)
 
 	nodesCount := float64(d.NodesCount)
-	cost.MinRequested = (nodesCount * cpuReq * cpuMonthlyPrice) + (nodesCount * memReq * memoryMonthlyPrice)
-	cost.MaxRequested = cost.MinRequested
+	maxNodesCount := float64(d.getMaxNodesCount())
+	cost.MinRequested = nodesCount * requested
+	cost.MaxRequested = maxNodesCount * requested
 	cost.HPABuffer = cost.MinRequested
-	cost.MinLimited = (nodesCount * cpuLim * cpuMonthlyPrice) + (nodesCount * memLim * memoryMonthlyPrice)
-	cost.MaxLimited = cost.MinLimited
+	cost.MinLimited = nodesCount * limited
+	cost.MaxLimited = maxNodesCount * limited
 
 	return postProcessCost(cost)
 }
 
+func (d *DaemonSet) getMaxNodesCount() int32 {
+	if d.MaxNodesCount < d.NodesCount {
+		return d.NodesCount
+	}
+	return d.MaxNodesCount
+}
+
+// Pod is the simplified reprsentation of k8s Pod
+// Client doesn't need to handle different version and the complexity of k8s.io package
+// A bare Pod is never scaled, so it is always estimated as a single replica
+type Pod struct {
+	APIVersionKindName string
+	Containers         []Container
+	PodTemplate
+}
+
+func (p *Pod) estimateCost(rp ResourcePrice) CostRange {
+	return estimateCost(PodKind, p, rp)
+}
+
+func (p *Pod) objectCostRange(cost CostRange) ObjectCostRange {
+	return newWorkloadCostRange(p.APIVersionKindName, p, cost)
+}
+
+func (p *Pod) getContainers() []Container {
+	return p.Containers
+}
+
+func (p *Pod) getReplicas() int32 {
+	return 1
+}
+
+func (p *Pod) hasHPA() bool {
+	return false
+}
+
+func (p *Pod) getHPA() HPA {
+	return HPA{}
+}
+
+// Job is the simplified reprsentation of k8s Job
+// Client doesn't need to handle different version and the complexity of k8s.io package
+// A Job has no schedule, so it is priced as if it ran once every month
+type Job struct {
+	APIVersionKindName string
+	Parallelism        int32
+	Completions        int32
+	RunDuration        time.Duration
+	Containers         []Container
+	PodTemplate
+}
+
+func (j *Job) estimateCost(rp ResourcePrice) CostRange {
+	return estimateBatchCost(JobKind, j.Containers, float64(j.Completions), j.RunDuration, rp)
+}
+
+func (j *Job) getContainers() []Container {
+	return j.Containers
+}
+
+func (j *Job) objectCostRange(cost CostRange) ObjectCostRange {
+	return newPodsCostRange(j.APIVersionKindName, j, j.Parallelism, cost)
+}
+
+// CronJob is the simplified reprsentation of k8s CronJob
+// Client doesn't need to handle different version and the complexity of k8s.io package
+type CronJob struct {
+	APIVersionKindName string
+	Schedule           string
+	Suspended          bool
+	MonthlyRuns        float64 // zero when suspended
+	Parallelism        int32
+	Completions        int32
+	RunDuration        time.Duration
+	Containers         []Container
+	PodTemplate
+}
+
+func (c *CronJob) estimateCost(rp ResourcePrice) CostRange {
+	return estimateBatchCost(CronJobKind, c.Containers, c.MonthlyRuns*float64(c.Completions), c.RunDuration, rp)
+}
+
+func (c *CronJob) getContainers() []Container {
+	return c.Containers
+}
+
+func (c *CronJob) objectCostRange(cost CostRange) ObjectCostRange {
+	return newPodsCostRange(c.APIVersionKindName, c, c.Parallelism, cost)
+}
+
 // VolumeClaim is the simplified reprsentation of k8s VolumeClaim
 // Client doesn't need to handle different version and the complexity of k8s.io package
 type VolumeClaim struct {
 	APIVersionKindName string
 	StorageClass       string
+	Disk               PersistentDisk
 	Requests           Resource
 	Limits             Resource
+	Labels             map[string]string
 }
 
 func (v *VolumeClaim) estimateCost(sp StoragePrice) CostRange {
-	storageMonthlyPrice := float64(sp.PdStandardMonthlyPrice())
-	storageClass := v.StorageClass
-	if storageClass != storageClassStandard {
-		log.Infof("Estimation for StorageClass '%s' not implemented for PersistentVolumeClaim. Using standard (GCE Regional Persistent Disk) instead", storageClass)
-		storageMonthlyPrice = float64(sp.PdStandardMonthlyPrice())
-	}
+	storageMonthlyPrice := float64(sp.StorageMonthlyPrice(v.Disk))
 
 	cost := CostRange{Kind: VolumeClaimKind}
 	cost.MinRequested = (float64(v.Requests.Storage) * storageMonthlyPrice)
@@ -222,19 +430,79 @@ func (v *VolumeClaim) estimateCost(sp StoragePrice) CostRange {
 	return postProcessCost(cost)
 }
 
+// ContainerType tells how a Container contributes to the pod effective requests and limits
+type ContainerType int
+
+const (
+	// AppContainer is a regular container, running during the whole pod lifecycle
+	AppContainer ContainerType = iota
+	// InitContainer runs to completion, one at a time, before app containers start
+	InitContainer
+	// SidecarContainer is an init container with restartPolicy Always, running alongside app containers
+	SidecarContainer
+	// PodOverhead is the resources consumed by the pod sandbox (RuntimeClass overhead)
+	PodOverhead
+	// AutopilotAdjustment is the resources GKE Autopilot adds when rounding pod requests up
+	AutopilotAdjustment
+	// EmptyDirVolume is the ephemeral storage of an emptyDir volume, up to its size limit
+	EmptyDirVolume
+)
+
 // Container is the simplified representation of k8s Container
 // Client doesn't need to handle different version and the complexity of k8s.io package
 type Container struct {
+	Name     string
 	Requests Resource
 	Limits   Resource
+	Type     ContainerType
 }
 
 // Resource is the simplified reprsentation of k8s Resource
 // Client doesn't need to handle different version and the complexity of k8s.io package
 type Resource struct {
-	CPU     int64
-	Memory  int64
-	Storage int64
+	CPU         int64  `json:"cpu"`                   // millis
+	Memory      int64  `json:"memory"`                // bytes
+	Storage     int64  `json:"storage"`               // bytes
+	GPU         int64  `json:"gpu,omitempty"`         // # of GPUs
+	Accelerator string `json:"accelerator,omitempty"` // GPU type (eg. nvidia-tesla-t4). Empty when no GPU is requested
+	LocalSSD    bool   `json:"localSSD,omitempty"`    // ephemeral storage lives on node local SSDs instead of the boot disk
+}
+
+func (r Resource) add(o Resource) Resource {
+	return Resource{
+		CPU:         r.CPU + o.CPU,
+		Memory:      r.Memory + o.Memory,
+		Storage:     r.Storage + o.Storage,
+		GPU:         r.GPU + o.GPU,
+		Accelerator: r.accelerator(o),
+		LocalSSD:    r.LocalSSD || o.LocalSSD,
+	}
+}
+
+func (r Resource) max(o Resource) Resource {
+	if o.CPU > r.CPU {
+		r.CPU = o.CPU
+	}
+	if o.Memory > r.Memory {
+		r.Memory = o.Memory
+	}
+	if o.Storage > r.Storage {
+		r.Storage = o.Storage
+	}
+	if o.GPU > r.GPU {
+		r.GPU = o.GPU
+	}
+	r.Accelerator = r.accelerator(o)
+	r.LocalSSD = r.LocalSSD || o.LocalSSD
+	return r
+}
+
+// accelerator returns the GPU type of either resource. All containers of a pod share the same GPU type
+func (r Resource) accelerator(o Resource) string {
+	if r.Accelerator != "" {
+		return r.Accelerator
+	}
+	return o.Accelerator
 }
 
 // -------- Price Catalog ---------
@@ -247,14 +515,34 @@ type ResourcePrice interface {
 
 //StoragePrice interface
 type StoragePrice interface {
-	PdStandardMonthlyPrice() float32
+	StorageMonthlyPrice(disk PersistentDisk) float32
+}
+
+//PriceProvider interface groups all prices used to estimate costs
+//Implement it to provide your own prices (eg. negotiated rates or test doubles)
+type PriceProvider interface {
+	ResourcePrice
+	StoragePrice
+}
+
+//SpotPriceProvider interface is implemented by price providers knowing Spot VM prices
+//found is false if Spot VM prices are not available
+type SpotPriceProvider interface {
+	SpotResourcePrice() (rp ResourcePrice, found bool)
 }
 
 //GCPPriceCatalog implementation to make call to GCP CloudCatalog
 type GCPPriceCatalog struct {
-	cpuPrice        float32
-	memoryPrice     float32
-	pdStandardPrice float32
+	cpuPrice          float32
+	memoryPrice       float32
+	spotCPUPrice      float32
+	spotMemoryPrice   float32
+	diskPrices        map[PersistentDisk]float32
+	autopilotPrices   map[autopilotPriceKey]autopilotPrice
+	gpuPrices         map[string]float32 // by accelerator type
+	spotGPUPrices     map[string]float32
+	localSSDPrice     float32
+	spotLocalSSDPrice float32
 }
 
 // CPUMonthlyPrice returns the GCP CPU price in USD
@@ -267,9 +555,31 @@ func (pc *GCPPriceCatalog) MemoryMonthlyPrice() float32 {
 	return pc.memoryPrice
 }
 
-// PdStandardMonthlyPrice returns the GCP Storage PD price in USD
+// SpotResourcePrice returns the GCP Spot VM CPU and Memory prices in USD
+func (pc *GCPPriceCatalog) SpotResourcePrice() (ResourcePrice, bool) {
+	if pc.spotCPUPrice == 0 || pc.spotMemoryPrice == 0 {
+		return nil, false
+	}
+	return &GCPPriceCatalog{
+		cpuPrice:      pc.spotCPUPrice,
+		memoryPrice:   pc.spotMemoryPrice,
+		diskPrices:    pc.diskPrices,
+		gpuPrices:     pc.spotGPUPrices,
+		localSSDPrice: pc.spotLocalSSDPrice}, true
+}
+
+// PdStandardMonthlyPrice returns the GCP Storage PD (zonal standard) price in USD
 func (pc *GCPPriceCatalog) PdStandardMonthlyPrice() float32 {
-	return pc.pdStandardPrice
+	return pc.diskPrices[defaultPersistentDisk]
+}
+
+// StorageMonthlyPrice returns the GCP Storage price in USD for the given GCE Persistent Disk
+func (pc *GCPPriceCatalog) StorageMonthlyPrice(disk PersistentDisk) float32 {
+	if price, ok := pc.diskPrices[disk]; ok {
+		return price
+	}
+	log.Infof("Price for %s not found in the price catalog. Using %s instead", disk, defaultPersistentDisk)
+	return pc.diskPrices[defaultPersistentDisk]
 }
 
 // --- utility functions ---
@@ -282,49 +592,184 @@ func buildAPIVersionKindName(apiVersion, kind, ns, name string) string {
 	return fmt.Sprintf("%s|%s|%s|%s", apiVersion, kind, namespace, name)
 }
 
+// buildPodTemplate builds where pods run, for pod spec, and how they are labeled, for the object and pod template labels
+func buildPodTemplate(spec coreV1.PodSpec, objectLabels, templateLabels map[string]string, conf CostimatorConfig) PodTemplate {
+	return PodTemplate{
+		Spot:         isSpot(spec, conf),
+		ComputeClass: computeClass(spec, conf),
+		NodePool:     nodePool(spec, conf),
+		Labels:       buildLabels(objectLabels, templateLabels),
+	}
+}
+
+// buildLabels returns the object labels. Pod template labels are used for the ones not set on the object, since teams often only label pods
+func buildLabels(objectLabels, templateLabels map[string]string) map[string]string {
+	if len(objectLabels) == 0 && len(templateLabels) == 0 {
+		return nil
+	}
+	labels := make(map[string]string)
+	for k, v := range templateLabels {
+		labels[k] = v
+	}
+	for k, v := range objectLabels {
+		labels[k] = v
+	}
+	return labels
+}
+
 func buildKindName(apiVersionKindName string) string {
 	index := strings.Index(apiVersionKindName, "|")
 	return apiVersionKindName[index:]
 }
 
+func newWorkloadCostRange(apiVersionKindName string, r HorizontalScalableResource, cost CostRange) ObjectCostRange {
+	or := newPodsCostRange(apiVersionKindName, r, r.getReplicas(), cost)
+	if r.hasHPA() {
+		hpa := r.getHPA()
+		or.MinReplicas = hpa.MinReplicas
+		or.MaxReplicas = hpa.MaxReplicas
+		or.HPAMetrics = hpa.Metrics
+		or.HPABufferMetric = hpa.bufferMetricDescription(r.getContainers())
+	}
+	return or
+}
+
+// newPodsCostRange returns the cost of an object running the given number of pods
+func newPodsCostRange(apiVersionKindName string, r podResource, replicas int32, cost CostRange) ObjectCostRange {
+	or := newObjectCostRange(apiVersionKindName, cost)
+	or.Requests, or.Limits = sumContainers(r.getContainers())
+	or.Replicas = replicas
+	or.MinReplicas = replicas
+	or.MaxReplicas = replicas
+	or.Spot = r.isSpot()
+	or.ComputeClass = r.getComputeClass()
+	or.NodePool = r.getNodePool()
+	or.Labels = r.getLabels()
+	return or
+}
+
+func newObjectCostRange(apiVersionKindName string, cost CostRange) ObjectCostRange {
+	parts := strings.SplitN(apiVersionKindName, "|", 4)
+	for len(parts) < 4 {
+		parts = append(parts, "")
+	}
+	return ObjectCostRange{
+		APIVersionKindName: apiVersionKindName,
+		Namespace:          parts[2],
+		Kind:               parts[1],
+		Name:               parts[3],
+		MonthlyRange:       cost,
+	}
+}
+
 func estimateCost(kind string, r HorizontalScalableResource, rp ResourcePrice) CostRange {
 	cost := CostRange{Kind: kind}
-	cpuReq, cpuLim, memReq, memLim := totalContainers(r.getContainers())
-
-	var cpuMonthlyPrice = float64(rp.CPUMonthlyPrice())
-	var memoryMonthlyPrice = float64(rp.MemoryMonthlyPrice())
+	requested, limited := podMonthlyCost(r.getContainers(), rp)
 
 	if r.hasHPA() {
 		hpa := r.getHPA()
-		targetCPUPercentage := hpa.TargetCPUPercentage
+		_, targetPercentage, found := hpa.bufferTarget(r.getContainers())
 		minReplicas := float64(hpa.MinReplicas)
 		maxReplicas := float64(hpa.MaxReplicas)
 
-		cost.MinRequested = (minReplicas * cpuReq * cpuMonthlyPrice) + (minReplicas * memReq * memoryMonthlyPrice)
-		cost.MaxRequested = (maxReplicas * cpuReq * cpuMonthlyPrice) + (maxReplicas * memReq * memoryMonthlyPrice)
+		cost.MinRequested = minReplicas * requested
+		cost.MaxRequested = maxReplicas * requested
 
-		cpuBuffer := minReplicas
-		if targetCPUPercentage > 0 {
-			buff := float64(100-targetCPUPercentage) / 100
-			cpuBuffer = minReplicas + (buff * minReplicas)
+		// the most restrictive cpu or memory target defines the headroom kept over min replicas
+		bufferReplicas := minReplicas
+		if found {
+			buff := float64(100-targetPercentage) / 100
+			bufferReplicas = minReplicas + (buff * minReplicas)
 		}
-		cost.HPABuffer = (cpuBuffer * cpuReq * cpuMonthlyPrice) + (cpuBuffer * memReq * memoryMonthlyPrice)
+		cost.HPABuffer = bufferReplicas * requested
 
-		cost.MinLimited = (minReplicas * cpuLim * cpuMonthlyPrice) + (minReplicas * memLim * memoryMonthlyPrice)
-		cost.MaxLimited = (maxReplicas * cpuLim * cpuMonthlyPrice) + (maxReplicas * memLim * memoryMonthlyPrice)
+		cost.MinLimited = minReplicas * limited
+		cost.MaxLimited = maxReplicas * limited
 
 	} else {
 		replicas := float64(r.getReplicas())
-		cost.MinRequested = (replicas * cpuReq * cpuMonthlyPrice) + (replicas * memReq * memoryMonthlyPrice)
+		cost.MinRequested = replicas * requested
 		cost.MaxRequested = cost.MinRequested
 		cost.HPABuffer = cost.MinRequested
-		cost.MinLimited = (replicas * cpuLim * cpuMonthlyPrice) + (replicas * memLim * memoryMonthlyPrice)
+		cost.MinLimited = replicas * limited
 		cost.MaxLimited = cost.MinLimited
 	}
 
 	return postProcessCost(cost)
 }
 
+// estimateBatchCost estimates the cost of pods running only part of the month (duty-cycle)
+func estimateBatchCost(kind string, containers []Container, podRuns float64, runDuration time.Duration, rp ResourcePrice) CostRange {
+	cost := CostRange{Kind: kind}
+	requested, limited := podMonthlyCost(containers, rp)
+
+	dutyCycle := podRuns * runDuration.Hours() / hoursInMonth
+	cost.MinRequested = dutyCycle * requested
+	cost.MaxRequested = cost.MinRequested
+	cost.HPABuffer = cost.MinRequested
+	cost.MinLimited = dutyCycle * limited
+	cost.MaxLimited = cost.MinLimited
+
+	return postProcessCost(cost)
+}
+
+// podMonthlyCost returns the monthly cost of a single pod running all month long, both at requests and at limits
+// Ephemeral storage and GPUs are only priced when the resource price bills them (see ephemeralStoragePrice and AcceleratorPrice)
+func podMonthlyCost(containers []Container, rp ResourcePrice) (requested float64, limited float64) {
+	cpuReq, cpuLim, memReq, memLim := totalContainers(containers)
+
+	var cpuMonthlyPrice = float64(rp.CPUMonthlyPrice())
+	var memoryMonthlyPrice = float64(rp.MemoryMonthlyPrice())
+
+	requested = (cpuReq * cpuMonthlyPrice) + (memReq * memoryMonthlyPrice)
+	limited = (cpuLim * cpuMonthlyPrice) + (memLim * memoryMonthlyPrice)
+	requests, limits := sumContainers(containers)
+	if price, _, found := ephemeralStoragePrice(rp, requests.LocalSSD); found {
+		storageMonthlyPrice := float64(price)
+		requested = requested + (float64(requests.Storage) * storageMonthlyPrice)
+		limited = limited + (float64(limits.Storage) * storageMonthlyPrice)
+	}
+	if ap, ok := rp.(AcceleratorPrice); ok && requests.GPU > 0 {
+		gpuMonthlyPrice, _ := ap.GPUMonthlyPrice(requests.Accelerator)
+		requested = requested + (float64(requests.GPU) * float64(gpuMonthlyPrice))
+		limited = limited + (float64(limits.GPU) * float64(gpuMonthlyPrice))
+	}
+	return
+}
+
+// workloadResourcePrice returns the prices for workloads running on Spot VMs and/or on an Autopilot compute class
+// It falls back to Standard prices when the price provider doesn't know Autopilot prices,
+// and to on-demand prices when it doesn't know Spot VM prices
+func workloadResourcePrice(rp ResourcePrice, spot bool, class ComputeClass) ResourcePrice {
+	if price, found := findResourcePrice(rp, spot, class); found {
+		return price
+	}
+	if class != "" {
+		log.Infof("GKE Autopilot %s prices not available in the price catalog. Using Standard prices instead", class)
+		return workloadResourcePrice(rp, spot, "")
+	}
+	log.Infof("Spot VM prices not available in the price catalog. Using on-demand prices instead")
+	return rp
+}
+
+// findResourcePrice returns the prices for workloads running on Spot VMs and/or on an Autopilot compute class (empty for Standard clusters)
+// found is false when the price provider doesn't know them
+func findResourcePrice(rp ResourcePrice, spot bool, class ComputeClass) (ResourcePrice, bool) {
+	if class != "" {
+		if ap, ok := rp.(AutopilotPriceProvider); ok {
+			return ap.AutopilotResourcePrice(class, spot)
+		}
+		return nil, false
+	}
+	if !spot {
+		return rp, true
+	}
+	if sp, ok := rp.(SpotPriceProvider); ok {
+		return sp.SpotResourcePrice()
+	}
+	return nil, false
+}
+
 func postProcessCost(cost CostRange) CostRange {
 	// just to make sure limit will not be smaller than requested
 	if cost.MinLimited < cost.MinRequested {
@@ -336,66 +781,184 @@ func postProcessCost(cost CostRange) CostRange {
 	return cost
 }
 
-func buildContainers(cont []coreV1.Container, conf CostimatorConfig) []Container {
-	containers := []Container{}
-	for i := 0; i < len(cont); i++ {
-		requests := cont[i].Resources.Requests
-		requestsCPU := requests[coreV1.ResourceCPU]
-		requestsMemory := requests[coreV1.ResourceMemory]
-		limits := cont[i].Resources.Limits
-		limitsCPU := limits[coreV1.ResourceCPU]
-		limitsMemory := limits[coreV1.ResourceMemory]
-
-		requestsCPUinMilli := requestsCPU.MilliValue()
-		requestsMemoryinMilli := requestsMemory.Value()
-		limitsCPUinMilli := limitsCPU.MilliValue()
-		limitsMemoryinMilli := limitsMemory.Value()
-		// If Requests is omitted for a container, it defaults to Limits if that is explicitly specified
-		if requestsCPUinMilli == 0 {
-			requestsCPUinMilli = limitsCPUinMilli
-		}
-		if requestsMemoryinMilli == 0 {
-			requestsMemoryinMilli = limitsMemoryinMilli
-		}
-		// otherwise to an config-defined value.
-		if requestsCPUinMilli == 0 {
-			requestsCPUinMilli = conf.ResourceConf.DefaultCPUinMillis
-		}
-		if requestsMemoryinMilli == 0 {
-			requestsMemoryinMilli = conf.ResourceConf.DefaultMemoryinBytes
+// isSpot tells if pods run on Spot (or preemptible) VMs, either because they are placed on a Spot node pool (see ClusterConfig.NodePools),
+// because all cluster nodes are Spot VMs (see ClusterConfig.SpotVMs) or because pods select or tolerate Spot nodes
+func isSpot(spec coreV1.PodSpec, conf CostimatorConfig) bool {
+	if pool, found := matchNodePool(spec, conf); found {
+		return pool.Spot
+	}
+	if conf.ClusterConf.SpotVMs {
+		return true
+	}
+	for _, label := range []string{SpotNodeLabel, PreemptibleNodeLabel} {
+		if spec.NodeSelector[label] == "true" {
+			return true
 		}
-		// Give a percentage increase for umbounded resources
-		if limitsCPUinMilli == 0 {
-			limitsCPUinMilli = requestsCPUinMilli + (conf.ResourceConf.PercentageIncreaseForUnboundedRerouces * requestsCPUinMilli / 100)
+		for _, toleration := range spec.Tolerations {
+			// GKE taints Spot nodes with NoSchedule effect
+			if toleration.Key == label && (toleration.Effect == "" || toleration.Effect == coreV1.TaintEffectNoSchedule) {
+				return true
+			}
 		}
-		if limitsMemoryinMilli == 0 {
-			limitsMemoryinMilli = requestsMemoryinMilli + (conf.ResourceConf.PercentageIncreaseForUnboundedRerouces * requestsMemoryinMilli / 100)
+	}
+	return false
+}
+
+// buildPodContainers builds app containers, init containers (including sidecars) and pod overhead
+// In Autopilot clusters, it also builds the adjustment Autopilot makes to pod requests
+func buildPodContainers(spec coreV1.PodSpec, conf CostimatorConfig) []Container {
+	containers := []Container{}
+	for _, initContainer := range spec.InitContainers {
+		containerType := InitContainer
+		if initContainer.RestartPolicy != nil && *initContainer.RestartPolicy == coreV1.ContainerRestartPolicyAlways {
+			containerType = SidecarContainer
 		}
+		containers = append(containers, buildContainer(initContainer, containerType, conf))
+	}
+	for _, appContainer := range spec.Containers {
+		containers = append(containers, buildContainer(appContainer, AppContainer, conf))
+	}
 
-		container := Container{
-			Requests: Resource{
-				CPU:    requestsCPUinMilli,
-				Memory: requestsMemoryinMilli,
-			},
-			Limits: Resource{
-				CPU:    limitsCPUinMilli,
-				Memory: limitsMemoryinMilli,
-			},
+	if len(spec.Overhead) > 0 {
+		overheadCPU := spec.Overhead[coreV1.ResourceCPU]
+		overheadMemory := spec.Overhead[coreV1.ResourceMemory]
+		overhead := Resource{
+			CPU:    overheadCPU.MilliValue(),
+			Memory: overheadMemory.Value(),
 		}
-		containers = append(containers, container)
+		containers = append(containers, Container{Requests: overhead, Limits: overhead, Type: PodOverhead})
+	}
+	containers = withEphemeralStorage(containers, spec, conf)
+	if class := computeClass(spec, conf); class != "" {
+		containers = autopilotContainers(containers, class)
 	}
-	return containers
+	return withAccelerator(containers, spec, conf)
 }
 
-func totalContainers(containers []Container) (cpuReq float64, cpuLim float64, memReq float64, memLim float64) {
+// buildContainer builds a container of the given type
+// Init containers run one at a time before the pod starts, so they don't get the default requests of long running containers
+func buildContainer(cont coreV1.Container, containerType ContainerType, conf CostimatorConfig) Container {
+	defaults := Resource{CPU: conf.ResourceConf.DefaultCPUinMillis, Memory: conf.ResourceConf.DefaultMemoryinBytes}
+	if conf.ClusterConf.Mode == Autopilot {
+		defaults = autopilotDefaultRequests
+	}
+	if containerType == InitContainer {
+		defaults = Resource{}
+	}
+
+	requests := cont.Resources.Requests
+	requestsCPU := requests[coreV1.ResourceCPU]
+	requestsMemory := requests[coreV1.ResourceMemory]
+	requestsStorage := requests[coreV1.ResourceEphemeralStorage]
+	limits := cont.Resources.Limits
+	limitsCPU := limits[coreV1.ResourceCPU]
+	limitsMemory := limits[coreV1.ResourceMemory]
+	limitsStorage := limits[coreV1.ResourceEphemeralStorage]
+	requestsGPU := requests[GPUResourceName]
+	limitsGPU := limits[GPUResourceName]
+
+	requestsCPUinMilli := requestsCPU.MilliValue()
+	requestsMemoryinMilli := requestsMemory.Value()
+	requestsStorageinBytes := requestsStorage.Value()
+	limitsCPUinMilli := limitsCPU.MilliValue()
+	limitsMemoryinMilli := limitsMemory.Value()
+	limitsStorageinBytes := limitsStorage.Value()
+	// GPUs can't be overcommitted, so requests and limits must be equal when both are specified
+	requestsGPUCount := requestsGPU.Value()
+	limitsGPUCount := limitsGPU.Value()
+	if requestsGPUCount == 0 {
+		requestsGPUCount = limitsGPUCount
+	}
+	if limitsGPUCount == 0 {
+		limitsGPUCount = requestsGPUCount
+	}
+	// If Requests is omitted for a container, it defaults to Limits if that is explicitly specified
+	if requestsCPUinMilli == 0 {
+		requestsCPUinMilli = limitsCPUinMilli
+	}
+	if requestsMemoryinMilli == 0 {
+		requestsMemoryinMilli = limitsMemoryinMilli
+	}
+	if requestsStorageinBytes == 0 {
+		requestsStorageinBytes = limitsStorageinBytes
+	}
+	// otherwise to an config-defined value (or to Autopilot defaults).
+	if requestsCPUinMilli == 0 {
+		requestsCPUinMilli = defaults.CPU
+	}
+	if requestsMemoryinMilli == 0 {
+		requestsMemoryinMilli = defaults.Memory
+	}
+	if requestsStorageinBytes == 0 {
+		requestsStorageinBytes = defaults.Storage
+	}
+	// Give a percentage increase for umbounded resources
+	if limitsCPUinMilli == 0 {
+		limitsCPUinMilli = requestsCPUinMilli + (conf.ResourceConf.PercentageIncreaseForUnboundedRerouces * requestsCPUinMilli / 100)
+	}
+	if limitsMemoryinMilli == 0 {
+		limitsMemoryinMilli = requestsMemoryinMilli + (conf.ResourceConf.PercentageIncreaseForUnboundedRerouces * requestsMemoryinMilli / 100)
+	}
+	if limitsStorageinBytes == 0 {
+		limitsStorageinBytes = requestsStorageinBytes
+	}
+
+	return Container{
+		Name: cont.Name,
+		Type: containerType,
+		Requests: Resource{
+			CPU:     requestsCPUinMilli,
+			Memory:  requestsMemoryinMilli,
+			Storage: requestsStorageinBytes,
+			GPU:     requestsGPUCount,
+		},
+		Limits: Resource{
+			CPU:     limitsCPUinMilli,
+			Memory:  limitsMemoryinMilli,
+			Storage: limitsStorageinBytes,
+			GPU:     limitsGPUCount,
+		},
+	}
+}
+
+// sumContainers returns the effective pod requests and limits, the same way k8s scheduler computes them:
+// max(largest init container, sum of app containers) + overhead, where sidecars are added to both sides
+func sumContainers(containers []Container) (requests Resource, limits Resource) {
+	var initRequests, initLimits Resource
+	var sidecarRequests, sidecarLimits Resource
+	var appRequests, appLimits Resource
+	var overheadRequests, overheadLimits Resource
 	for _, container := range containers {
-		cpuReq = cpuReq + float64(container.Requests.CPU)
-		cpuLim = cpuLim + float64(container.Limits.CPU)
-		memReq = memReq + float64(container.Requests.Memory) // bytes
-		memLim = memLim + float64(container.Limits.Memory)   // bytes
+		switch container.Type {
+		case InitContainer:
+			// sidecars started before this init container are still running
+			initRequests = initRequests.max(sidecarRequests.add(container.Requests))
+			initLimits = initLimits.max(sidecarLimits.add(container.Limits))
+		case SidecarContainer:
+			sidecarRequests = sidecarRequests.add(container.Requests)
+			sidecarLimits = sidecarLimits.add(container.Limits)
+			initRequests = initRequests.max(sidecarRequests)
+			initLimits = initLimits.max(sidecarLimits)
+		case PodOverhead, AutopilotAdjustment, EmptyDirVolume:
+			overheadRequests = overheadRequests.add(container.Requests)
+			overheadLimits = overheadLimits.add(container.Limits)
+		default:
+			appRequests = appRequests.add(container.Requests)
+			appLimits = appLimits.add(container.Limits)
+		}
 	}
-	cpuReq = cpuReq / 1000 // from milis to # of cores
-	cpuLim = cpuLim / 1000 // from milis to # of cores
+	requests = initRequests.max(appRequests.add(sidecarRequests)).add(overheadRequests)
+	limits = initLimits.max(appLimits.add(sidecarLimits)).add(overheadLimits)
+	return
+}
+
+// totalContainers returns the effective pod requests and limits in # of cores and bytes
+func totalContainers(containers []Container) (cpuReq float64, cpuLim float64, memReq float64, memLim float64) {
+	requests, limits := sumContainers(containers)
+	cpuReq = float64(requests.CPU) / 1000 // from milis to # of cores
+	cpuLim = float64(limits.CPU) / 1000   // from milis to # of cores
+	memReq = float64(requests.Memory)     // bytes
+	memLim = float64(limits.Memory)       // bytes
 	return
 }
 
@@ -14,7 +14,12 @@
 
 package api
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	coreV1 "k8s.io/api/core/v1"
+)
 
 func TestDeploymentGetKindName(t *testing.T) {
 	d := Deployment{APIVersionKindName: "version|kind|namespace|name"}
@@ -172,6 +177,54 @@ func TestDeploymentEstimateCostWithHPA(t *testing.T) {
 	}
 }
 
+func TestDeploymentEstimateCostWithMemoryHPA(t *testing.T) {
+	rp := &GCPPriceCatalog{
+		cpuPrice:    4,
+		memoryPrice: 2,
+	}
+	cpu, memory := int32(80), int32(50)
+	deploy := Deployment{
+		APIVersionKindName: "apps/v1|Deployment|default|app",
+		Replicas:           2,
+		Containers: []Container{
+			{
+				Requests: Resource{
+					CPU:    1000,  // 1 vCPU
+					Memory: 10000, // bytes
+				},
+				Limits: Resource{
+					CPU:    2000,  // 2 vCPU
+					Memory: 20000, // bytes
+				},
+			},
+		},
+		hpa: HPA{
+			APIVersionKindName:  "HPA",
+			MinReplicas:         2,
+			MaxReplicas:         4,
+			TargetCPUPercentage: cpu,
+			Metrics: []HPAMetric{
+				newHPAMetric("Resource", "cpu", "", &cpu, nil, nil),
+				newHPAMetric("Resource", "memory", "", &memory, nil, nil),
+			}},
+	}
+	cr := deploy.estimateCost(rp)
+
+	// memory target is the most restrictive, so it drives the buffer
+	hpaBuffer := cr.MinRequested + (cr.MinRequested * 0.5)
+	if got := cr.HPABuffer; got != hpaBuffer {
+		t.Errorf("HPABuffer is %v, want %v", got, hpaBuffer)
+	}
+
+	or := newWorkloadCostRange(deploy.APIVersionKindName, &deploy, cr)
+	if got, want := or.HPABufferMetric, "memory: 50% utilization"; got != want {
+		t.Errorf("HPABufferMetric is '%v', want '%v'", got, want)
+	}
+	if got := len(or.HPAMetrics); got != 2 {
+		t.Errorf("HPAMetrics should have 2 metrics, got %v", got)
+	}
+}
+
 func TestStatefulSetGetKindName(t *testing.T) {
 	s := StatefulSet{APIVersionKindName: "version|kind|namespace|name"}
 	want := "|kind|namespace|name"
@@ -325,10 +378,11 @@ func TestDaemonSetEstimateCost(t *testing.T) {
 
 func TestVolumeClaimEstimateCost(t *testing.T) {
 	rp := &GCPPriceCatalog{
-		pdStandardPrice: 2,
+		diskPrices: map[PersistentDisk]float32{defaultPersistentDisk: 2},
 	}
 	volume := VolumeClaim{
 		StorageClass: storageClassStandard,
+		Disk:         defaultPersistentDisk,
 		Requests: Resource{
 			Storage: 10000, // bytes
 		},
@@ -360,3 +414,185 @@ func TestVolumeClaimEstimateCost(t *testing.T) {
 		t.Errorf("MaxLimited is %v, want %v", got, cost)
 	}
 }
+
+func TestJobEstimateCost(t *testing.T) {
+	rp := &GCPPriceCatalog{
+		cpuPrice:    4,
+		memoryPrice: 2,
+	}
+	job := Job{
+		Parallelism: 2,
+		Completions: 4,
+		RunDuration: 93 * time.Hour, // 1/8 of a month
+		Containers: []Container{
+			{
+				Requests: Resource{
+					CPU:    1000,  // 1 vCPU
+					Memory: 10000, // bytes
+				},
+				Limits: Resource{
+					CPU:    2000,  // 2 vCPU
+					Memory: 20000, // bytes
+				},
+			},
+		},
+	}
+	cr := job.estimateCost(rp)
+
+	want := JobKind
+	if got := cr.Kind; got != want {
+		t.Errorf("Kind is %v, want %v", got, want)
+	}
+
+	// 4 pods running 1/8 of a month
+	cost := (4.0 + 20000.0) * 4 / 8
+	if got := cr.MinRequested; got != cost {
+		t.Errorf("MinRequested is %v, want %v", got, cost)
+	}
+	if got := cr.MaxRequested; got != cost {
+		t.Errorf("MaxRequested is %v, want %v", got, cost)
+	}
+
+	cost = (8.0 + 40000.0) * 4 / 8
+	if got := cr.MaxLimited; got != cost {
+		t.Errorf("MaxLimited is %v, want %v", got, cost)
+	}
+}
+
+func TestCronJobEstimateCost(t *testing.T) {
+	rp := &GCPPriceCatalog{
+		cpuPrice:    4,
+		memoryPrice: 2,
+	}
+	cronjob := CronJob{
+		MonthlyRuns: 31,
+		Parallelism: 1,
+		Completions: 1,
+		RunDuration: 6 * time.Hour, // 1/4 of each day
+		Containers: []Container{
+			{
+				Requests: Resource{
+					CPU:    1000,  // 1 vCPU
+					Memory: 10000, // bytes
+				},
+				Limits: Resource{
+					CPU:    1000,  // 1 vCPU
+					Memory: 10000, // bytes
+				},
+			},
+		},
+	}
+	cr := cronjob.estimateCost(rp)
+
+	want := CronJobKind
+	if got := cr.Kind; got != want {
+		t.Errorf("Kind is %v, want %v", got, want)
+	}
+
+	cost := (4.0 + 20000.0) / 4
+	if got := cr.MinRequested; got != cost {
+		t.Errorf("MinRequested is %v, want %v", got, cost)
+	}
+	if got := cr.MaxLimited; got != cost {
+		t.Errorf("MaxLimited is %v, want %v", got, cost)
+	}
+}
+
+func TestSumContainersEffectiveRequests(t *testing.T) {
+	containers := []Container{
+		{Type: SidecarContainer, Requests: Resource{CPU: 100, Memory: 100}, Limits: Resource{CPU: 200, Memory: 100}},
+		{Type: InitContainer, Requests: Resource{CPU: 1000, Memory: 50}, Limits: Resource{CPU: 1000, Memory: 50}},
+		{Type: AppContainer, Requests: Resource{CPU: 500, Memory: 300}, Limits: Resource{CPU: 500, Memory: 300}},
+		{Type: AppContainer, Requests: Resource{CPU: 200, Memory: 200}, Limits: Resource{CPU: 400, Memory: 200}},
+		{Type: PodOverhead, Requests: Resource{CPU: 10, Memory: 10}, Limits: Resource{CPU: 10, Memory: 10}},
+	}
+
+	requests, limits := sumContainers(containers)
+
+	// the init container runs with the sidecar started before it: max(100 + 1000, 100 + 500 + 200) + 10
+	expectedRequests := Resource{CPU: 1110, Memory: 610}
+	if requests != expectedRequests {
+		t.Errorf("Requests should be equal, expected: %+v, got: %+v", expectedRequests, requests)
+	}
+	// max(200 + 1000, 200 + 500 + 400) + 10
+	expectedLimits := Resource{CPU: 1210, Memory: 610}
+	if limits != expectedLimits {
+		t.Errorf("Limits should be equal, expected: %+v, got: %+v", expectedLimits, limits)
+	}
+
+	cpuReq, _, memReq, _ := totalContainers(containers)
+	if cpuReq != 1.11 || memReq != 610 {
+		t.Errorf("Expected effective requests of 1.11 cores and 610 bytes, got %+v and %+v", cpuReq, memReq)
+	}
+}
+
+func TestGCPPriceCatalogStorageMonthlyPrice(t *testing.T) {
+	ssd := PersistentDisk{DiskType: PdSSD, Replication: Zonal}
+	pc := &GCPPriceCatalog{
+		diskPrices: map[PersistentDisk]float32{defaultPersistentDisk: 1, ssd: 4},
+	}
+
+	if got := pc.StorageMonthlyPrice(ssd); got != 4 {
+		t.Errorf("StorageMonthlyPrice for %s is %v, want %v", ssd, got, 4)
+	}
+	// prices not found fall back to pd-standard
+	extreme := PersistentDisk{DiskType: PdExtreme, Replication: Zonal}
+	if got := pc.StorageMonthlyPrice(extreme); got != 1 {
+		t.Errorf("StorageMonthlyPrice for %s is %v, want %v", extreme, got, 1)
+	}
+}
+
+func TestIsSpot(t *testing.T) {
+	tests := map[string]struct {
+		spec coreV1.PodSpec
+		conf CostimatorConfig
+		want bool
+	}{
+		"on-demand": {
+			spec: coreV1.PodSpec{},
+			want: false,
+		},
+		"spot nodeSelector": {
+			spec: coreV1.PodSpec{NodeSelector: map[string]string{SpotNodeLabel: "true"}},
+			want: true,
+		},
+		"preemptible nodeSelector": {
+			spec: coreV1.PodSpec{NodeSelector: map[string]string{PreemptibleNodeLabel: "true"}},
+			want: true,
+		},
+		"spot toleration": {
+			spec: coreV1.PodSpec{Tolerations: []coreV1.Toleration{{Key: SpotNodeLabel, Operator: coreV1.TolerationOpEqual, Value: "true", Effect: coreV1.TaintEffectNoSchedule}}},
+			want: true,
+		},
+		"spot toleration for another effect": {
+			spec: coreV1.PodSpec{Tolerations: []coreV1.Toleration{{Key: SpotNodeLabel, Operator: coreV1.TolerationOpExists, Effect: coreV1.TaintEffectNoExecute}}},
+			want: false,
+		},
+		"all cluster on spot": {
+			spec: coreV1.PodSpec{},
+			conf: CostimatorConfig{ClusterConf: ClusterConfig{SpotVMs: true}},
+			want: true,
+		},
+	}
+	for name, tt := range tests {
+		if got := isSpot(tt.spec, tt.conf); got != tt.want {
+			t.Errorf("%s: isSpot is %v, want %v", name, got, tt.want)
+		}
+	}
+}
+
+func TestWorkloadResourcePrice(t *testing.T) {
+	pc := &GCPPriceCatalog{cpuPrice: 10, memoryPrice: 2, spotCPUPrice: 3, spotMemoryPrice: 1}
+	if got := workloadResourcePrice(pc, false, ""); got.CPUMonthlyPrice() != 10 || got.MemoryMonthlyPrice() != 2 {
+		t.Errorf("Should have used on-demand prices, got %+v", got)
+	}
+	if got := workloadResourcePrice(pc, true, ""); got.CPUMonthlyPrice() != 3 || got.MemoryMonthlyPrice() != 1 {
+		t.Errorf("Should have used Spot VM prices, got %+v", got)
+	}
+
+	// on-demand prices are used when Spot VM prices are not available
+	pc = &GCPPriceCatalog{cpuPrice: 10, memoryPrice: 2}
+	if got := workloadResourcePrice(pc, true, ""); got.CPUMonthlyPrice() != 10 || got.MemoryMonthlyPrice() != 2 {
+		t.Errorf("Should have fallen back to on-demand prices, got %+v", got)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/fernandorubbo/k8s-cost-estimator/api"
 	log "github.com/sirupsen/logrus"
@@ -31,15 +32,31 @@ import (
 const version = "v0.0.1"
 
 var (
-	k8sPath     = flag.String("k8s", "", "Required. Path to k8s manifests folder")
-	k8sPrevPath = flag.String("k8s-prev", "", "Optional. Path to the previous K8s manifests folder. Useful to compare prices.")
+	k8sPath     = flag.String("k8s", "", "Required. Path to k8s manifests folder, Kustomize folder or Helm chart folder")
+	k8sPrevPath = flag.String("k8s-prev", "", "Optional. Path to the previous K8s manifests folder, Kustomize folder or Helm chart folder. Useful to compare prices.")
 	outputFile  = flag.String("output", "", "Optional. Output file path. If not provided, console is used")
 	environ     = flag.String("environ", "LOCAL", "Optional. Where your code is running at. Used to know determine the output file format: GITHUB | GITLAB | LOCAL")
 	authKey     = flag.String("auth-key", "", "Optional. The GCP service account JSON key filepath. If not provided, default service account is used (Run 'gcloud auth application-default login' to set your user as the default service account)")
 	configFile  = flag.String("config", "", "Optional. The defaults configuration YAML filepath to set: machine family, region and compute resources not provided in k8s manifests")
 	verbosity   = flag.String("v", "panic", "Optional. Verbosity: panic|fatal|error|warn|info|debug|trace. Default panic")
+	format      = flag.String("format", "MARKDOWN", "Optional. Output format: MARKDOWN | JSON | YAML | CSV. JSON and YAML follow the versioned report schema (see api.Report) for both estimate and diff runs. CSV has one row per object per cost dimension and, for 'k8s-prev' runs, a matching '.diff.csv' file is saved next to 'output'")
+
+	priceCatalogFile       = flag.String("price-catalog", "", "Optional. Offline price catalog YAML/JSON filepath. If provided, prices are read from this file instead of GCP Cloud Billing API")
+	exportPriceCatalogFile = flag.String("export-price-catalog", "", "Optional. Exports GCP prices for the configured machine family and region (and node pools) into the given YAML/JSON filepath and exits. Entries for other machine families and regions already in the file are kept")
+	priceCacheDir          = flag.String("price-cache-dir", "", "Optional. Folder where GCP prices are cached. If not provided, the user cache folder is used (eg. ~/.cache/k8s-cost-estimator)")
+	priceCacheTTL          = flag.Duration("price-cache-ttl", 24*time.Hour, "Optional. How long cached GCP prices are valid for. Use 0 to disable the cache")
+	refreshPrices          = flag.Bool("refresh-prices", false, "Optional. Ignores cached GCP prices, retrieving them from GCP and refreshing the cache")
+
+	policyFile   = flag.String("policy", "", fmt.Sprintf("Optional. Cost policy YAML/JSON filepath with warn and fail thresholds for the cost increase between 'k8s-prev' and 'k8s'. Exit codes: %d pass | %d warn | %d fail", api.PolicyPassExitCode, api.PolicyWarnExitCode, api.PolicyFailExitCode))
+	templateFile = flag.String("template", "", "Optional. Go text/template filepath rendering the estimate or diff data model (see api.RenderInput) into your own PR comment, Slack text, HTML, etc. If provided, 'format' is ignored")
+
+	helmValues      = flag.String("helm-values", "", "Optional. Comma separated list of values YAML filepaths used when 'k8s' or 'k8s-prev' is a Helm chart folder")
+	helmReleaseName = flag.String("helm-release-name", "release-name", "Optional. Release name used when 'k8s' or 'k8s-prev' is a Helm chart folder")
+	helmNamespace   = flag.String("helm-namespace", "default", "Optional. Release namespace used when 'k8s' or 'k8s-prev' is a Helm chart folder")
 )
 
+var renderer api.Renderer
+
 func init() {
 	flag.Parse()
 
@@ -56,26 +73,69 @@ func init() {
 	log.SetOutput(os.Stdout)
 	log.SetLevel(level)
 
+	renderer = newRenderer()
+
 	// required flags
-	validateK8sPath(*k8sPath, "k8s")
+	if *exportPriceCatalogFile == "" {
+		validateK8sPath(*k8sPath, "k8s")
+	}
+	if *policyFile != "" && *k8sPrevPath == "" {
+		exit("policy requires k8s-prev")
+	}
 }
 
 func main() {
 	log.Infof("Starting cost estimation (version %s)...", version)
 
 	config := readConfigFromFile()
-	priceCatalog := newGCPPriceCatalog(config)
-	currentCost := estimateCost(*k8sPath, config, priceCatalog)
+	exitOnError("Invalid 'config' file", api.ValidateConfig(config))
+	if *exportPriceCatalogFile != "" {
+		exportPriceCatalog(config)
+		log.Info("Finished price catalog export!")
+		return
+	}
+
+	policy := readPolicyFromFile()
+	// prices needed by k8s objects must be known before retrieving them
+	currentManifests := loadManifests(*k8sPath, config)
+	requirements := currentManifests.PriceRequirements()
+	var previousManifests api.Manifests
+	if isPreviousPathProvided() {
+		previousManifests = loadManifests(*k8sPrevPath, config)
+		requirements = requirements.Add(previousManifests.PriceRequirements())
+	}
+	priceProvider, prices := newPriceProvider(config, requirements)
+	currentCost := estimateCost(*k8sPath, currentManifests, priceProvider)
+	input := api.RenderInput{Report: api.NewEstimateReport(currentCost, config, prices, version), Cost: currentCost}
 	if isPreviousPathProvided() {
 		log.Infof("Comparing current cost against previous version. Paths: '%s' vs '%s'", *k8sPath, *k8sPrevPath)
-		previousCosts := estimateCost(*k8sPrevPath, config, priceCatalog)
+		previousCosts := estimateCost(*k8sPrevPath, previousManifests, priceProvider)
 		diffCost := currentCost.Subtract(previousCosts)
-		outputDiff(diffCost)
-	} else {
-		output(currentCost.ToMarkdown())
+		input.Report = api.NewDiffReport(diffCost, config, prices, version)
+		input.Diff = &diffCost
+		if policy != nil {
+			result := policy.Evaluate(diffCost)
+			log.Infof("Cost policy status: %s", result.Status)
+			input.Policy = &result
+			input.Report.Policy = &result
+		}
 	}
+	if len(config.BudgetConf.Budgets) > 0 {
+		budgets, err := api.EvaluateBudgets(config, currentCost)
+		exitOnError("Unable to evaluate budgets", err)
+		input.Budgets = budgets
+		input.Report.Budgets = budgets
+	}
+	render(input)
 
 	log.Info("Finished cost estimation!")
+	exitCode := api.BudgetsExitCode(input.Budgets)
+	if input.Policy != nil && input.Policy.ExitCode() > exitCode {
+		exitCode = input.Policy.ExitCode()
+	}
+	if exitCode != api.PolicyPassExitCode {
+		os.Exit(exitCode)
+	}
 }
 
 func readConfigFromFile() api.CostimatorConfig {
@@ -91,10 +151,106 @@ func readConfigFromFile() api.CostimatorConfig {
 	return conf
 }
 
-func newGCPPriceCatalog(config api.CostimatorConfig) api.GCPPriceCatalog {
+func readPolicyFromFile() *api.Policy {
+	if *policyFile == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(*policyFile)
+	exitOnError("Unable to read 'policy' file", err)
+	policy, err := api.ReadPolicyFile(data)
+	exitOnError("Invalid 'policy' file", err)
+	return &policy
+}
+
+// newPriceProvider returns the prices for the configured machine family and region, along with the prices of each node pool
+// Prices used are also returned in the offline price catalog format, so they can be written in JSON/YAML reports
+func newPriceProvider(config api.CostimatorConfig, requirements api.PriceRequirements) (api.PriceProvider, api.PriceCatalogFile) {
+	prices := api.PriceCatalogFile{}
+	priceCatalog := newPriceCatalog(config, requirements)
+	prices.AddEntry(api.NewPriceCatalogEntry(priceCatalog, config))
+	nodePools := config.ClusterConf.EffectiveNodePools()
+	if len(nodePools) == 0 {
+		return &priceCatalog, prices
+	}
+	pools := make(map[string]api.PriceProvider)
+	for _, pool := range nodePools {
+		poolConfig := pool.PriceConfig(config)
+		poolCatalog := newPriceCatalog(poolConfig, requirements)
+		pools[pool.Name] = &poolCatalog
+		prices.AddEntry(api.NewPriceCatalogEntry(poolCatalog, poolConfig))
+	}
+	return api.NewNodePoolPriceCatalog(&priceCatalog, pools), prices
+}
+
+func newPriceCatalog(config api.CostimatorConfig, requirements api.PriceRequirements) api.GCPPriceCatalog {
+	if *priceCatalogFile != "" {
+		return readPriceCatalogFromFile(config)
+	}
+	return newGCPPriceCatalog(config, requirements)
+}
+
+func readPriceCatalogFromFile(config api.CostimatorConfig) api.GCPPriceCatalog {
+	log.Debugf("Reading Price Catalog from file '%s'...", *priceCatalogFile)
+	data, err := ioutil.ReadFile(*priceCatalogFile)
+	exitOnError("Unable to read 'price-catalog' file", err)
+	priceCatalog, err := api.NewGCPPriceCatalogFromFile(data, config)
+	exitOnError("Unable to load 'price-catalog' file", err)
+	return priceCatalog
+}
+
+func exportPriceCatalog(config api.CostimatorConfig) {
+	log.Infof("Exporting Price Catalog from GCP into '%s'...", *exportPriceCatalogFile)
+	catalogFile := api.PriceCatalogFile{}
+	data, err := ioutil.ReadFile(*exportPriceCatalogFile)
+	if err == nil {
+		catalogFile, err = api.ReadPriceCatalogFile(data)
+		exitOnError("Unable to load existing 'export-price-catalog' file", err)
+	} else if !os.IsNotExist(err) {
+		exitOnError("Unable to read existing 'export-price-catalog' file", err)
+	}
+
+	// exported catalogs are used offline with any k8s objects, so they have all prices
+	// and they are always retrieved from GCP, so they are never older than the export
+	requirements := api.PriceRequirements{All: true}
+	priceCatalog := newLiveGCPPriceCatalog(config, requirements)
+	catalogFile.AddEntry(api.NewPriceCatalogEntry(priceCatalog, config))
+	for _, pool := range config.ClusterConf.EffectiveNodePools() {
+		poolConfig := pool.PriceConfig(config)
+		catalogFile.AddEntry(api.NewPriceCatalogEntry(newLiveGCPPriceCatalog(poolConfig, requirements), poolConfig))
+	}
+
+	if strings.HasSuffix(*exportPriceCatalogFile, ".json") {
+		data, err = json.MarshalIndent(catalogFile, "", "  ")
+	} else {
+		data, err = yaml.Marshal(catalogFile)
+	}
+	exitOnError("Unable to marshal price catalog", err)
+	err = ioutil.WriteFile(*exportPriceCatalogFile, data, 0644)
+	exitOnError(fmt.Sprintf("Writing price catalog file %s", *exportPriceCatalogFile), err)
+}
+
+func newLiveGCPPriceCatalog(config api.CostimatorConfig, requirements api.PriceRequirements) api.GCPPriceCatalog {
+	log.Debug("Retriving Price Catalog from GCP...")
+	priceCatalog, err := api.NewGCPPriceCatalog(readAuthKeyFromFile(), config, requirements)
+	exitOnError("Unable to read Pricing Catalog from GCP", err)
+	return priceCatalog
+}
+
+func newGCPPriceCatalog(config api.CostimatorConfig, requirements api.PriceRequirements) api.GCPPriceCatalog {
+	if *priceCacheTTL <= 0 {
+		log.Info("Price cache disabled. Using live GCP price catalog.")
+		return newLiveGCPPriceCatalog(config, requirements)
+	}
 	log.Debug("Retriving Price Catalog from GCP...")
 	credentials := readAuthKeyFromFile()
-	priceCatalog, err := api.NewGCPPriceCatalog(credentials, config)
+
+	cache := api.PriceCache{Dir: *priceCacheDir, TTL: *priceCacheTTL}
+	if cache.Dir == "" {
+		var err error
+		cache.Dir, err = api.DefaultPriceCacheDir()
+		exitOnError("Unable to find user cache folder. Use 'price-cache-dir' parameter", err)
+	}
+	priceCatalog, err := api.NewCachedGCPPriceCatalog(credentials, config, requirements, cache, *refreshPrices)
 	exitOnError("Unable to read Pricing Catalog from GCP", err)
 	return priceCatalog
 }
@@ -136,23 +292,74 @@ func isK8sPathProvided(k8sPath string, flag string) bool {
 	return true
 }
 
-func estimateCost(path string, conf api.CostimatorConfig, pc api.GCPPriceCatalog) api.Cost {
-	log.Infof("Estimating monthly cost for k8s objects in path '%s'...", path)
+func loadManifests(path string, conf api.CostimatorConfig) api.Manifests {
+	log.Infof("Loading k8s objects in path '%s'...", path)
 	manifests := api.Manifests{}
-	err := manifests.LoadObjectsFromPath(path, conf)
+	var err error
+	if api.IsHelmChart(path) {
+		log.Infof("Rendering Helm chart in path '%s'...", path)
+		err = manifests.LoadObjectsFromHelmChart(path, helmOptions(), conf)
+	} else {
+		err = manifests.LoadObjectsFromPath(path, conf)
+	}
 	if err != nil {
 		exitOnError(fmt.Sprintf("Unable estimate cost for %s", path), err)
 	}
-	return manifests.EstimateCost(pc)
+	return manifests
 }
 
-func outputDiff(diffCost api.DiffCost) {
-	output(diffCost.ToMarkdown())
+func estimateCost(path string, manifests api.Manifests, pp api.PriceProvider) api.Cost {
+	log.Infof("Estimating monthly cost for k8s objects in path '%s'...", path)
+	return manifests.EstimateCost(pp)
+}
 
-	if *outputFile == "" {
+func helmOptions() api.HelmOptions {
+	opts := api.HelmOptions{
+		ReleaseName: *helmReleaseName,
+		Namespace:   *helmNamespace,
+	}
+	if *helmValues != "" {
+		opts.ValuesFiles = strings.Split(*helmValues, ",")
+	}
+	return opts
+}
+
+// newRenderer returns the user-supplied template renderer or, if not provided, the renderer registered for 'format'
+func newRenderer() api.Renderer {
+	if *templateFile != "" {
+		data, err := ioutil.ReadFile(*templateFile)
+		exitOnError("Unable to read 'template' file", err)
+		r, err := api.NewTemplateRenderer(path.Base(*templateFile), string(data))
+		exitOnError("Invalid 'template' file", err)
+		return r
+	}
+	r, err := api.GetRenderer(*format)
+	exitOnError("Invalid 'format' parameter", err)
+	return r
+}
+
+// isTextOutput tells if the output is free text, such as Markdown or templates, so it's saved according to 'environ'
+// Data formats (JSON, YAML and CSV) are always saved as they are rendered
+func isTextOutput() bool {
+	return *templateFile != "" || strings.EqualFold(*format, "MARKDOWN")
+}
+
+func render(input api.RenderInput) {
+	data, err := renderer.Render(input)
+	exitOnError("Unable to render output", err)
+	if isTextOutput() {
+		output(string(data))
+	} else {
+		outputData(data)
+	}
+
+	if !input.IsDiff() || *outputFile == "" {
 		return
 	}
-	saveDiffFile(diffCost)
+	saveDiffFile(*input.Diff)
+	if *templateFile == "" && strings.EqualFold(*format, "CSV") {
+		saveDiffCSVFile(*input.Diff)
+	}
 }
 
 func output(markdown string) {
@@ -175,6 +382,29 @@ func output(markdown string) {
 	}
 }
 
+// outputData writes JSON, YAML or CSV data to console and, if provided, to the output file regardless of 'environ'
+func outputData(data []byte) {
+	fmt.Printf("%s", data)
+
+	if *outputFile == "" {
+		return
+	}
+	log.Debugf("Saving %s file at '%s'", strings.ToUpper(*format), *outputFile)
+	err := ioutil.WriteFile(*outputFile, data, 0644)
+	exitOnError(fmt.Sprintf("Writing output file %s", *outputFile), err)
+}
+
+func saveDiffCSVFile(diffCost api.DiffCost) {
+	ext := path.Ext(*outputFile)
+	diffOutputFile := (*outputFile)[0:len(*outputFile)-len(ext)] + ".diff.csv"
+	log.Debugf("Saving Diff CSV file at '%s'", diffOutputFile)
+
+	data, err := diffCost.ToCSV()
+	exitOnError("Unable to convert cost diff to CSV", err)
+	err = ioutil.WriteFile(diffOutputFile, []byte(data), 0644)
+	exitOnError(fmt.Sprintf("Writing Diff CSV file %s", diffOutputFile), err)
+}
+
 func saveDiffFile(diffCost api.DiffCost) {
 	ext := path.Ext(*outputFile)
 	diffOutputFile := (*outputFile)[0:len(*outputFile)-len(ext)] + ".diff"
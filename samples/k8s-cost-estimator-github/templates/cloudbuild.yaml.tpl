@@ -22,7 +22,16 @@ steps:
       echo "*************************************************************************"
       echo "** Estimating cost difference between current and previous versions..."
       echo "*************************************************************************"
-      k8s-cost-estimator --k8s wordpress --k8s-prev previous/wordpress --output output.json --environ=GITHUB
+      # FinOps approval is required when any cost increases more than the threshold (policy exit code 11)
+      printf "rules:\n- name: finops-approval\n  fail:\n    usd: %s\n" "$_GITHUB_FINOPS_COST_USD_THRESHOLD" > policy.yaml
+      set +e
+      k8s-cost-estimator --k8s wordpress --k8s-prev previous/wordpress --output output.json --environ=GITHUB --policy policy.yaml
+      POLICY_EXIT_CODE=$$?
+      set -e
+      if [ $$POLICY_EXIT_CODE != 0 ] && [ $$POLICY_EXIT_CODE != 11 ]
+        then
+          exit $$POLICY_EXIT_CODE
+      fi
 
       echo ""
       echo "***************************************************************************************************************"
@@ -48,8 +57,7 @@ steps:
       createObject $$comments_url "$$comments_body"
 
       COST_USD_THRESHOLD=$_GITHUB_FINOPS_COST_USD_THRESHOLD
-      POSSIBLY_COST_INCREASE=$(cat output.diff | jq ".summary.maxDiff.usd")
-      if (( $(echo "$$POSSIBLY_COST_INCREASE > $$COST_USD_THRESHOLD" | bc -l) ))
+      if [ $$POLICY_EXIT_CODE == 11 ]
         then
           echo ""
           echo "****************************************************************************************"
@@ -29,7 +29,16 @@ estimate-cost:
     echo "*************************************************************************"
     echo "** Estimating cost difference between current and previous versions..."
     echo "*************************************************************************"
-    k8s-cost-estimator --k8s wordpress --k8s-prev previous/wordpress --output output.json --environ=GITLAB
+    # FinOps approval is required when any cost increases more than the threshold (policy exit code 11)
+    printf "rules:\n- name: finops-approval\n  fail:\n    usd: %s\n" "$GITLAB_FINOPS_COST_USD_THRESHOLD" > policy.yaml
+    set +e
+    k8s-cost-estimator --k8s wordpress --k8s-prev previous/wordpress --output output.json --environ=GITLAB --policy policy.yaml
+    POLICY_EXIT_CODE=$?
+    set -e
+    if [ $POLICY_EXIT_CODE != 0 ] && [ $POLICY_EXIT_CODE != 11 ]
+      then
+        exit $POLICY_EXIT_CODE
+    fi
 
     echo ""
     echo "***************************************************************************************************************"
@@ -54,8 +63,7 @@ estimate-cost:
     comments_body="$(cat output.json)"
     createObject $comments_url "$comments_body"
     
-    POSSIBLY_COST_INCREASE=$(cat output.diff | jq ".summary.maxDiff.usd")
-    if (( $(echo "$POSSIBLY_COST_INCREASE > $GITLAB_FINOPS_COST_USD_THRESHOLD" | bc -l) ))
+    if [ $POLICY_EXIT_CODE == 11 ]
       then
         echo ""
         echo "****************************************************************************************"